@@ -19,6 +19,21 @@ type Statement interface {
 	statementNode() // helps go type-checker
 }
 
+// StatementToken returns the first token of a statement, the zero token for unknown statements.
+func StatementToken(stmt Statement) token.Token {
+	switch stmt := stmt.(type) {
+	case *LetStatement:
+		return stmt.Token
+	case *ReturnStatement:
+		return stmt.Token
+	case *ExpressionStatement:
+		return stmt.Token
+	case *BlockStatement:
+		return stmt.Token
+	}
+	return token.Token{}
+}
+
 type Expression interface {
 	Node
 	expressionNode() // helps go type-checker
@@ -27,6 +42,7 @@ type Expression interface {
 // Program is always the root node of the AST
 type Program struct {
 	Statements []Statement
+	Resolved   bool // whether the resolver already annotated the program
 }
 
 func (p *Program) TokenLiteral() string {
@@ -67,7 +83,7 @@ func (s *LetStatement) String() string {
 	var out bytes.Buffer
 	out.WriteString(s.TokenLiteral())
 	out.WriteString(" ")
-	out.WriteString(s.Name.String())
+	out.WriteString(s.Name.declaration())
 	out.WriteString(" = ")
 	if s.Value != nil {
 		out.WriteString(s.Value.String())
@@ -76,10 +92,22 @@ func (s *LetStatement) String() string {
 	return out.String()
 }
 
+// GlobalDepth is the Depth of identifiers bound outside of any function.
+const GlobalDepth = -1
+
 // Identifiers are things like variable and function names, and constants like numbers
 type Identifier struct {
 	Token token.Token // token.IDENT
 	Value string
+
+	// lexical address set by the resolver: Depth is the number of function scopes to walk up
+	// (or GlobalDepth) and Slot the position of the binding in that scope.
+	Resolved bool
+	Depth    int
+	Slot     int
+
+	// optional annotation, only for identifiers that declare bindings (let names and parameters)
+	Type *TypeAnnotation
 }
 
 func (i *Identifier) ChildNodes() []Node   { return []Node{} }
@@ -87,6 +115,39 @@ func (i *Identifier) expressionNode()      {}
 func (i *Identifier) TokenLiteral() string { return i.Token.Literal }
 func (i *Identifier) String() string       { return i.Value }
 
+// declaration returns the identifier along with its type annotation, if it has one.
+func (i *Identifier) declaration() string {
+	if i.Type == nil {
+		return i.Value
+	}
+	return i.Value + ": " + i.Type.String()
+}
+
+// TypeAnnotation is the type given to a binding or to the result of a function,
+// eg. `int`, `[string]`, `{string: int}` or `fn(int, int): bool`.
+type TypeAnnotation struct {
+	Token  token.Token       // first token of the annotation
+	Name   string            // int, string, bool, null, any, or [] for arrays, {} for hashes and fn for functions
+	Params []*TypeAnnotation // items of arrays, key and value of hashes, parameters of functions
+	Result *TypeAnnotation   // result of functions
+}
+
+func (ta *TypeAnnotation) String() string {
+	params := make([]string, len(ta.Params))
+	for ix, p := range ta.Params {
+		params[ix] = p.String()
+	}
+	switch ta.Name {
+	case "[]":
+		return "[" + params[0] + "]"
+	case "{}":
+		return "{" + params[0] + ": " + params[1] + "}"
+	case "fn":
+		return "fn(" + strings.Join(params, ", ") + "): " + ta.Result.String()
+	}
+	return ta.Name
+}
+
 type ReturnStatement struct {
 	Token       token.Token // token.RETURN
 	ReturnValue Expression
@@ -142,6 +203,50 @@ func (sl *StringLiteral) expressionNode()      {}
 func (sl *StringLiteral) TokenLiteral() string { return sl.Token.Literal }
 func (sl *StringLiteral) String() string       { return fmt.Sprintf("\"%s\"", sl.Token.Literal) }
 
+// InterpolatedString is a string containing embedded expressions, eg. "Hello ${name}".
+// Parts holds TemplateTexts for the raw text and any other Expression for the interpolated values.
+type InterpolatedString struct {
+	Token token.Token // the token.TEMPLATE_START token
+	Parts []Expression
+}
+
+func (is *InterpolatedString) ChildNodes() []Node {
+	nodes := make([]Node, len(is.Parts))
+	for ix := range is.Parts {
+		nodes[ix] = is.Parts[ix]
+	}
+	return nodes
+}
+func (is *InterpolatedString) expressionNode()      {}
+func (is *InterpolatedString) TokenLiteral() string { return is.Token.Literal }
+func (is *InterpolatedString) String() string {
+	var out bytes.Buffer
+	out.WriteString("\"")
+	for _, p := range is.Parts {
+		if text, ok := p.(*TemplateText); ok {
+			out.WriteString(text.Value)
+		} else {
+			out.WriteString("${")
+			out.WriteString(p.String())
+			out.WriteString("}")
+		}
+	}
+	out.WriteString("\"")
+	return out.String()
+}
+
+// TemplateText is the raw text around the interpolated values of an InterpolatedString. unlike a
+// StringLiteral it is written as is, without quotes.
+type TemplateText struct {
+	Token token.Token // the token.TEMPLATE_START, token.TEMPLATE_MIDDLE or token.TEMPLATE_END token
+	Value string
+}
+
+func (tt *TemplateText) ChildNodes() []Node   { return []Node{} }
+func (tt *TemplateText) expressionNode()      {}
+func (tt *TemplateText) TokenLiteral() string { return tt.Token.Literal }
+func (tt *TemplateText) String() string       { return tt.Value }
+
 type PrefixExpression struct {
 	Token    token.Token // prefix token, eg. !
 	Operator string
@@ -194,6 +299,7 @@ func (b *Boolean) String() string       { return b.Token.Literal }
 type BlockStatement struct {
 	Token      token.Token // the { token
 	Statements []Statement
+	End        token.Token // the } token
 }
 
 func (bs *BlockStatement) ChildNodes() []Node {
@@ -251,6 +357,8 @@ type FunctionLiteral struct {
 	Token      token.Token // the 'fn' token
 	Parameters []*Identifier
 	Body       *BlockStatement
+	ReturnType *TypeAnnotation // optional
+	Locals     []string        // names of the local slots (parameters first), set by the resolver
 }
 
 func (fl *FunctionLiteral) ChildNodes() []Node {
@@ -268,13 +376,17 @@ func (fl *FunctionLiteral) String() string {
 
 	params := make([]string, len(fl.Parameters))
 	for ix, p := range fl.Parameters {
-		params[ix] = p.String()
+		params[ix] = p.declaration()
 	}
 
 	out.WriteString(fl.TokenLiteral())
 	out.WriteString("(")
 	out.WriteString(strings.Join(params, ","))
-	out.WriteString(") ")
+	out.WriteString(")")
+	if fl.ReturnType != nil {
+		out.WriteString(": " + fl.ReturnType.String())
+	}
+	out.WriteString(" ")
 	out.WriteString(fl.Body.String())
 
 	return out.String()
@@ -360,16 +472,58 @@ func (ie *IndexExpression) String() string {
 	return out.String()
 }
 
+// SliceExpression represents `left[start:end]`, both Start and End are optional and may be nil.
+type SliceExpression struct {
+	Token token.Token // the '[' token
+	Left  Expression
+	Start Expression
+	End   Expression
+}
+
+func (se *SliceExpression) ChildNodes() []Node {
+	nodes := []Node{se.Left}
+	if se.Start != nil {
+		nodes = append(nodes, se.Start)
+	}
+	if se.End != nil {
+		nodes = append(nodes, se.End)
+	}
+	return nodes
+}
+func (se *SliceExpression) expressionNode()      {}
+func (se *SliceExpression) TokenLiteral() string { return se.Token.Literal }
+func (se *SliceExpression) String() string {
+	var out bytes.Buffer
+	out.WriteString("(")
+	out.WriteString(se.Left.String())
+	out.WriteString("[")
+	if se.Start != nil {
+		out.WriteString(se.Start.String())
+	}
+	out.WriteString(":")
+	if se.End != nil {
+		out.WriteString(se.End.String())
+	}
+	out.WriteString("])")
+	return out.String()
+}
+
+// HashItem is a single `key: value` entry of a HashLiteral.
+type HashItem struct {
+	Key   Expression
+	Value Expression
+}
+
 type HashLiteral struct {
 	Token token.Token // the '{' token
-	Items map[Expression]Expression
+	Items []HashItem  // in source order
 }
 
 func (hl *HashLiteral) ChildNodes() []Node {
-	nodes := make([]Node, 0)
-	for key, val := range hl.Items {
-		nodes = append(nodes, key)
-		nodes = append(nodes, val)
+	nodes := make([]Node, 0, len(hl.Items)*2)
+	for _, item := range hl.Items {
+		nodes = append(nodes, item.Key)
+		nodes = append(nodes, item.Value)
 	}
 	return nodes
 }
@@ -378,14 +532,14 @@ func (hl *HashLiteral) TokenLiteral() string { return hl.Token.Literal }
 func (hl *HashLiteral) String() string {
 	var out bytes.Buffer
 
-	items := make([]string, 0)
-	for key, val := range hl.Items {
-		items = append(items, fmt.Sprintf("%s: %s", key.String(), val.String()))
+	items := make([]string, 0, len(hl.Items))
+	for _, item := range hl.Items {
+		items = append(items, fmt.Sprintf("%s: %s", item.Key.String(), item.Value.String()))
 	}
 
-	out.WriteString("[")
+	out.WriteString("{")
 	out.WriteString(strings.Join(items, ", "))
-	out.WriteString("]")
+	out.WriteString("}")
 
 	return out.String()
 }
@@ -0,0 +1,273 @@
+package dap
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const src = `let add = fn(a, b) {
+    let total = a + b;
+    total
+};
+let pair = {"x": [1, 2], "y": add};
+let x = add(1, 2);
+x`
+
+type client struct {
+	t   *testing.T
+	w   io.Writer
+	r   *bufio.Reader
+	seq int
+	err chan error
+}
+
+func newClient(t *testing.T) *client {
+	inR, inW := io.Pipe()
+	outR, outW := io.Pipe()
+	c := &client{t: t, w: inW, r: bufio.NewReader(outR), err: make(chan error, 1)}
+	go func() {
+		c.err <- Serve(inR, outW)
+		outW.Close()
+	}()
+	return c
+}
+
+func (c *client) send(command string, args any) int {
+	c.t.Helper()
+	c.seq++
+	raw, _ := json.Marshal(args)
+	body, _ := json.Marshal(map[string]any{"seq": c.seq, "type": "request", "command": command, "arguments": json.RawMessage(raw)})
+	if _, err := fmt.Fprintf(c.w, "Content-Length: %d\r\n\r\n%s", len(body), body); err != nil {
+		c.t.Fatalf("failed to send %s: %v", command, err)
+	}
+	return c.seq
+}
+
+// next reads messages until one matches the given type and command or event name.
+func (c *client) next(kind string, name string) map[string]any {
+	c.t.Helper()
+	for {
+		msg, err := c.read()
+		if err != nil {
+			c.t.Fatalf("waiting for %s %s: %v", kind, name, err)
+		}
+		if msg["type"] == kind && (msg["command"] == name || msg["event"] == name) {
+			return msg
+		}
+	}
+}
+
+func (c *client) read() (map[string]any, error) {
+	line, err := c.r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	var length int
+	if _, err := fmt.Sscanf(line, "Content-Length: %d\r\n", &length); err != nil {
+		return nil, err
+	}
+	if _, err := c.r.ReadString('\n'); err != nil {
+		return nil, err
+	}
+	body := make([]byte, length)
+	if _, err := io.ReadFull(c.r, body); err != nil {
+		return nil, err
+	}
+	var msg map[string]any
+	return msg, json.Unmarshal(body, &msg)
+}
+
+// request sends a request and returns the body of its successful response.
+func (c *client) request(command string, args any) map[string]any {
+	c.t.Helper()
+	c.send(command, args)
+	resp := c.next("response", command)
+	if resp["success"] != true {
+		c.t.Fatalf("%s failed: %v", command, resp["message"])
+	}
+	body, _ := resp["body"].(map[string]any)
+	return body
+}
+
+func (c *client) launch(stopOnEntry bool, breakpoints ...int) {
+	c.t.Helper()
+	c.launchSource(src, stopOnEntry, breakpoints...)
+}
+
+func (c *client) launchSource(source string, stopOnEntry bool, breakpoints ...int) {
+	c.t.Helper()
+	path := filepath.Join(c.t.TempDir(), "test.monkey")
+	if err := os.WriteFile(path, []byte(source), 0o644); err != nil {
+		c.t.Fatal(err)
+	}
+	c.request("initialize", map[string]any{"adapterID": "monkey"})
+	c.next("event", "initialized")
+	c.request("launch", map[string]any{"program": path, "stopOnEntry": stopOnEntry})
+
+	lines := make([]map[string]any, len(breakpoints))
+	for ix, line := range breakpoints {
+		lines[ix] = map[string]any{"line": line}
+	}
+	c.request("setBreakpoints", map[string]any{"source": map[string]any{"path": path}, "breakpoints": lines})
+	c.request("configurationDone", nil)
+}
+
+func (c *client) close() {
+	c.t.Helper()
+	c.request("disconnect", nil)
+	select {
+	case err := <-c.err:
+		if err != nil {
+			c.t.Errorf("Serve failed: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		c.t.Fatal("server didn't stop after disconnecting")
+	}
+}
+
+func (c *client) stoppedAt() (string, []string) {
+	c.t.Helper()
+	stopped := c.next("event", "stopped")
+	body := c.request("stackTrace", map[string]any{"threadId": 1})
+	var frames []string
+	for _, frame := range body["stackFrames"].([]any) {
+		frame := frame.(map[string]any)
+		frames = append(frames, fmt.Sprintf("%s:%v", frame["name"], frame["line"]))
+	}
+	return stopped["body"].(map[string]any)["reason"].(string), frames
+}
+
+func TestRunToCompletion(t *testing.T) {
+	c := newClient(t)
+	c.launch(false)
+	output := c.next("event", "output")
+	if body := output["body"].(map[string]any); body["output"] != "3\n" {
+		t.Errorf("wrong output: %v", body)
+	}
+	exited := c.next("event", "exited")
+	if code := exited["body"].(map[string]any)["exitCode"]; code != float64(0) {
+		t.Errorf("wrong exit code: %v", code)
+	}
+	c.next("event", "terminated")
+	c.close()
+}
+
+func TestProgramOutput(t *testing.T) {
+	c := newClient(t)
+	c.launchSource(`inspect("hello", 1); let name = input("name? "); name`, false)
+	for _, expected := range []map[string]any{
+		{"category": "stdout", "output": "hello\n"},
+		{"category": "stdout", "output": "1\n"},
+		{"category": "stdout", "output": "name? "},
+		{"category": "console", "output": "null\n"},
+	} {
+		body := c.next("event", "output")["body"].(map[string]any)
+		if body["category"] != expected["category"] || body["output"] != expected["output"] {
+			t.Errorf("wrong output. expected=%v, got=%v", expected, body)
+		}
+	}
+	c.next("event", "terminated")
+	c.close()
+}
+
+func TestStepping(t *testing.T) {
+	c := newClient(t)
+	c.launch(true, 2)
+
+	expect := func(reason string, frames string) {
+		t.Helper()
+		gotReason, gotFrames := c.stoppedAt()
+		if gotReason != reason || strings.Join(gotFrames, " ") != frames {
+			t.Errorf("expected to stop by %s at %s, got %s at %s", reason, frames, gotReason, strings.Join(gotFrames, " "))
+		}
+	}
+
+	expect("entry", "<program>:1")
+	c.request("next", map[string]any{"threadId": 1})
+	expect("step", "<program>:5")
+	c.request("continue", map[string]any{"threadId": 1})
+	expect("breakpoint", "add:2 <program>:6")
+	c.request("stepIn", map[string]any{"threadId": 1})
+	expect("step", "add:3 <program>:6")
+	c.request("stepOut", map[string]any{"threadId": 1})
+	expect("step", "<program>:7")
+	c.request("continue", map[string]any{"threadId": 1})
+	c.next("event", "terminated")
+	c.close()
+}
+
+func TestVariables(t *testing.T) {
+	c := newClient(t)
+	c.launch(false, 2)
+	c.stoppedAt()
+
+	scopes := c.request("scopes", map[string]any{"frameId": 1})["scopes"].([]any)
+	if len(scopes) != 2 {
+		t.Fatalf("expected locals and globals, got %v", scopes)
+	}
+	variables := func(ref any) map[string]map[string]any {
+		t.Helper()
+		out := make(map[string]map[string]any)
+		for _, v := range c.request("variables", map[string]any{"variablesReference": ref})["variables"].([]any) {
+			v := v.(map[string]any)
+			out[v["name"].(string)] = v
+		}
+		return out
+	}
+
+	locals := variables(scopes[0].(map[string]any)["variablesReference"])
+	if locals["a"]["value"] != "1" || locals["b"]["value"] != "2" || len(locals) != 2 {
+		t.Errorf("wrong locals: %v", locals)
+	}
+
+	globals := variables(scopes[1].(map[string]any)["variablesReference"])
+	if globals["add"]["value"] != "fn(a, b)" {
+		t.Errorf("wrong value for add: %v", globals["add"])
+	}
+	pair := globals["pair"]
+	if pair == nil || pair["variablesReference"] == float64(0) {
+		t.Fatalf("expected pair to have children, got %v", pair)
+	}
+	children := variables(pair["variablesReference"])
+	if children[`"y"`]["value"] != "fn(a, b)" {
+		t.Errorf("wrong hash children: %v", children)
+	}
+	items := variables(children[`"x"`]["variablesReference"])
+	if items["[0]"]["value"] != "1" || items["[1]"]["value"] != "2" {
+		t.Errorf("wrong array children: %v", items)
+	}
+
+	result := c.request("evaluate", map[string]any{"expression": "add(a, b) * 10", "frameId": 1})
+	if result["result"] != "30" {
+		t.Errorf("wrong evaluation: %v", result)
+	}
+	c.send("evaluate", map[string]any{"expression": "missing", "frameId": 1})
+	if resp := c.next("response", "evaluate"); resp["success"] != false {
+		t.Errorf("expected evaluation to fail, got %v", resp)
+	}
+
+	c.close()
+}
+
+func TestUnsupportedRequest(t *testing.T) {
+	c := newClient(t)
+	c.send("restartFrame", nil)
+	if resp := c.next("response", "restartFrame"); resp["success"] != false {
+		t.Errorf("expected request to fail, got %v", resp)
+	}
+	c.close()
+}
+
+func TestInvalidContentLength(t *testing.T) {
+	err := Serve(strings.NewReader("Content-Length: -1\r\n\r\n{}"), io.Discard)
+	if err == nil || !strings.Contains(err.Error(), "invalid Content-Length") {
+		t.Errorf("expected framing error, got %v", err)
+	}
+}
@@ -0,0 +1,423 @@
+// Package dap implements a Debug Adapter Protocol server for Monkey over stdio.
+//
+// The debugged program runs in its own goroutine on top of debug.Debugger, while requests are
+// handled as they arrive. Inspecting the program (stack traces, scopes, variables and evaluation)
+// is only possible while it is paused.
+package dap
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/manuelpepe/interpreter/ast"
+	"github.com/manuelpepe/interpreter/debug"
+	"github.com/manuelpepe/interpreter/eval"
+	"github.com/manuelpepe/interpreter/lexer"
+	"github.com/manuelpepe/interpreter/object"
+	"github.com/manuelpepe/interpreter/parser"
+)
+
+// the program runs in a single thread
+const threadID = 1
+
+type server struct {
+	in *bufio.Reader
+
+	mu     sync.Mutex // guards out, seq and paused
+	out    io.Writer
+	seq    int
+	paused bool
+
+	debugger *debug.Debugger
+	resume   chan debug.Action
+	done     chan struct{} // closed when the program finishes
+
+	source      Source
+	prog        *ast.Program
+	stopOnEntry bool
+	launched    bool
+	configured  bool
+	started     bool
+
+	refs []any // environments and values with children, by variablesReference - 1
+}
+
+// Serve handles requests read from r, writing responses and events to w, until the client
+// disconnects or r is closed.
+func Serve(r io.Reader, w io.Writer) error {
+	s := &server{
+		in:     bufio.NewReader(r),
+		out:    w,
+		resume: make(chan debug.Action),
+		done:   make(chan struct{}),
+	}
+	s.debugger = debug.New(s.pause)
+
+	for {
+		req, err := readRequest(s.in)
+		if errors.Is(err, io.EOF) {
+			s.stop()
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		h, ok := handlers[req.Command]
+		var body any
+		if !ok {
+			err = fmt.Errorf("unsupported request: %s", req.Command)
+		} else {
+			body, err = h(s, req.Arguments)
+		}
+		resp := &response{Type: "response", RequestSeq: req.Seq, Command: req.Command, Success: err == nil, Body: body}
+		if err != nil {
+			resp.Message = err.Error()
+		}
+		if err := s.send(resp); err != nil {
+			return err
+		}
+
+		switch req.Command {
+		case "initialize":
+			if err := s.event("initialized", nil); err != nil {
+				return err
+			}
+		case "launch", "configurationDone":
+			s.start()
+		case "disconnect":
+			return nil
+		}
+	}
+}
+
+type handler func(s *server, args json.RawMessage) (any, error)
+
+var handlers = map[string]handler{
+	"initialize":        (*server).initialize,
+	"launch":            (*server).launch,
+	"setBreakpoints":    (*server).setBreakpoints,
+	"configurationDone": (*server).configurationDone,
+	"threads":           (*server).threads,
+	"stackTrace":        (*server).stackTrace,
+	"scopes":            (*server).scopes,
+	"variables":         (*server).variables,
+	"evaluate":          (*server).evaluate,
+	"continue":          resumeWith(debug.Continue),
+	"next":              resumeWith(debug.StepOver),
+	"stepIn":            resumeWith(debug.StepIn),
+	"stepOut":           resumeWith(debug.StepOut),
+	"disconnect":        (*server).disconnect,
+	"terminate":         (*server).disconnect,
+}
+
+// send writes a response or event, numbering it.
+func (s *server) send(msg any) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.seq++
+	switch msg := msg.(type) {
+	case *response:
+		msg.Seq = s.seq
+	case *event:
+		msg.Seq = s.seq
+	}
+	return writeMessage(s.out, msg)
+}
+
+func (s *server) event(name string, body any) error {
+	return s.send(&event{Type: "event", Event: name, Body: body})
+}
+
+func (s *server) isPaused() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.paused
+}
+
+func (s *server) initialize(args json.RawMessage) (any, error) {
+	return map[string]any{
+		"supportsConfigurationDoneRequest": true,
+		"supportsEvaluateForHovers":        true,
+		"supportsTerminateRequest":         true,
+	}, nil
+}
+
+func (s *server) launch(args json.RawMessage) (any, error) {
+	var a LaunchArguments
+	if err := json.Unmarshal(args, &a); err != nil {
+		return nil, err
+	}
+	if s.launched {
+		return nil, errors.New("a program was already launched")
+	}
+	data, err := os.ReadFile(a.Program)
+	if err != nil {
+		return nil, err
+	}
+
+	p := parser.New(lexer.NewLexer(string(data)))
+	prog := p.ParseProgram()
+	if errs := p.SyntaxErrors(); len(errs) != 0 {
+		return nil, fmt.Errorf("%s:%s", a.Program, errs[0])
+	}
+
+	s.source = Source{Name: filepath.Base(a.Program), Path: a.Program}
+	s.prog = prog
+	s.stopOnEntry = a.StopOnEntry
+	s.launched = true
+	return nil, nil
+}
+
+// setBreakpoints replaces all breakpoints, as there is a single source.
+func (s *server) setBreakpoints(args json.RawMessage) (any, error) {
+	var a SetBreakpointsArguments
+	if err := json.Unmarshal(args, &a); err != nil {
+		return nil, err
+	}
+	s.debugger.ClearBreakpoints()
+	breakpoints := make([]Breakpoint, len(a.Breakpoints))
+	for ix, bp := range a.Breakpoints {
+		s.debugger.SetBreakpoint(bp.Line)
+		breakpoints[ix] = Breakpoint{Verified: true, Line: bp.Line}
+	}
+	return map[string]any{"breakpoints": breakpoints}, nil
+}
+
+func (s *server) configurationDone(args json.RawMessage) (any, error) {
+	s.configured = true
+	return nil, nil
+}
+
+// start runs the program once it was launched and configured.
+func (s *server) start() {
+	if !s.launched || !s.configured || s.started {
+		return
+	}
+	s.started = true
+	env := object.NewEnvironment()
+	env.Runtime().Builtins = s.builtins()
+	go func() {
+		defer close(s.done)
+		res, err := s.debugger.Run(s.prog, env)
+		code := 0
+		switch {
+		case err != nil:
+			code = 1
+		case res != nil && res.Type() == object.ERROR_OBJ:
+			code = 1
+			s.event("output", map[string]any{"category": "stderr", "output": res.Inspect() + "\n"})
+		case res != nil:
+			s.event("output", map[string]any{"category": "console", "output": res.Inspect() + "\n"})
+		}
+		s.event("exited", map[string]any{"exitCode": code})
+		s.event("terminated", nil)
+	}()
+}
+
+// builtins returns the builtins of the program, with the ones that print sending output events
+// instead, as stdout is used by the protocol. the input can't be read for the same reason, so
+// `input` always returns null.
+func (s *server) builtins() *object.Registry {
+	builtins := eval.Builtins()
+	out := &output{s: s}
+	inspect, _ := builtins.Get("inspect")
+	builtins.MustRegister(&eval.InspectBuiltin{Out: out}, inspect.Spec)
+	input, _ := builtins.Get("input")
+	builtins.MustRegister(&eval.InputBuiltin{In: bufio.NewReader(strings.NewReader("")), Out: out}, input.Spec)
+	return builtins
+}
+
+// output writes what the program prints as output events.
+type output struct {
+	s *server
+}
+
+func (o *output) Write(p []byte) (int, error) {
+	if err := o.s.event("output", map[string]any{"category": "stdout", "output": string(p)}); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// pause is called from the goroutine running the program, it blocks until the client resumes it.
+func (s *server) pause(reason debug.Reason) debug.Action {
+	if reason == debug.ReasonEntry && !s.stopOnEntry {
+		return debug.Continue
+	}
+	s.mu.Lock()
+	s.paused = true
+	s.mu.Unlock()
+	s.event("stopped", map[string]any{"reason": string(reason), "threadId": threadID, "allThreadsStopped": true})
+	return <-s.resume
+}
+
+func resumeWith(action debug.Action) handler {
+	return func(s *server, args json.RawMessage) (any, error) {
+		if !s.resumeProgram(action) {
+			return nil, errors.New("the program is not paused")
+		}
+		return map[string]any{"allThreadsContinued": true}, nil
+	}
+}
+
+func (s *server) resumeProgram(action debug.Action) bool {
+	s.mu.Lock()
+	paused := s.paused
+	s.paused = false
+	s.mu.Unlock()
+	if !paused {
+		return false
+	}
+	s.refs = nil
+	s.resume <- action
+	return true
+}
+
+// stop aborts the program if it's paused and waits for it to finish.
+func (s *server) stop() {
+	if s.resumeProgram(debug.Stop) {
+		<-s.done
+	}
+}
+
+func (s *server) disconnect(args json.RawMessage) (any, error) {
+	s.stop()
+	return nil, nil
+}
+
+func (s *server) threads(args json.RawMessage) (any, error) {
+	return map[string]any{"threads": []Thread{{ID: threadID, Name: "main"}}}, nil
+}
+
+func (s *server) stackTrace(args json.RawMessage) (any, error) {
+	if !s.isPaused() {
+		return nil, errors.New("the program is not paused")
+	}
+	stack := s.debugger.Stack()
+	frames := make([]StackFrame, len(stack))
+	for ix, frame := range stack {
+		frames[ix] = StackFrame{ID: ix + 1, Name: frame.Name, Source: s.source, Line: frame.Line, Column: frame.Column}
+	}
+	return map[string]any{"stackFrames": frames, "totalFrames": len(frames)}, nil
+}
+
+// frame returns the frame of the paused program with the given id, as returned by stackTrace.
+func (s *server) frame(id int) (*debug.Frame, error) {
+	if !s.isPaused() {
+		return nil, errors.New("the program is not paused")
+	}
+	stack := s.debugger.Stack()
+	if id < 1 || id > len(stack) {
+		return nil, fmt.Errorf("invalid frame: %d", id)
+	}
+	return stack[id-1], nil
+}
+
+func (s *server) scopes(args json.RawMessage) (any, error) {
+	var a struct {
+		FrameID int `json:"frameId"`
+	}
+	if err := json.Unmarshal(args, &a); err != nil {
+		return nil, err
+	}
+	frame, err := s.frame(a.FrameID)
+	if err != nil {
+		return nil, err
+	}
+
+	scopes := []Scope{}
+	for env := frame.Env; env != nil; env = env.Outer() {
+		name := "Closure"
+		switch {
+		case env.Outer() == nil:
+			name = "Globals"
+		case env == frame.Env:
+			name = "Locals"
+		}
+		scopes = append(scopes, Scope{Name: name, VariablesReference: s.reference(env)})
+	}
+	return map[string]any{"scopes": scopes}, nil
+}
+
+func (s *server) variables(args json.RawMessage) (any, error) {
+	var a struct {
+		VariablesReference int `json:"variablesReference"`
+	}
+	if err := json.Unmarshal(args, &a); err != nil {
+		return nil, err
+	}
+	if !s.isPaused() || a.VariablesReference < 1 || a.VariablesReference > len(s.refs) {
+		return nil, fmt.Errorf("invalid variables reference: %d", a.VariablesReference)
+	}
+
+	variables := []Variable{}
+	switch ref := s.refs[a.VariablesReference-1].(type) {
+	case *object.Environment:
+		for _, b := range ref.Bindings() {
+			variables = append(variables, s.variable(b.Name, b.Value))
+		}
+	case *object.Array:
+		for ix, item := range ref.Items() {
+			variables = append(variables, s.variable(fmt.Sprintf("[%d]", ix), item))
+		}
+	case *object.Hash:
+		for _, pair := range ref.Items() {
+			variables = append(variables, s.variable(object.Repr(pair.Key), pair.Value))
+		}
+	}
+	return map[string]any{"variables": variables}, nil
+}
+
+func (s *server) evaluate(args json.RawMessage) (any, error) {
+	var a EvaluateArguments
+	if err := json.Unmarshal(args, &a); err != nil {
+		return nil, err
+	}
+	frameID := a.FrameID
+	if frameID == 0 {
+		frameID = 1 // the innermost frame when the client doesn't give one
+	}
+	frame, err := s.frame(frameID)
+	if err != nil {
+		return nil, err
+	}
+	res, err := s.debugger.Evaluate(a.Expression, frame)
+	if err != nil {
+		return nil, err
+	}
+	if res == nil {
+		return map[string]any{"result": "", "variablesReference": 0}, nil
+	}
+	v := s.variable("", res)
+	return map[string]any{"result": v.Value, "type": v.Type, "variablesReference": v.VariablesReference}, nil
+}
+
+// reference returns a new variablesReference for an environment or value with children.
+// references are only valid until the program is resumed.
+func (s *server) reference(ref any) int {
+	s.refs = append(s.refs, ref)
+	return len(s.refs)
+}
+
+func (s *server) variable(name string, obj object.Object) Variable {
+	v := Variable{Name: name, Value: summary(obj), Type: string(obj.Type())}
+	switch obj := obj.(type) {
+	case *object.Array:
+		if obj.Len() != 0 {
+			v.VariablesReference = s.reference(obj)
+		}
+	case *object.Hash:
+		if obj.Len() != 0 {
+			v.VariablesReference = s.reference(obj)
+		}
+	}
+	return v
+}
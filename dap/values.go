@@ -0,0 +1,26 @@
+package dap
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"github.com/manuelpepe/interpreter/object"
+)
+
+// maxSummary is the length after which values are truncated, their children can still be expanded.
+const maxSummary = 80
+
+// summary is a single line representation of a value.
+func summary(obj object.Object) string {
+	var out string
+	switch obj := obj.(type) {
+	case *object.Function:
+		out = obj.Signature()
+	default:
+		out = strings.ReplaceAll(object.Repr(obj), "\n", " ")
+	}
+	if utf8.RuneCountInString(out) > maxSummary {
+		out = string([]rune(out)[:maxSummary-3]) + "..."
+	}
+	return out
+}
@@ -0,0 +1,202 @@
+package debug
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/manuelpepe/interpreter/ast"
+	"github.com/manuelpepe/interpreter/object"
+)
+
+const PROMPT = "(debug) "
+
+const help = `commands:
+  break, b [line]     set a breakpoint, or list them without a line
+  clear [line]        remove a breakpoint, or all of them without a line
+  continue, c         run until the next breakpoint
+  step, s             step into the next statement
+  next, n             step over function calls
+  out, o              run until the current function returns
+  print, p <expr>     evaluate an expression in the selected frame
+  env, e              print the scopes of the selected frame
+  backtrace, bt       print the call stack
+  frame, f <n>        select a frame of the call stack
+  list, l             print the source around the current line
+  quit, q             stop the program
+`
+
+type console struct {
+	name  string
+	lines []string
+
+	in  *bufio.Scanner
+	out io.Writer
+
+	debugger *Debugger
+	frame    int // selected frame, as an index of Debugger.Stack
+}
+
+// Start runs prog under the debugger, reading commands from in until the program finishes.
+// It pauses before the first statement so breakpoints can be set.
+func Start(name string, src string, prog *ast.Program, in io.Reader, out io.Writer) {
+	c := &console{
+		name:  name,
+		lines: strings.Split(src, "\n"),
+		in:    bufio.NewScanner(in),
+		out:   out,
+	}
+	c.debugger = New(c.pause)
+
+	res, err := c.debugger.Run(prog, object.NewEnvironment())
+	if err != nil {
+		fmt.Fprintln(out, err)
+		return
+	}
+	if res != nil {
+		fmt.Fprintln(out, res.Inspect())
+	}
+	fmt.Fprintln(out, "program finished")
+}
+
+func (c *console) pause(reason Reason) Action {
+	c.frame = 0
+	top := c.debugger.Stack()[0]
+	fmt.Fprintf(c.out, "stopped at %s:%d in %s (%s)\n", c.name, top.Line, top.Name, reason)
+	c.list(top.Line, 0)
+
+	for {
+		fmt.Fprint(c.out, PROMPT)
+		if !c.in.Scan() {
+			return Stop
+		}
+		cmd, arg, _ := strings.Cut(strings.TrimSpace(c.in.Text()), " ")
+		arg = strings.TrimSpace(arg)
+
+		switch cmd {
+		case "":
+		case "continue", "c":
+			return Continue
+		case "step", "s":
+			return StepIn
+		case "next", "n":
+			return StepOver
+		case "out", "o":
+			return StepOut
+		case "quit", "q":
+			return Stop
+		case "break", "b":
+			c.breakpoint(arg, true)
+		case "clear":
+			c.breakpoint(arg, false)
+		case "print", "p":
+			c.print(arg)
+		case "env", "e":
+			c.env()
+		case "backtrace", "bt":
+			c.backtrace()
+		case "frame", "f":
+			c.selectFrame(arg)
+		case "list", "l":
+			c.list(c.debugger.Stack()[c.frame].Line, 3)
+		case "help", "h":
+			io.WriteString(c.out, help)
+		default:
+			fmt.Fprintf(c.out, "unknown command: %s, try help\n", cmd)
+		}
+	}
+}
+
+func (c *console) breakpoint(arg string, set bool) {
+	if arg == "" {
+		if set {
+			for _, line := range c.debugger.Breakpoints() {
+				fmt.Fprintf(c.out, "%s:%d\n", c.name, line)
+			}
+		} else {
+			c.debugger.ClearBreakpoints()
+		}
+		return
+	}
+	line, err := strconv.Atoi(arg)
+	if err != nil || line < 1 || line > len(c.lines) {
+		fmt.Fprintf(c.out, "invalid line: %s\n", arg)
+		return
+	}
+	if set {
+		c.debugger.SetBreakpoint(line)
+	} else {
+		c.debugger.ClearBreakpoint(line)
+	}
+}
+
+func (c *console) print(expr string) {
+	if expr == "" {
+		fmt.Fprintln(c.out, "usage: print <expr>")
+		return
+	}
+	res, err := c.debugger.Evaluate(expr, c.debugger.Stack()[c.frame])
+	if err != nil {
+		fmt.Fprintf(c.out, "error: %v\n", err)
+		return
+	}
+	if res != nil {
+		fmt.Fprintln(c.out, object.Repr(res))
+	}
+}
+
+func (c *console) env() {
+	for env := c.debugger.Stack()[c.frame].Env; env != nil; env = env.Outer() {
+		if env.Outer() == nil {
+			fmt.Fprintln(c.out, "globals:")
+		} else {
+			fmt.Fprintln(c.out, "locals:")
+		}
+		for _, b := range env.Bindings() {
+			fmt.Fprintf(c.out, "  %s = %s\n", b.Name, summary(b.Value))
+		}
+	}
+}
+
+// summary is a single line representation of a value.
+func summary(obj object.Object) string {
+	if fn, ok := obj.(*object.Function); ok {
+		return fn.Signature() + " { ... }"
+	}
+	return object.Repr(obj)
+}
+
+func (c *console) backtrace() {
+	for ix, frame := range c.debugger.Stack() {
+		marker := " "
+		if ix == c.frame {
+			marker = "*"
+		}
+		fmt.Fprintf(c.out, "%s #%d %s at %s:%d\n", marker, ix, frame.Name, c.name, frame.Line)
+	}
+}
+
+func (c *console) selectFrame(arg string) {
+	ix, err := strconv.Atoi(arg)
+	if err != nil || ix < 0 || ix >= len(c.debugger.Stack()) {
+		fmt.Fprintf(c.out, "invalid frame: %s\n", arg)
+		return
+	}
+	c.frame = ix
+	frame := c.debugger.Stack()[ix]
+	fmt.Fprintf(c.out, "#%d %s at %s:%d\n", ix, frame.Name, c.name, frame.Line)
+	c.list(frame.Line, 0)
+}
+
+// list prints the line with the given number and the surrounding ones, marking it with an arrow.
+func (c *console) list(line int, around int) {
+	for n := max(line-around, 1); n <= min(line+around, len(c.lines)); n++ {
+		marker := "  "
+		if n == line {
+			marker = "=>"
+		}
+		fmt.Fprintf(c.out, "%s %4d  %s\n", marker, n, c.lines[n-1])
+	}
+}
@@ -0,0 +1,167 @@
+package debug
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/manuelpepe/interpreter/ast"
+	"github.com/manuelpepe/interpreter/lexer"
+	"github.com/manuelpepe/interpreter/object"
+	"github.com/manuelpepe/interpreter/parser"
+)
+
+const src = `let add = fn(a, b) {
+    let total = a + b;
+    total
+};
+let x = add(1, 2);
+let y = add(x, 3);
+y`
+
+func parse(t *testing.T, src string) *ast.Program {
+	t.Helper()
+	p := parser.New(lexer.NewLexer(src))
+	prog := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("parser errors: %v", p.Errors())
+	}
+	return prog
+}
+
+// stop describes a pause as "reason line frame,frame...".
+func stop(d *Debugger, reason Reason) string {
+	stack := d.Stack()
+	names := make([]string, len(stack))
+	for ix, frame := range stack {
+		names[ix] = frame.Name
+	}
+	return fmt.Sprintf("%s %d %s", reason, stack[0].Line, strings.Join(names, ","))
+}
+
+func TestStepping(t *testing.T) {
+	tests := []struct {
+		breakpoints []int
+		actions     []Action
+		expected    []string
+	}{
+		{
+			nil,
+			[]Action{Continue},
+			[]string{"entry 1 <program>"},
+		},
+		{
+			[]int{2},
+			[]Action{Continue, Continue, Continue},
+			[]string{"entry 1 <program>", "breakpoint 2 add,<program>", "breakpoint 2 add,<program>"},
+		},
+		{
+			nil,
+			[]Action{StepOver, StepIn, StepIn, StepOut, StepOver, Continue},
+			[]string{
+				"entry 1 <program>",
+				"step 5 <program>",
+				"step 2 add,<program>",
+				"step 3 add,<program>",
+				"step 6 <program>",
+				"step 7 <program>",
+			},
+		},
+		{
+			[]int{3},
+			[]Action{StepOver, StepOver, StepOver, Continue, Continue},
+			[]string{"entry 1 <program>", "step 5 <program>", "breakpoint 3 add,<program>", "step 6 <program>", "breakpoint 3 add,<program>"},
+		},
+	}
+
+	for _, tt := range tests {
+		var stops []string
+		var d *Debugger
+		d = New(func(reason Reason) Action {
+			stops = append(stops, stop(d, reason))
+			if len(stops) > len(tt.actions) {
+				t.Fatalf("unexpected pause: %s", stops[len(stops)-1])
+			}
+			return tt.actions[len(stops)-1]
+		})
+		for _, line := range tt.breakpoints {
+			d.SetBreakpoint(line)
+		}
+
+		res, err := d.Run(parse(t, src), object.NewEnvironment())
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if res.Inspect() != "6" {
+			t.Errorf("wrong result: %s", res.Inspect())
+		}
+		if strings.Join(stops, "\n") != strings.Join(tt.expected, "\n") {
+			t.Errorf("wrong pauses for %v.\nexpected:\n%s\ngot:\n%s", tt.actions,
+				strings.Join(tt.expected, "\n"), strings.Join(stops, "\n"))
+		}
+	}
+}
+
+func TestStop(t *testing.T) {
+	d := New(func(reason Reason) Action { return Stop })
+	env := object.NewEnvironment()
+	res, err := d.Run(parse(t, src), env)
+	if err == nil || res != nil {
+		t.Fatalf("expected the program to be stopped, got %v", res)
+	}
+	if env.Debugger() != nil {
+		t.Errorf("expected the debugger to be detached")
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	var d *Debugger
+	var results []string
+	d = New(func(reason Reason) Action {
+		if reason != ReasonBreakpoint {
+			return Continue
+		}
+		for _, expr := range []string{"a + b", "add(a, 10)", "x", "missing"} {
+			res, err := d.Evaluate(expr, d.Stack()[0])
+			if err != nil {
+				results = append(results, "error: "+err.Error())
+			} else {
+				results = append(results, res.Inspect())
+			}
+		}
+		res, _ := d.Evaluate("a", d.Stack()[1])
+		results = append(results, res.Inspect())
+		return Stop
+	})
+	d.SetBreakpoint(3)
+	d.Run(parse(t, "let a = 100;\n"+src), object.NewEnvironment())
+
+	// the breakpoint is on the first line of add's body, after shifting the source one line
+	expected := []string{"3", "11", "error: identifier not found: x", "error: identifier not found: missing", "100"}
+	if strings.Join(results, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("wrong results.\nexpected:\n%s\ngot:\n%s", strings.Join(expected, "\n"), strings.Join(results, "\n"))
+	}
+}
+
+func TestConsole(t *testing.T) {
+	in := strings.NewReader("b 2\nc\nbt\nenv\np a * 10\nf 1\nout\nc\nc\n")
+	var out bytes.Buffer
+	Start("test.monkey", src, parse(t, src), in, &out)
+
+	for _, expected := range []string{
+		"stopped at test.monkey:1 in <program> (entry)",
+		"stopped at test.monkey:2 in add (breakpoint)",
+		"=>    2      let total = a + b;",
+		"* #0 add at test.monkey:2\n  #1 <program> at test.monkey:5",
+		"locals:\n  a = 1\n  b = 2\nglobals:\n  add = fn(a, b) { ... }",
+		"(debug) 10\n",
+		"#1 <program> at test.monkey:5",
+		"stopped at test.monkey:6 in <program> (step)",
+		"6\nprogram finished",
+	} {
+		if !strings.Contains(out.String(), expected) {
+			t.Errorf("expected output to contain %q, got:\n%s", expected, out.String())
+		}
+	}
+}
@@ -0,0 +1,213 @@
+// Package debug implements breakpoints and stepping on top of the evaluator's debugger hook,
+// along with a terminal front-end for it.
+package debug
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/manuelpepe/interpreter/ast"
+	"github.com/manuelpepe/interpreter/eval"
+	"github.com/manuelpepe/interpreter/lexer"
+	"github.com/manuelpepe/interpreter/object"
+	"github.com/manuelpepe/interpreter/parser"
+)
+
+// Action tells the debugger how to resume after a pause.
+type Action int
+
+const (
+	Continue Action = iota // run until the next breakpoint
+	StepIn                 // pause at the next statement
+	StepOver               // pause at the next statement of the current or a calling frame
+	StepOut                // pause at the next statement of a calling frame
+	Stop                   // abort the program
+)
+
+// Reason describes why the program was paused.
+type Reason string
+
+const (
+	ReasonEntry      Reason = "entry"
+	ReasonBreakpoint Reason = "breakpoint"
+	ReasonStep       Reason = "step"
+)
+
+// Frame is a call in progress. The first frame of the stack is the program itself.
+type Frame struct {
+	Name      string
+	Fn        *object.Function // nil for the program
+	Env       *object.Environment
+	Statement ast.Statement // statement being executed
+	Line      int
+	Column    int
+}
+
+// Debugger runs programs pausing at breakpoints and while stepping. Every time the program is
+// paused OnPause is called, the program resumes according to the action it returns.
+//
+// Breakpoints may be changed from other goroutines while the program runs, everything else must
+// only be used from OnPause or while the program is paused in it.
+type Debugger struct {
+	OnPause func(reason Reason) Action
+
+	mu          sync.Mutex // guards breakpoints
+	breakpoints map[int]bool
+	stack       []*Frame
+
+	action Action
+	depth  int // size of the stack when the last action was given
+
+	evaluating bool
+}
+
+var errStopped = errors.New("program stopped by the debugger")
+
+func New(onPause func(reason Reason) Action) *Debugger {
+	return &Debugger{OnPause: onPause, breakpoints: make(map[int]bool)}
+}
+
+// Run evaluates the program in env pausing at its first statement. It returns the result
+// of the program, or an error if it was stopped.
+func (d *Debugger) Run(prog *ast.Program, env *object.Environment) (result object.Object, err error) {
+	d.stack = []*Frame{{Name: "<program>", Env: env}}
+	d.action = StepIn
+	d.depth = 1
+
+	defer func() {
+		d.stack = nil
+		env.SetDebugger(nil)
+		if r := recover(); r != nil {
+			if r != errStopped {
+				panic(r)
+			}
+			result, err = nil, errStopped
+		}
+	}()
+	env.SetDebugger(d)
+	return eval.Eval(prog, env), nil
+}
+
+func (d *Debugger) SetBreakpoint(line int) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.breakpoints[line] = true
+}
+
+func (d *Debugger) ClearBreakpoint(line int) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delete(d.breakpoints, line)
+}
+
+func (d *Debugger) ClearBreakpoints() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	clear(d.breakpoints)
+}
+
+// Breakpoints returns the lines with breakpoints in order.
+func (d *Debugger) Breakpoints() []int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	lines := make([]int, 0, len(d.breakpoints))
+	for line := range d.breakpoints {
+		lines = append(lines, line)
+	}
+	sort.Ints(lines)
+	return lines
+}
+
+// Stack returns the frames of the running program, innermost first.
+func (d *Debugger) Stack() []*Frame {
+	frames := make([]*Frame, len(d.stack))
+	for ix, frame := range d.stack {
+		frames[len(d.stack)-1-ix] = frame
+	}
+	return frames
+}
+
+// Evaluate runs src in the environment of a frame of the paused program, as returned by Stack.
+// Breakpoints are ignored while doing so.
+func (d *Debugger) Evaluate(src string, frame *Frame) (object.Object, error) {
+	p := parser.New(lexer.NewLexer(src))
+	prog := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		return nil, errors.New(strings.Join(p.Errors(), "\n"))
+	}
+
+	// statements are evaluated without resolving them, so identifiers are looked up by name
+	// through the scopes of the frame.
+	d.evaluating = true
+	defer func() { d.evaluating = false }()
+	var result object.Object
+	for _, stmt := range prog.Statements {
+		result = eval.Eval(stmt, frame.Env)
+		if ret, ok := result.(*object.ReturnValue); ok {
+			result = ret.Value
+			break
+		}
+		if err, ok := result.(*object.Error); ok {
+			return nil, fmt.Errorf("%s", err.Message)
+		}
+	}
+	return result, nil
+}
+
+func (d *Debugger) Statement(stmt ast.Statement, env *object.Environment) {
+	if d.evaluating {
+		return
+	}
+	frame := d.stack[len(d.stack)-1]
+	prev := frame.Line
+	frame.Statement = stmt
+	frame.Env = env
+	tok := ast.StatementToken(stmt)
+	frame.Line, frame.Column = tok.Line, tok.Column
+
+	d.mu.Lock()
+	breakpoint := d.breakpoints[frame.Line]
+	d.mu.Unlock()
+
+	var reason Reason
+	switch {
+	case d.action == StepIn && len(d.stack) == 1 && prev == 0:
+		reason = ReasonEntry
+	case d.action == StepIn,
+		d.action == StepOver && len(d.stack) <= d.depth,
+		d.action == StepOut && len(d.stack) < d.depth:
+		reason = ReasonStep
+	case breakpoint && frame.Line != prev:
+		// statements nested in the same line only stop once
+		reason = ReasonBreakpoint
+	default:
+		return
+	}
+
+	d.action = d.OnPause(reason)
+	d.depth = len(d.stack)
+	if d.action == Stop {
+		panic(errStopped)
+	}
+}
+
+func (d *Debugger) Enter(fn *object.Function, env *object.Environment) {
+	if d.evaluating {
+		return
+	}
+	name := fn.Name
+	if name == "" {
+		name = "<anonymous>"
+	}
+	d.stack = append(d.stack, &Frame{Name: name, Fn: fn, Env: env})
+}
+
+func (d *Debugger) Leave(fn *object.Function, result object.Object) {
+	if d.evaluating {
+		return
+	}
+	d.stack = d.stack[:len(d.stack)-1]
+}
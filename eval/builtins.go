@@ -1,18 +1,188 @@
 package eval
 
 import (
+	"bufio"
 	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"unicode/utf8"
 
 	"github.com/manuelpepe/interpreter/object"
 )
 
-var builtins = map[string]*object.Builtin{
-	"len":     {Fn: &LenBuiltin{}},
-	"first":   {Fn: &FirstBuiltin{}},
-	"last":    {Fn: &LastBuiltin{}},
-	"rest":    {Fn: &RestBuiltin{}},
-	"push":    {Fn: &PushBuiltin{}},
-	"inspect": {Fn: &InspectBuiltin{}},
+// builtins holds the default builtin functions. it's never modified after init, runtimes that need
+// different builtins use a copy returned by Builtins.
+var builtins = object.NewRegistry()
+
+func init() {
+	for _, b := range []struct {
+		fn   object.BuiltinFunction
+		spec *object.BuiltinSpec
+	}{
+		{&LenBuiltin{}, returns(object.INTEGER_OBJ,
+			spec("number of items of an array or characters of a string", param("x")))},
+		{&FirstBuiltin{}, spec("first item of an array, or null if it's empty", param("arr"))},
+		{&LastBuiltin{}, spec("last item of an array, or null if it's empty", param("arr"))},
+		{&RestBuiltin{}, spec("array without its first item, or null if it's empty", param("arr"))},
+		{&PushBuiltin{}, spec("new array with x added at the end", param("arr"), param("x"))},
+		{&InspectBuiltin{}, variadic(1, "prints each value in a line", param("values"))},
+		{&InputBuiltin{}, optional(1, "reads a line after printing the prompt, null at the end of the input",
+			param("prompt", object.STRING_OBJ))},
+		{&BytesBuiltin{}, spec("converts a string to an array of bytes and back", param("x"))},
+		{&RunesBuiltin{}, spec("converts a string to an array of code points and back", param("x"))},
+		{&StrBuiltin{}, returns(object.STRING_OBJ, spec("converts any value to a string", param("x")))},
+
+		// strings
+		{&SplitBuiltin{}, returns(object.ARRAY_OBJ, optional(1, "splits a string by a separator, or by whitespace",
+			param("s", object.STRING_OBJ), param("sep", object.STRING_OBJ)))},
+		{&JoinBuiltin{}, returns(object.STRING_OBJ,
+			optional(1, "concatenates an array of strings with an optional separator",
+				param("arr", object.ARRAY_OBJ), param("sep", object.STRING_OBJ)))},
+		{&TrimBuiltin{}, returns(object.STRING_OBJ,
+			optional(1, "removes leading and trailing whitespace, or the characters in cutset",
+				param("s", object.STRING_OBJ), param("cutset", object.STRING_OBJ)))},
+		{&UpperBuiltin{}, returns(object.STRING_OBJ,
+			spec("converts a string to upper case", param("s", object.STRING_OBJ)))},
+		{&LowerBuiltin{}, returns(object.STRING_OBJ,
+			spec("converts a string to lower case", param("s", object.STRING_OBJ)))},
+		{&ReplaceBuiltin{}, returns(object.STRING_OBJ,
+			optional(1, "replaces the first n occurrences of old with new, or all of them",
+				param("s", object.STRING_OBJ), param("old", object.STRING_OBJ), param("new", object.STRING_OBJ),
+				param("n", object.INTEGER_OBJ)))},
+		{&ContainsBuiltin{}, returns(object.BOOLEAN_OBJ, spec("whether s contains sub",
+			param("s", object.STRING_OBJ), param("sub", object.STRING_OBJ)))},
+		{&StartsWithBuiltin{}, returns(object.BOOLEAN_OBJ, spec("whether s starts with prefix",
+			param("s", object.STRING_OBJ), param("prefix", object.STRING_OBJ)))},
+		{&EndsWithBuiltin{}, returns(object.BOOLEAN_OBJ, spec("whether s ends with suffix",
+			param("s", object.STRING_OBJ), param("suffix", object.STRING_OBJ)))},
+		{&IndexOfBuiltin{}, returns(object.INTEGER_OBJ, spec("position of the first occurrence of sub in s, or -1",
+			param("s", object.STRING_OBJ), param("sub", object.STRING_OBJ)))},
+		{&RepeatBuiltin{}, returns(object.STRING_OBJ, spec("concatenates n copies of s",
+			param("s", object.STRING_OBJ), param("n", object.INTEGER_OBJ)))},
+		{&PadBuiltin{Left: true}, returns(object.STRING_OBJ,
+			optional(1, "adds spaces or pad before s up to width characters",
+				param("s", object.STRING_OBJ), param("width", object.INTEGER_OBJ), param("pad", object.STRING_OBJ)))},
+		{&PadBuiltin{Left: false}, returns(object.STRING_OBJ,
+			optional(1, "adds spaces or pad after s up to width characters",
+				param("s", object.STRING_OBJ), param("width", object.INTEGER_OBJ), param("pad", object.STRING_OBJ)))},
+		{&CharsBuiltin{}, returns(object.ARRAY_OBJ,
+			spec("splits a string into its characters", param("s", object.STRING_OBJ)))},
+		{&FormatBuiltin{}, returns(object.STRING_OBJ, variadic(1, "formats the values printf-style",
+			param("format", object.STRING_OBJ), param("values")))},
+
+		// collections
+		{&MapBuiltin{}, returns(object.ARRAY_OBJ, spec("new array with the results of calling fn on each item",
+			param("arr", object.ARRAY_OBJ), param("fn")))},
+		{&FilterBuiltin{}, returns(object.ARRAY_OBJ,
+			spec("new array with the items for which fn returns a truthy value",
+				param("arr", object.ARRAY_OBJ), param("fn")))},
+		{&ReduceBuiltin{}, spec("folds an array calling fn(accumulated, item) for each item",
+			param("arr", object.ARRAY_OBJ), param("initial"), param("fn"))},
+		{&SortBuiltin{}, returns(object.ARRAY_OBJ,
+			optional(1, "sorted copy of an array, cmp(a, b) tells whether a goes before b",
+				param("arr", object.ARRAY_OBJ), param("cmp")))},
+		{&ReverseBuiltin{}, spec("reversed copy of an array or string", param("x"))},
+		{&RangeBuiltin{}, returns(object.ARRAY_OBJ,
+			optional(2, "integers from start (or 0) up to end, not included: range(end), range(start, end, step?)",
+				param("start", object.INTEGER_OBJ), param("end", object.INTEGER_OBJ), param("step", object.INTEGER_OBJ)))},
+		{&ZipBuiltin{}, returns(object.ARRAY_OBJ, variadic(0, "groups the items of many arrays by position",
+			param("arrays", object.ARRAY_OBJ)))},
+		{&EnumerateBuiltin{}, returns(object.ARRAY_OBJ,
+			spec("pairs each item with its index", param("arr", object.ARRAY_OBJ)))},
+		{&PredicateBuiltin{All: false}, returns(object.BOOLEAN_OBJ,
+			optional(1, "whether any item (or its result for fn) is truthy",
+				param("arr", object.ARRAY_OBJ), param("fn")))},
+		{&PredicateBuiltin{All: true}, returns(object.BOOLEAN_OBJ,
+			optional(1, "whether all items (or their results for fn) are truthy",
+				param("arr", object.ARRAY_OBJ), param("fn")))},
+		{&FlattenBuiltin{}, returns(object.ARRAY_OBJ,
+			spec("concatenates the arrays inside an array", param("arr", object.ARRAY_OBJ)))},
+		{&UniqueBuiltin{}, returns(object.ARRAY_OBJ,
+			spec("copy of an array without repeated items", param("arr", object.ARRAY_OBJ)))},
+		{&ExtremeBuiltin{Max: false}, variadic(0, "smallest of the values, or of the items of an array",
+			param("values"))},
+		{&ExtremeBuiltin{Max: true}, variadic(0, "largest of the values, or of the items of an array",
+			param("values"))},
+		{&SumBuiltin{}, returns(object.INTEGER_OBJ,
+			spec("adds up an array of integers", param("arr", object.ARRAY_OBJ)))},
+
+		// hashes
+		{&KeysBuiltin{}, returns(object.ARRAY_OBJ,
+			spec("array with the keys of a hash", param("hash", object.HASH_OBJ)))},
+		{&ValuesBuiltin{}, returns(object.ARRAY_OBJ,
+			spec("array with the values of a hash", param("hash", object.HASH_OBJ)))},
+		{&ItemsBuiltin{}, returns(object.ARRAY_OBJ,
+			spec("array with the [key, value] pairs of a hash", param("hash", object.HASH_OBJ)))},
+		{&HasBuiltin{}, returns(object.BOOLEAN_OBJ,
+			spec("whether a hash contains key", param("hash", object.HASH_OBJ), param("key")))},
+		{&DeleteBuiltin{}, returns(object.HASH_OBJ,
+			spec("copy of a hash without key", param("hash", object.HASH_OBJ), param("key")))},
+		{&MergeBuiltin{}, returns(object.HASH_OBJ,
+			variadic(0, "new hash with the pairs of all hashes, the last ones winning",
+				param("hashes", object.HASH_OBJ)))},
+		{&GetBuiltin{}, optional(1, "value for key in a hash, or default (or null) if missing",
+			param("hash", object.HASH_OBJ), param("key"), param("default"))},
+		{&SetBuiltin{}, spec("copy of a hash or array with the value for key replaced",
+			param("x"), param("key"), param("value"))},
+	} {
+		builtins.MustRegister(b.fn, b.spec)
+	}
+}
+
+// returns sets the type of the result of a spec.
+func returns(typ object.ObjectType, s *object.BuiltinSpec) *object.BuiltinSpec {
+	s.Result = typ
+	return s
+}
+
+func param(name string, types ...object.ObjectType) object.BuiltinParam {
+	return object.BuiltinParam{Name: name, Types: types}
+}
+
+func spec(doc string, params ...object.BuiltinParam) *object.BuiltinSpec {
+	return &object.BuiltinSpec{Params: params, Doc: doc}
+}
+
+// optional is like spec, with the last n params being optional.
+func optional(n int, doc string, params ...object.BuiltinParam) *object.BuiltinSpec {
+	return &object.BuiltinSpec{Params: params, Optional: n, Doc: doc}
+}
+
+// variadic is like optional, with the last param accepting any number of arguments.
+func variadic(n int, doc string, params ...object.BuiltinParam) *object.BuiltinSpec {
+	return &object.BuiltinSpec{Params: params, Optional: n, Variadic: true, Doc: doc}
+}
+
+// IsBuiltin reports whether name refers to a default builtin function or namespace.
+func IsBuiltin(name string) bool {
+	_, ok := builtins.Lookup(name)
+	return ok
+}
+
+// LookupSpec returns the spec of a default builtin function, if it has one.
+func LookupSpec(name string) (*object.BuiltinSpec, bool) {
+	b, ok := builtins.Get(name)
+	if !ok || b.Spec == nil {
+		return nil, false
+	}
+	return b.Spec, true
+}
+
+// Builtins returns a copy of the registry of default builtin functions, to be customized in a Runtime.
+func Builtins() *object.Registry {
+	return builtins.Clone()
+}
+
+// BuiltinNames returns the names of all default builtin functions, sorted.
+func BuiltinNames() []string {
+	return builtins.Names()
+}
+
+// BuiltinHelp returns the signature and documentation of a default builtin function.
+func BuiltinHelp(name string) (string, bool) {
+	return builtins.Help(name)
 }
 
 func checkArgs(n int, args []object.Object) (bool, *object.Error) {
@@ -30,9 +200,9 @@ func (lb *LenBuiltin) Do(args ...object.Object) object.Object {
 	}
 	switch arg := args[0].(type) {
 	case *object.Array:
-		return &object.Integer{Value: int64(len(arg.Items))}
+		return &object.Integer{Value: int64(arg.Len())}
 	case *object.String:
-		return &object.Integer{Value: int64(len(arg.Value))}
+		return &object.Integer{Value: int64(utf8.RuneCountInString(arg.Value))}
 	default:
 		return newError("argument to `len` not supported, got %s",
 			args[0].Type())
@@ -53,8 +223,8 @@ func (fb *FirstBuiltin) Do(args ...object.Object) object.Object {
 	}
 
 	arg := args[0].(*object.Array)
-	if len(arg.Items) > 0 {
-		return arg.Items[0]
+	if arg.Len() > 0 {
+		return arg.Get(0)
 	}
 
 	return NULL
@@ -74,8 +244,8 @@ func (fb *LastBuiltin) Do(args ...object.Object) object.Object {
 	}
 
 	arg := args[0].(*object.Array)
-	if countItems := len(arg.Items); countItems > 0 {
-		return arg.Items[countItems-1]
+	if countItems := arg.Len(); countItems > 0 {
+		return arg.Get(countItems - 1)
 	}
 
 	return NULL
@@ -95,10 +265,8 @@ func (rb *RestBuiltin) Do(args ...object.Object) object.Object {
 	}
 
 	arr := args[0].(*object.Array)
-	if length := len(arr.Items); length > 0 {
-		newItems := make([]object.Object, length-1)
-		copy(newItems, arr.Items[1:length])
-		return &object.Array{Items: newItems}
+	if length := arr.Len(); length > 0 {
+		return arr.Slice(1, length)
 	}
 
 	return NULL
@@ -118,22 +286,24 @@ func (pb *PushBuiltin) Do(args ...object.Object) object.Object {
 	}
 
 	arr := args[0].(*object.Array)
-	length := len(arr.Items)
-	newItems := make([]object.Object, length+1)
-	copy(newItems, arr.Items)
-	newItems[length] = args[1]
-
-	return &object.Array{Items: newItems}
+	return arr.Push(args[1])
 }
 func (pb *PushBuiltin) Name() string {
 	return "push"
 }
 
-type InspectBuiltin struct{}
+// InspectBuiltin prints its arguments, one per line.
+type InspectBuiltin struct {
+	Out io.Writer // where to print, os.Stdout if nil
+}
 
 func (ib *InspectBuiltin) Do(args ...object.Object) object.Object {
+	out := ib.Out
+	if out == nil {
+		out = os.Stdout
+	}
 	for _, arg := range args {
-		fmt.Printf("%s\n", arg.Inspect())
+		fmt.Fprintf(out, "%s\n", arg.Inspect())
 	}
 	return NULL
 }
@@ -141,3 +311,131 @@ func (ib *InspectBuiltin) Do(args ...object.Object) object.Object {
 func (ib *InspectBuiltin) Name() string {
 	return "inspect"
 }
+
+var (
+	stdinMu sync.Mutex
+	stdin   = bufio.NewReader(os.Stdin) // shared by all input builtins without a reader
+)
+
+// InputBuiltin reads a line, after printing the optional prompt given as argument.
+// it returns null at the end of the input.
+type InputBuiltin struct {
+	In  *bufio.Reader // where to read from, os.Stdin if nil
+	Out io.Writer     // where to print the prompt, os.Stdout if nil
+}
+
+func (ib *InputBuiltin) Do(args ...object.Object) object.Object {
+	if ok, err := checkArgsRange(0, 1, args); !ok {
+		return err
+	}
+	if len(args) == 1 {
+		prompt, err := stringArg(ib.Name(), args, 0)
+		if err != nil {
+			return err
+		}
+		out := ib.Out
+		if out == nil {
+			out = os.Stdout
+		}
+		io.WriteString(out, prompt)
+	}
+
+	in := ib.In
+	if in == nil {
+		// builtins are shared, so the default reader can't be stored in ib
+		stdinMu.Lock()
+		defer stdinMu.Unlock()
+		in = stdin
+	}
+	line, err := in.ReadString('\n')
+	if err != nil && line == "" {
+		return NULL
+	}
+	return &object.String{Value: strings.TrimRight(line, "\r\n")}
+}
+
+func (ib *InputBuiltin) Name() string {
+	return "input"
+}
+
+// BytesBuiltin converts a string into an array with the integer value of each of its bytes,
+// and an array of such integers back into a string.
+type BytesBuiltin struct{}
+
+func (bb *BytesBuiltin) Do(args ...object.Object) object.Object {
+	if ok, err := checkArgs(1, args); !ok {
+		return err
+	}
+	switch arg := args[0].(type) {
+	case *object.String:
+		items := make([]object.Object, len(arg.Value))
+		for ix := 0; ix < len(arg.Value); ix++ {
+			items[ix] = &object.Integer{Value: int64(arg.Value[ix])}
+		}
+		return object.NewArray(items)
+	case *object.Array:
+		buf := make([]byte, arg.Len())
+		for ix, item := range arg.Items() {
+			n, ok := item.(*object.Integer)
+			if !ok || n.Value < 0 || n.Value > 255 {
+				return newError("argument to `bytes` must contain integers between 0 and 255, got %s", item.Inspect())
+			}
+			buf[ix] = byte(n.Value)
+		}
+		return &object.String{Value: string(buf)}
+	default:
+		return newError("argument to `bytes` not supported, got %s", args[0].Type())
+	}
+}
+func (bb *BytesBuiltin) Name() string {
+	return "bytes"
+}
+
+// RunesBuiltin converts a string into an array with the integer value of each of its code points,
+// and an array of such integers back into a string.
+type RunesBuiltin struct{}
+
+func (rb *RunesBuiltin) Do(args ...object.Object) object.Object {
+	if ok, err := checkArgs(1, args); !ok {
+		return err
+	}
+	switch arg := args[0].(type) {
+	case *object.String:
+		items := make([]object.Object, 0, len(arg.Value))
+		for _, r := range arg.Value {
+			items = append(items, &object.Integer{Value: int64(r)})
+		}
+		return object.NewArray(items)
+	case *object.Array:
+		runes := make([]rune, arg.Len())
+		for ix, item := range arg.Items() {
+			n, ok := item.(*object.Integer)
+			if !ok || n.Value < 0 || n.Value > utf8.MaxRune {
+				return newError("argument to `runes` must contain valid code points, got %s", item.Inspect())
+			}
+			runes[ix] = rune(n.Value)
+		}
+		return &object.String{Value: string(runes)}
+	default:
+		return newError("argument to `runes` not supported, got %s", args[0].Type())
+	}
+}
+func (rb *RunesBuiltin) Name() string {
+	return "runes"
+}
+
+// StrBuiltin converts any value into a string using its Inspect form.
+type StrBuiltin struct{}
+
+func (sb *StrBuiltin) Do(args ...object.Object) object.Object {
+	if ok, err := checkArgs(1, args); !ok {
+		return err
+	}
+	if str, ok := args[0].(*object.String); ok {
+		return str
+	}
+	return &object.String{Value: args[0].Inspect()}
+}
+func (sb *StrBuiltin) Name() string {
+	return "str"
+}
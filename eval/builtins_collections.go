@@ -0,0 +1,497 @@
+package eval
+
+import (
+	"sort"
+
+	"github.com/manuelpepe/interpreter/object"
+)
+
+// arrayArg returns args[ix] as an array, failing if it is not one.
+func arrayArg(name string, args []object.Object, ix int) (*object.Array, *object.Error) {
+	arr, ok := args[ix].(*object.Array)
+	if !ok {
+		return nil, newError("argument %d to `%s` must be ARRAY, got %s", ix+1, name, args[ix].Type())
+	}
+	return arr, nil
+}
+
+// functionArg checks that args[ix] can be called, either a user defined function or a builtin.
+func functionArg(name string, args []object.Object, ix int) (object.Object, *object.Error) {
+	switch args[ix].(type) {
+	case *object.Function, *object.Builtin:
+		return args[ix], nil
+	default:
+		return nil, newError("argument %d to `%s` must be FUNCTION, got %s", ix+1, name, args[ix].Type())
+	}
+}
+
+// compareObjects orders two integers or two strings, returning a negative number if a < b,
+// zero if they are equal and a positive number if a > b.
+func compareObjects(a object.Object, b object.Object) (int, *object.Error) {
+	switch a := a.(type) {
+	case *object.Integer:
+		if b, ok := b.(*object.Integer); ok {
+			switch {
+			case a.Value < b.Value:
+				return -1, nil
+			case a.Value > b.Value:
+				return 1, nil
+			}
+			return 0, nil
+		}
+	case *object.String:
+		if b, ok := b.(*object.String); ok {
+			switch {
+			case a.Value < b.Value:
+				return -1, nil
+			case a.Value > b.Value:
+				return 1, nil
+			}
+			return 0, nil
+		}
+	}
+	return 0, newError("can't compare %s with %s", a.Type(), b.Type())
+}
+
+// MapBuiltin returns a new array with the result of calling a function on each item.
+type MapBuiltin struct{}
+
+func (mb *MapBuiltin) Do(args ...object.Object) object.Object {
+	if ok, err := checkArgs(2, args); !ok {
+		return err
+	}
+	arr, err := arrayArg(mb.Name(), args, 0)
+	if err != nil {
+		return err
+	}
+	fn, err := functionArg(mb.Name(), args, 1)
+	if err != nil {
+		return err
+	}
+	items := make([]object.Object, arr.Len())
+	for ix, item := range arr.Items() {
+		res := applyFunction(fn, []object.Object{item})
+		if isError(res) {
+			return res
+		}
+		items[ix] = res
+	}
+	return object.NewArray(items)
+}
+func (mb *MapBuiltin) Name() string {
+	return "map"
+}
+
+// FilterBuiltin returns a new array with the items for which the function returns a truthy value.
+type FilterBuiltin struct{}
+
+func (fb *FilterBuiltin) Do(args ...object.Object) object.Object {
+	if ok, err := checkArgs(2, args); !ok {
+		return err
+	}
+	arr, err := arrayArg(fb.Name(), args, 0)
+	if err != nil {
+		return err
+	}
+	fn, err := functionArg(fb.Name(), args, 1)
+	if err != nil {
+		return err
+	}
+	items := make([]object.Object, 0)
+	for _, item := range arr.Items() {
+		res := applyFunction(fn, []object.Object{item})
+		if isError(res) {
+			return res
+		}
+		if isTruthy(res) {
+			items = append(items, item)
+		}
+	}
+	return object.NewArray(items)
+}
+func (fb *FilterBuiltin) Name() string {
+	return "filter"
+}
+
+// ReduceBuiltin folds an array into a single value calling `f(accumulated, item)` for each item.
+type ReduceBuiltin struct{}
+
+func (rb *ReduceBuiltin) Do(args ...object.Object) object.Object {
+	if ok, err := checkArgs(3, args); !ok {
+		return err
+	}
+	arr, err := arrayArg(rb.Name(), args, 0)
+	if err != nil {
+		return err
+	}
+	fn, err := functionArg(rb.Name(), args, 2)
+	if err != nil {
+		return err
+	}
+	acc := args[1]
+	for _, item := range arr.Items() {
+		acc = applyFunction(fn, []object.Object{acc, item})
+		if isError(acc) {
+			return acc
+		}
+	}
+	return acc
+}
+func (rb *ReduceBuiltin) Name() string {
+	return "reduce"
+}
+
+// SortBuiltin returns a sorted copy of an array. without a comparator the array must contain only
+// integers or only strings, otherwise `cmp(a, b)` must return a truthy value if a goes before b.
+type SortBuiltin struct{}
+
+func (sb *SortBuiltin) Do(args ...object.Object) object.Object {
+	if ok, err := checkArgsRange(1, 2, args); !ok {
+		return err
+	}
+	arr, err := arrayArg(sb.Name(), args, 0)
+	if err != nil {
+		return err
+	}
+	var cmp object.Object
+	if len(args) == 2 {
+		if cmp, err = functionArg(sb.Name(), args, 1); err != nil {
+			return err
+		}
+	}
+
+	items := arr.Items()
+
+	var sortErr object.Object
+	sort.SliceStable(items, func(i, j int) bool {
+		if sortErr != nil {
+			return false
+		}
+		if cmp == nil {
+			order, err := compareObjects(items[i], items[j])
+			if err != nil {
+				sortErr = err
+			}
+			return order < 0
+		}
+		res := applyFunction(cmp, []object.Object{items[i], items[j]})
+		if isError(res) {
+			sortErr = res
+			return false
+		}
+		return isTruthy(res)
+	})
+	if sortErr != nil {
+		return sortErr
+	}
+
+	return object.NewArray(items)
+}
+func (sb *SortBuiltin) Name() string {
+	return "sort"
+}
+
+// ReverseBuiltin returns a reversed copy of an array or string.
+type ReverseBuiltin struct{}
+
+func (rb *ReverseBuiltin) Do(args ...object.Object) object.Object {
+	if ok, err := checkArgs(1, args); !ok {
+		return err
+	}
+	switch arg := args[0].(type) {
+	case *object.Array:
+		length := arg.Len()
+		items := make([]object.Object, length)
+		for ix, item := range arg.Items() {
+			items[length-ix-1] = item
+		}
+		return object.NewArray(items)
+	case *object.String:
+		runes := []rune(arg.Value)
+		for i, j := 0, len(runes)-1; i < j; i, j = i+1, j-1 {
+			runes[i], runes[j] = runes[j], runes[i]
+		}
+		return &object.String{Value: string(runes)}
+	default:
+		return newError("argument to `reverse` not supported, got %s", args[0].Type())
+	}
+}
+func (rb *ReverseBuiltin) Name() string {
+	return "reverse"
+}
+
+// maxRangeSize is the largest array `range` will build, so that scripts can't exhaust the memory
+// of the host.
+const maxRangeSize = 1 << 24
+
+// rangeLen returns the number of items from start to end (excluded) by step, computed on unsigned
+// integers as the distance between the bounds may not fit in an int64.
+func rangeLen(start int64, end int64, step int64) uint64 {
+	if step > 0 && start < end {
+		return (uint64(end)-uint64(start)-1)/uint64(step) + 1
+	}
+	if step < 0 && start > end {
+		return (uint64(start)-uint64(end)-1)/(-uint64(step)) + 1
+	}
+	return 0
+}
+
+// RangeBuiltin generates an array of integers: `range(end)`, `range(start, end)` or `range(start, end, step)`.
+// end is never included.
+type RangeBuiltin struct{}
+
+func (rb *RangeBuiltin) Do(args ...object.Object) object.Object {
+	if ok, err := checkArgsRange(1, 3, args); !ok {
+		return err
+	}
+	bounds := make([]int64, len(args))
+	for ix := range args {
+		n, err := integerArg(rb.Name(), args, ix)
+		if err != nil {
+			return err
+		}
+		bounds[ix] = n
+	}
+
+	start, end, step := int64(0), bounds[0], int64(1)
+	if len(bounds) > 1 {
+		start, end = bounds[0], bounds[1]
+	}
+	if len(bounds) > 2 {
+		step = bounds[2]
+	}
+	if step == 0 {
+		return newError("argument 3 to `range` must not be zero")
+	}
+
+	count := rangeLen(start, end, step)
+	if count > maxRangeSize {
+		return newError("`range` would have %d items, more than the maximum of %d", count, maxRangeSize)
+	}
+	items := make([]object.Object, count)
+	for ix := range items {
+		items[ix] = &object.Integer{Value: start + int64(ix)*step}
+	}
+	return object.NewArray(items)
+}
+func (rb *RangeBuiltin) Name() string {
+	return "range"
+}
+
+// ZipBuiltin groups the items of many arrays by position, stopping at the shortest one.
+type ZipBuiltin struct{}
+
+func (zb *ZipBuiltin) Do(args ...object.Object) object.Object {
+	if len(args) < 1 {
+		return newError("wrong number of arguments. got=%d, want=1+", len(args))
+	}
+	arrs := make([]*object.Array, len(args))
+	length := -1
+	for ix := range args {
+		arr, err := arrayArg(zb.Name(), args, ix)
+		if err != nil {
+			return err
+		}
+		arrs[ix] = arr
+		if length < 0 || arr.Len() < length {
+			length = arr.Len()
+		}
+	}
+
+	items := make([]object.Object, length)
+	for ix := range items {
+		group := make([]object.Object, len(arrs))
+		for jx, arr := range arrs {
+			group[jx] = arr.Get(ix)
+		}
+		items[ix] = object.NewArray(group)
+	}
+	return object.NewArray(items)
+}
+func (zb *ZipBuiltin) Name() string {
+	return "zip"
+}
+
+// EnumerateBuiltin pairs each item of an array with its index: `[[0, a], [1, b], ...]`.
+type EnumerateBuiltin struct{}
+
+func (eb *EnumerateBuiltin) Do(args ...object.Object) object.Object {
+	if ok, err := checkArgs(1, args); !ok {
+		return err
+	}
+	arr, err := arrayArg(eb.Name(), args, 0)
+	if err != nil {
+		return err
+	}
+	items := make([]object.Object, arr.Len())
+	for ix, item := range arr.Items() {
+		items[ix] = object.NewArray([]object.Object{&object.Integer{Value: int64(ix)}, item})
+	}
+	return object.NewArray(items)
+}
+func (eb *EnumerateBuiltin) Name() string {
+	return "enumerate"
+}
+
+// PredicateBuiltin implements `any` and `all`. the items are tested for truthiness directly unless
+// a predicate function is given.
+type PredicateBuiltin struct {
+	All bool
+}
+
+func (pb *PredicateBuiltin) Do(args ...object.Object) object.Object {
+	if ok, err := checkArgsRange(1, 2, args); !ok {
+		return err
+	}
+	arr, err := arrayArg(pb.Name(), args, 0)
+	if err != nil {
+		return err
+	}
+	var fn object.Object
+	if len(args) == 2 {
+		if fn, err = functionArg(pb.Name(), args, 1); err != nil {
+			return err
+		}
+	}
+	for _, item := range arr.Items() {
+		res := item
+		if fn != nil {
+			res = applyFunction(fn, []object.Object{item})
+			if isError(res) {
+				return res
+			}
+		}
+		if isTruthy(res) != pb.All {
+			return evalBoolean(!pb.All)
+		}
+	}
+	return evalBoolean(pb.All)
+}
+func (pb *PredicateBuiltin) Name() string {
+	if pb.All {
+		return "all"
+	}
+	return "any"
+}
+
+// FlattenBuiltin returns a new array where all nested arrays are replaced by their items, recursively.
+type FlattenBuiltin struct{}
+
+func (fb *FlattenBuiltin) Do(args ...object.Object) object.Object {
+	if ok, err := checkArgs(1, args); !ok {
+		return err
+	}
+	arr, err := arrayArg(fb.Name(), args, 0)
+	if err != nil {
+		return err
+	}
+	return object.NewArray(flatten(make([]object.Object, 0, arr.Len()), arr))
+}
+func (fb *FlattenBuiltin) Name() string {
+	return "flatten"
+}
+
+func flatten(out []object.Object, arr *object.Array) []object.Object {
+	for _, item := range arr.Items() {
+		if nested, ok := item.(*object.Array); ok {
+			out = flatten(out, nested)
+		} else {
+			out = append(out, item)
+		}
+	}
+	return out
+}
+
+// UniqueBuiltin returns a new array without repeated items, keeping the first occurrence of each one.
+// all items must be usable as hash keys.
+type UniqueBuiltin struct{}
+
+func (ub *UniqueBuiltin) Do(args ...object.Object) object.Object {
+	if ok, err := checkArgs(1, args); !ok {
+		return err
+	}
+	arr, err := arrayArg(ub.Name(), args, 0)
+	if err != nil {
+		return err
+	}
+	seen := object.NewHash()
+	items := make([]object.Object, 0)
+	for _, item := range arr.Items() {
+		if _, found := seen.Get(item); found {
+			continue
+		}
+		if !seen.Set(item, TRUE) {
+			return newError("unusable as hash key: %s", item.Type())
+		}
+		items = append(items, item)
+	}
+	return object.NewArray(items)
+}
+func (ub *UniqueBuiltin) Name() string {
+	return "unique"
+}
+
+// ExtremeBuiltin implements `min` and `max` over an array of integers or strings, or over
+// its arguments if more than one is given. returns null for empty arrays.
+type ExtremeBuiltin struct {
+	Max bool
+}
+
+func (eb *ExtremeBuiltin) Do(args ...object.Object) object.Object {
+	if len(args) < 1 {
+		return newError("wrong number of arguments. got=%d, want=1+", len(args))
+	}
+	items := args
+	if len(args) == 1 {
+		arr, err := arrayArg(eb.Name(), args, 0)
+		if err != nil {
+			return err
+		}
+		items = arr.Items()
+	}
+	if len(items) == 0 {
+		return NULL
+	}
+	best := items[0]
+	for _, item := range items[1:] {
+		order, err := compareObjects(item, best)
+		if err != nil {
+			return err
+		}
+		if eb.Max && order > 0 || !eb.Max && order < 0 {
+			best = item
+		}
+	}
+	return best
+}
+func (eb *ExtremeBuiltin) Name() string {
+	if eb.Max {
+		return "max"
+	}
+	return "min"
+}
+
+// SumBuiltin adds up an array of integers.
+type SumBuiltin struct{}
+
+func (sb *SumBuiltin) Do(args ...object.Object) object.Object {
+	if ok, err := checkArgs(1, args); !ok {
+		return err
+	}
+	arr, err := arrayArg(sb.Name(), args, 0)
+	if err != nil {
+		return err
+	}
+	var total int64
+	for _, item := range arr.Items() {
+		n, ok := item.(*object.Integer)
+		if !ok {
+			return newError("argument 1 to `sum` must contain only INTEGER, got %s", item.Type())
+		}
+		total += n.Value
+	}
+	return &object.Integer{Value: total}
+}
+func (sb *SumBuiltin) Name() string {
+	return "sum"
+}
@@ -0,0 +1,219 @@
+package eval
+
+import (
+	"github.com/manuelpepe/interpreter/object"
+)
+
+// hashArg returns args[ix] as a hash, failing if it is not one.
+func hashArg(name string, args []object.Object, ix int) (*object.Hash, *object.Error) {
+	hash, ok := args[ix].(*object.Hash)
+	if !ok {
+		return nil, newError("argument %d to `%s` must be HASHMAP, got %s", ix+1, name, args[ix].Type())
+	}
+	return hash, nil
+}
+
+// hashKeyArg returns args[ix], failing if it can't be used as a hash key.
+func hashKeyArg(args []object.Object, ix int) (object.Object, *object.Error) {
+	if _, ok := object.HashKeyOf(args[ix]); !ok {
+		return nil, newError("unusable as hash key: %s", args[ix].Type())
+	}
+	return args[ix], nil
+}
+
+// KeysBuiltin returns an array with the keys of a hash in insertion order.
+type KeysBuiltin struct{}
+
+func (kb *KeysBuiltin) Do(args ...object.Object) object.Object {
+	if ok, err := checkArgs(1, args); !ok {
+		return err
+	}
+	hash, err := hashArg(kb.Name(), args, 0)
+	if err != nil {
+		return err
+	}
+	items := make([]object.Object, 0, hash.Len())
+	for _, pair := range hash.Items() {
+		items = append(items, pair.Key)
+	}
+	return object.NewArray(items)
+}
+func (kb *KeysBuiltin) Name() string {
+	return "keys"
+}
+
+// ValuesBuiltin returns an array with the values of a hash in insertion order.
+type ValuesBuiltin struct{}
+
+func (vb *ValuesBuiltin) Do(args ...object.Object) object.Object {
+	if ok, err := checkArgs(1, args); !ok {
+		return err
+	}
+	hash, err := hashArg(vb.Name(), args, 0)
+	if err != nil {
+		return err
+	}
+	items := make([]object.Object, 0, hash.Len())
+	for _, pair := range hash.Items() {
+		items = append(items, pair.Value)
+	}
+	return object.NewArray(items)
+}
+func (vb *ValuesBuiltin) Name() string {
+	return "values"
+}
+
+// ItemsBuiltin returns an array of `[key, value]` pairs in insertion order.
+type ItemsBuiltin struct{}
+
+func (ib *ItemsBuiltin) Do(args ...object.Object) object.Object {
+	if ok, err := checkArgs(1, args); !ok {
+		return err
+	}
+	hash, err := hashArg(ib.Name(), args, 0)
+	if err != nil {
+		return err
+	}
+	items := make([]object.Object, 0, hash.Len())
+	for _, pair := range hash.Items() {
+		items = append(items, object.NewArray([]object.Object{pair.Key, pair.Value}))
+	}
+	return object.NewArray(items)
+}
+func (ib *ItemsBuiltin) Name() string {
+	return "items"
+}
+
+type HasBuiltin struct{}
+
+func (hb *HasBuiltin) Do(args ...object.Object) object.Object {
+	if ok, err := checkArgs(2, args); !ok {
+		return err
+	}
+	hash, err := hashArg(hb.Name(), args, 0)
+	if err != nil {
+		return err
+	}
+	key, err := hashKeyArg(args, 1)
+	if err != nil {
+		return err
+	}
+	_, found := hash.Get(key)
+	return evalBoolean(found)
+}
+func (hb *HasBuiltin) Name() string {
+	return "has"
+}
+
+// DeleteBuiltin returns a copy of a hash without the given key.
+type DeleteBuiltin struct{}
+
+func (db *DeleteBuiltin) Do(args ...object.Object) object.Object {
+	if ok, err := checkArgs(2, args); !ok {
+		return err
+	}
+	hash, err := hashArg(db.Name(), args, 0)
+	if err != nil {
+		return err
+	}
+	key, err := hashKeyArg(args, 1)
+	if err != nil {
+		return err
+	}
+	out := hash.Copy()
+	out.Delete(key)
+	return out
+}
+func (db *DeleteBuiltin) Name() string {
+	return "delete"
+}
+
+// MergeBuiltin returns a new hash with the pairs of all the given hashes. when a key is repeated
+// the value of the last hash wins, but the key keeps the position where it was first seen.
+type MergeBuiltin struct{}
+
+func (mb *MergeBuiltin) Do(args ...object.Object) object.Object {
+	if len(args) < 1 {
+		return newError("wrong number of arguments. got=%d, want=1+", len(args))
+	}
+	first, err := hashArg(mb.Name(), args, 0)
+	if err != nil {
+		return err
+	}
+	out := first.Copy()
+	for ix := 1; ix < len(args); ix++ {
+		hash, err := hashArg(mb.Name(), args, ix)
+		if err != nil {
+			return err
+		}
+		for _, pair := range hash.Items() {
+			out.Set(pair.Key, pair.Value)
+		}
+	}
+	return out
+}
+func (mb *MergeBuiltin) Name() string {
+	return "merge"
+}
+
+// SetBuiltin returns a copy of a hash or array with the value for the given key or index replaced.
+// arrays accept negative indexes counting from the end.
+type SetBuiltin struct{}
+
+func (sb *SetBuiltin) Do(args ...object.Object) object.Object {
+	if ok, err := checkArgs(3, args); !ok {
+		return err
+	}
+	switch coll := args[0].(type) {
+	case *object.Hash:
+		key, err := hashKeyArg(args, 1)
+		if err != nil {
+			return err
+		}
+		out := coll.Copy()
+		out.Set(key, args[2])
+		return out
+	case *object.Array:
+		ix, err := integerArg(sb.Name(), args, 1)
+		if err != nil {
+			return err
+		}
+		pos, ok := normalizeIndex(ix, coll.Len())
+		if !ok {
+			return newError("index out of range: %d", ix)
+		}
+		return coll.Set(pos, args[2])
+	default:
+		return newError("argument to `set` not supported, got %s", args[0].Type())
+	}
+}
+func (sb *SetBuiltin) Name() string {
+	return "set"
+}
+
+// GetBuiltin looks up a key in a hash returning a default value (or null) if it is missing.
+type GetBuiltin struct{}
+
+func (gb *GetBuiltin) Do(args ...object.Object) object.Object {
+	if ok, err := checkArgsRange(2, 3, args); !ok {
+		return err
+	}
+	hash, err := hashArg(gb.Name(), args, 0)
+	if err != nil {
+		return err
+	}
+	key, err := hashKeyArg(args, 1)
+	if err != nil {
+		return err
+	}
+	if val, found := hash.Get(key); found {
+		return val
+	}
+	if len(args) == 3 {
+		return args[2]
+	}
+	return NULL
+}
+func (gb *GetBuiltin) Name() string {
+	return "get"
+}
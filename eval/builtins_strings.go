@@ -0,0 +1,405 @@
+package eval
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/manuelpepe/interpreter/object"
+)
+
+// maxStringSize is the size in bytes of the largest string builtins like `repeat` will build, so
+// that scripts can't exhaust the memory of the host.
+const maxStringSize = 1 << 28
+
+// checkArgsRange is like checkArgs but accepts any number of arguments between min and max (inclusive).
+func checkArgsRange(min int, max int, args []object.Object) (bool, *object.Error) {
+	if len(args) < min || len(args) > max {
+		return false, newError("wrong number of arguments. got=%d, want=%d..%d", len(args), min, max)
+	}
+	return true, nil
+}
+
+// stringArg returns the value of args[ix], failing if it is not a string.
+func stringArg(name string, args []object.Object, ix int) (string, *object.Error) {
+	str, ok := args[ix].(*object.String)
+	if !ok {
+		return "", newError("argument %d to `%s` must be STRING, got %s", ix+1, name, args[ix].Type())
+	}
+	return str.Value, nil
+}
+
+// integerArg returns the value of args[ix], failing if it is not an integer.
+func integerArg(name string, args []object.Object, ix int) (int64, *object.Error) {
+	n, ok := args[ix].(*object.Integer)
+	if !ok {
+		return 0, newError("argument %d to `%s` must be INTEGER, got %s", ix+1, name, args[ix].Type())
+	}
+	return n.Value, nil
+}
+
+// stringArgs returns the values of all args, failing if any of them is not a string.
+func stringArgs(name string, args []object.Object) ([]string, *object.Error) {
+	out := make([]string, len(args))
+	for ix := range args {
+		str, err := stringArg(name, args, ix)
+		if err != nil {
+			return nil, err
+		}
+		out[ix] = str
+	}
+	return out, nil
+}
+
+func newStringArray(values []string) *object.Array {
+	items := make([]object.Object, len(values))
+	for ix, v := range values {
+		items[ix] = &object.String{Value: v}
+	}
+	return object.NewArray(items)
+}
+
+// SplitBuiltin splits a string by a separator, or by whitespace if no separator is given.
+type SplitBuiltin struct{}
+
+func (sb *SplitBuiltin) Do(args ...object.Object) object.Object {
+	if ok, err := checkArgsRange(1, 2, args); !ok {
+		return err
+	}
+	strs, err := stringArgs(sb.Name(), args)
+	if err != nil {
+		return err
+	}
+	if len(strs) == 1 {
+		return newStringArray(strings.Fields(strs[0]))
+	}
+	return newStringArray(strings.Split(strs[0], strs[1]))
+}
+func (sb *SplitBuiltin) Name() string {
+	return "split"
+}
+
+// JoinBuiltin concatenates an array of strings, optionally placing a separator between them.
+type JoinBuiltin struct{}
+
+func (jb *JoinBuiltin) Do(args ...object.Object) object.Object {
+	if ok, err := checkArgsRange(1, 2, args); !ok {
+		return err
+	}
+	arr, ok := args[0].(*object.Array)
+	if !ok {
+		return newError("argument 1 to `join` must be ARRAY, got %s", args[0].Type())
+	}
+	var sep string
+	if len(args) == 2 {
+		var err *object.Error
+		if sep, err = stringArg(jb.Name(), args, 1); err != nil {
+			return err
+		}
+	}
+	strs := make([]string, arr.Len())
+	for ix, item := range arr.Items() {
+		str, ok := item.(*object.String)
+		if !ok {
+			return newError("argument 1 to `join` must contain only STRING, got %s", item.Type())
+		}
+		strs[ix] = str.Value
+	}
+	return &object.String{Value: strings.Join(strs, sep)}
+}
+func (jb *JoinBuiltin) Name() string {
+	return "join"
+}
+
+// TrimBuiltin removes leading and trailing whitespace, or the characters in the given cutset.
+type TrimBuiltin struct{}
+
+func (tb *TrimBuiltin) Do(args ...object.Object) object.Object {
+	if ok, err := checkArgsRange(1, 2, args); !ok {
+		return err
+	}
+	strs, err := stringArgs(tb.Name(), args)
+	if err != nil {
+		return err
+	}
+	if len(strs) == 1 {
+		return &object.String{Value: strings.TrimSpace(strs[0])}
+	}
+	return &object.String{Value: strings.Trim(strs[0], strs[1])}
+}
+func (tb *TrimBuiltin) Name() string {
+	return "trim"
+}
+
+type UpperBuiltin struct{}
+
+func (ub *UpperBuiltin) Do(args ...object.Object) object.Object {
+	if ok, err := checkArgs(1, args); !ok {
+		return err
+	}
+	str, err := stringArg(ub.Name(), args, 0)
+	if err != nil {
+		return err
+	}
+	return &object.String{Value: strings.ToUpper(str)}
+}
+func (ub *UpperBuiltin) Name() string {
+	return "upper"
+}
+
+type LowerBuiltin struct{}
+
+func (lb *LowerBuiltin) Do(args ...object.Object) object.Object {
+	if ok, err := checkArgs(1, args); !ok {
+		return err
+	}
+	str, err := stringArg(lb.Name(), args, 0)
+	if err != nil {
+		return err
+	}
+	return &object.String{Value: strings.ToLower(str)}
+}
+func (lb *LowerBuiltin) Name() string {
+	return "lower"
+}
+
+// ReplaceBuiltin replaces occurrences of a substring, all of them unless a maximum count is given.
+type ReplaceBuiltin struct{}
+
+func (rb *ReplaceBuiltin) Do(args ...object.Object) object.Object {
+	if ok, err := checkArgsRange(3, 4, args); !ok {
+		return err
+	}
+	strs, err := stringArgs(rb.Name(), args[:3])
+	if err != nil {
+		return err
+	}
+	n := int64(-1)
+	if len(args) == 4 {
+		if n, err = integerArg(rb.Name(), args, 3); err != nil {
+			return err
+		}
+	}
+	return &object.String{Value: strings.Replace(strs[0], strs[1], strs[2], int(n))}
+}
+func (rb *ReplaceBuiltin) Name() string {
+	return "replace"
+}
+
+type ContainsBuiltin struct{}
+
+func (cb *ContainsBuiltin) Do(args ...object.Object) object.Object {
+	if ok, err := checkArgs(2, args); !ok {
+		return err
+	}
+	strs, err := stringArgs(cb.Name(), args)
+	if err != nil {
+		return err
+	}
+	return evalBoolean(strings.Contains(strs[0], strs[1]))
+}
+func (cb *ContainsBuiltin) Name() string {
+	return "contains"
+}
+
+type StartsWithBuiltin struct{}
+
+func (sb *StartsWithBuiltin) Do(args ...object.Object) object.Object {
+	if ok, err := checkArgs(2, args); !ok {
+		return err
+	}
+	strs, err := stringArgs(sb.Name(), args)
+	if err != nil {
+		return err
+	}
+	return evalBoolean(strings.HasPrefix(strs[0], strs[1]))
+}
+func (sb *StartsWithBuiltin) Name() string {
+	return "starts_with"
+}
+
+type EndsWithBuiltin struct{}
+
+func (eb *EndsWithBuiltin) Do(args ...object.Object) object.Object {
+	if ok, err := checkArgs(2, args); !ok {
+		return err
+	}
+	strs, err := stringArgs(eb.Name(), args)
+	if err != nil {
+		return err
+	}
+	return evalBoolean(strings.HasSuffix(strs[0], strs[1]))
+}
+func (eb *EndsWithBuiltin) Name() string {
+	return "ends_with"
+}
+
+// IndexOfBuiltin returns the position (in code points) of the first occurrence of a substring, or -1.
+type IndexOfBuiltin struct{}
+
+func (ib *IndexOfBuiltin) Do(args ...object.Object) object.Object {
+	if ok, err := checkArgs(2, args); !ok {
+		return err
+	}
+	strs, err := stringArgs(ib.Name(), args)
+	if err != nil {
+		return err
+	}
+	pos := strings.Index(strs[0], strs[1])
+	if pos < 0 {
+		return &object.Integer{Value: -1}
+	}
+	return &object.Integer{Value: int64(utf8.RuneCountInString(strs[0][:pos]))}
+}
+func (ib *IndexOfBuiltin) Name() string {
+	return "index_of"
+}
+
+type RepeatBuiltin struct{}
+
+func (rb *RepeatBuiltin) Do(args ...object.Object) object.Object {
+	if ok, err := checkArgs(2, args); !ok {
+		return err
+	}
+	str, err := stringArg(rb.Name(), args, 0)
+	if err != nil {
+		return err
+	}
+	n, err := integerArg(rb.Name(), args, 1)
+	if err != nil {
+		return err
+	}
+	if n < 0 {
+		return newError("argument 2 to `repeat` must not be negative, got %d", n)
+	}
+	if len(str) != 0 && n > int64(maxStringSize/len(str)) {
+		return newError("result of `repeat` would exceed %d bytes", maxStringSize)
+	}
+	return &object.String{Value: strings.Repeat(str, int(n))}
+}
+func (rb *RepeatBuiltin) Name() string {
+	return "repeat"
+}
+
+// PadBuiltin fills a string up to the given width (in code points) with spaces or the given padding.
+// Left controls whether the padding is added before or after the string.
+type PadBuiltin struct {
+	Left bool
+}
+
+func (pb *PadBuiltin) Do(args ...object.Object) object.Object {
+	if ok, err := checkArgsRange(2, 3, args); !ok {
+		return err
+	}
+	str, err := stringArg(pb.Name(), args, 0)
+	if err != nil {
+		return err
+	}
+	width, err := integerArg(pb.Name(), args, 1)
+	if err != nil {
+		return err
+	}
+	pad := " "
+	if len(args) == 3 {
+		if pad, err = stringArg(pb.Name(), args, 2); err != nil {
+			return err
+		}
+		if pad == "" {
+			return newError("argument 3 to `%s` must not be empty", pb.Name())
+		}
+	}
+
+	if width > maxStringSize {
+		return newError("result of `%s` would exceed %d bytes", pb.Name(), maxStringSize)
+	}
+	missing := int(width) - utf8.RuneCountInString(str)
+	if missing <= 0 {
+		return &object.String{Value: str}
+	}
+	// fill is made of whole copies of pad followed by the first runes of another one
+	padLen := utf8.RuneCountInString(pad)
+	whole, rest := missing/padLen, missing%padLen
+	prefix := ""
+	for ix := range pad {
+		if rest == 0 {
+			prefix = pad[:ix]
+			break
+		}
+		rest--
+	}
+	if len(str)+len(prefix) > maxStringSize || whole > (maxStringSize-len(str)-len(prefix))/len(pad) {
+		return newError("result of `%s` would exceed %d bytes", pb.Name(), maxStringSize)
+	}
+
+	var out strings.Builder
+	out.Grow(len(str) + whole*len(pad) + len(prefix))
+	if !pb.Left {
+		out.WriteString(str)
+	}
+	for i := 0; i < whole; i++ {
+		out.WriteString(pad)
+	}
+	out.WriteString(prefix)
+	if pb.Left {
+		out.WriteString(str)
+	}
+	return &object.String{Value: out.String()}
+}
+func (pb *PadBuiltin) Name() string {
+	if pb.Left {
+		return "pad_left"
+	}
+	return "pad_right"
+}
+
+// CharsBuiltin splits a string into an array with each of its characters.
+type CharsBuiltin struct{}
+
+func (cb *CharsBuiltin) Do(args ...object.Object) object.Object {
+	if ok, err := checkArgs(1, args); !ok {
+		return err
+	}
+	str, err := stringArg(cb.Name(), args, 0)
+	if err != nil {
+		return err
+	}
+	items := make([]object.Object, 0, len(str))
+	for _, r := range str {
+		items = append(items, &object.String{Value: string(r)})
+	}
+	return object.NewArray(items)
+}
+func (cb *CharsBuiltin) Name() string {
+	return "chars"
+}
+
+// FormatBuiltin formats its arguments printf-style. integers, strings and booleans are passed as their
+// Go values so verbs like %d, %5s or %t work, any other value is passed as its Inspect form.
+type FormatBuiltin struct{}
+
+func (fb *FormatBuiltin) Do(args ...object.Object) object.Object {
+	if len(args) < 1 {
+		return newError("wrong number of arguments. got=%d, want=1+", len(args))
+	}
+	format, err := stringArg(fb.Name(), args, 0)
+	if err != nil {
+		return err
+	}
+	values := make([]any, len(args)-1)
+	for ix, arg := range args[1:] {
+		switch arg := arg.(type) {
+		case *object.Integer:
+			values[ix] = arg.Value
+		case *object.String:
+			values[ix] = arg.Value
+		case *object.Boolean:
+			values[ix] = arg.Value
+		default:
+			values[ix] = arg.Inspect()
+		}
+	}
+	return &object.String{Value: fmt.Sprintf(format, values...)}
+}
+func (fb *FormatBuiltin) Name() string {
+	return "format"
+}
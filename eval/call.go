@@ -0,0 +1,51 @@
+package eval
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/manuelpepe/interpreter/object"
+)
+
+// Call calls a function or builtin with the given arguments, as a program would. user functions run
+// in the runtime of the environment where they were defined, so calls made from builtins while a
+// program runs share its context, limits and debugger. the result is null instead of nil if the
+// function doesn't produce a value, and an *object.Error result is returned as the error.
+func Call(fn object.Object, args ...object.Object) (object.Object, error) {
+	switch fn.(type) {
+	case *object.Function, *object.Builtin:
+	case nil:
+		return nil, fmt.Errorf("not a function: nil")
+	default:
+		return nil, fmt.Errorf("not a function: %s", fn.Type())
+	}
+
+	res := applyFunction(fn, args)
+	if err, ok := res.(*object.Error); ok {
+		return nil, err
+	}
+	if res == nil {
+		return NULL, nil
+	}
+	return res, nil
+}
+
+// CallContext is like Call, but stops a user function when ctx is done, returning ctx.Err().
+// builtins are only checked before calling them.
+func CallContext(ctx context.Context, fn object.Object, args ...object.Object) (object.Object, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if f, ok := fn.(*object.Function); ok {
+		rt := f.Env.Runtime()
+		prev := rt.Context
+		rt.Context = ctx
+		defer func() { rt.Context = prev }()
+	}
+
+	res, err := Call(fn, args...)
+	if err != nil && ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	return res, err
+}
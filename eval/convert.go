@@ -0,0 +1,479 @@
+package eval
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+
+	"github.com/manuelpepe/interpreter/object"
+)
+
+var objectType = reflect.TypeOf((*object.Object)(nil)).Elem()
+
+// ToObject converts a Go value into a Monkey value:
+//
+//   - nil, nil pointers, maps, slices and funcs become null
+//   - bools, strings and integers become their Monkey counterparts, floats must be whole numbers
+//   - slices and arrays become arrays, maps become hashes with their keys sorted
+//   - structs become hashes of their exported fields, named by the `monkey` tag if present, or
+//     skipped if the tag is "-"
+//   - funcs become builtins, see WrapFunc
+//   - pointers and interfaces are converted as the value they point to
+//   - values that already are an object.Object are returned as is
+//
+// values that contain themselves through pointers, maps or slices can't be converted.
+func ToObject(v any) (object.Object, error) {
+	return toObject(reflect.ValueOf(v))
+}
+
+func toObject(v reflect.Value) (object.Object, error) {
+	c := converter{visiting: make(map[visit]bool)}
+	return c.toObject(v)
+}
+
+// converter keeps track of the pointers, maps and slices being converted, to fail on values that
+// contain themselves instead of recursing forever.
+type converter struct {
+	visiting map[visit]bool
+}
+
+type visit struct {
+	ptr uintptr
+	typ reflect.Type
+	len int
+}
+
+// enter marks v as being converted, failing if it already is. leave must be called once it's done.
+func (c *converter) enter(v reflect.Value) (visit, error) {
+	key := visit{ptr: v.Pointer(), typ: v.Type()}
+	if v.Kind() == reflect.Slice {
+		key.len = v.Len()
+	}
+	if c.visiting[key] {
+		return key, fmt.Errorf("can't convert cyclic value of type %s", v.Type())
+	}
+	c.visiting[key] = true
+	return key, nil
+}
+
+func (c *converter) leave(key visit) {
+	delete(c.visiting, key)
+}
+
+func (c *converter) toObject(v reflect.Value) (object.Object, error) {
+	if !v.IsValid() {
+		return NULL, nil
+	}
+	if v.Type().Implements(objectType) {
+		if v.Kind() == reflect.Interface || v.Kind() == reflect.Pointer {
+			if v.IsNil() {
+				return NULL, nil
+			}
+		}
+		return v.Interface().(object.Object), nil
+	}
+
+	switch v.Kind() {
+	case reflect.Bool:
+		return evalBoolean(v.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return &object.Integer{Value: v.Int()}, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if v.Uint() > math.MaxInt64 {
+			return nil, fmt.Errorf("can't convert %d to INTEGER: out of range", v.Uint())
+		}
+		return &object.Integer{Value: int64(v.Uint())}, nil
+	case reflect.Float32, reflect.Float64:
+		f := v.Float()
+		if f != math.Trunc(f) || f < math.MinInt64 || f >= math.MaxInt64 {
+			return nil, fmt.Errorf("can't convert %v to INTEGER: not a whole number", f)
+		}
+		return &object.Integer{Value: int64(f)}, nil
+	case reflect.String:
+		return &object.String{Value: v.String()}, nil
+	case reflect.Interface:
+		if v.IsNil() {
+			return NULL, nil
+		}
+		return c.toObject(v.Elem())
+	case reflect.Pointer:
+		if v.IsNil() {
+			return NULL, nil
+		}
+		key, err := c.enter(v)
+		if err != nil {
+			return nil, err
+		}
+		defer c.leave(key)
+		return c.toObject(v.Elem())
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice {
+			if v.IsNil() {
+				return NULL, nil
+			}
+			key, err := c.enter(v)
+			if err != nil {
+				return nil, err
+			}
+			defer c.leave(key)
+		}
+		items := make([]object.Object, v.Len())
+		for ix := range items {
+			item, err := c.toObject(v.Index(ix))
+			if err != nil {
+				return nil, err
+			}
+			items[ix] = item
+		}
+		return object.NewArray(items), nil
+	case reflect.Map:
+		if v.IsNil() {
+			return NULL, nil
+		}
+		entered, err := c.enter(v)
+		if err != nil {
+			return nil, err
+		}
+		defer c.leave(entered)
+		hash := object.NewHash()
+		for _, key := range sortedKeys(v) {
+			k, err := c.toObject(key)
+			if err != nil {
+				return nil, err
+			}
+			val, err := c.toObject(v.MapIndex(key))
+			if err != nil {
+				return nil, err
+			}
+			if !hash.Set(k, val) {
+				return nil, fmt.Errorf("can't use %s as hash key", k.Type())
+			}
+		}
+		return hash, nil
+	case reflect.Struct:
+		hash := object.NewHash()
+		for _, f := range fields(v.Type()) {
+			val, err := c.toObject(v.FieldByIndex(f.index))
+			if err != nil {
+				return nil, fmt.Errorf("field %s: %w", f.name, err)
+			}
+			hash.Set(&object.String{Value: f.name}, val)
+		}
+		return hash, nil
+	case reflect.Func:
+		if v.IsNil() {
+			return NULL, nil
+		}
+		return wrapFunc("func", v)
+	default:
+		return nil, fmt.Errorf("can't convert %s to a Monkey value", v.Type())
+	}
+}
+
+// FromObject converts a Monkey value into the Go value pointed to by target, following the same
+// rules as ToObject in reverse. integers are checked to fit in the target type, and functions and
+// builtins can be converted into funcs that call them through Call, panicking on errors unless the
+// func returns an error as its last result.
+//
+// when the target is an interface the values are converted to int64, string, bool, nil, []any,
+// map[any]any or left as the object.Object if there's no such conversion, eg. for functions.
+func FromObject(obj object.Object, target any) error {
+	ptr := reflect.ValueOf(target)
+	if ptr.Kind() != reflect.Pointer || ptr.IsNil() {
+		return fmt.Errorf("target must be a non nil pointer, got %T", target)
+	}
+	return fromObject(obj, ptr.Elem())
+}
+
+func fromObject(obj object.Object, v reflect.Value) error {
+	if obj == nil {
+		obj = NULL
+	}
+	typ := v.Type()
+	if typ == objectType {
+		v.Set(reflect.ValueOf(obj))
+		return nil
+	}
+	if reflect.TypeOf(obj).AssignableTo(typ) && typ.Kind() != reflect.Interface {
+		v.Set(reflect.ValueOf(obj))
+		return nil
+	}
+
+	if _, isNull := obj.(*object.Null); isNull {
+		switch typ.Kind() {
+		case reflect.Pointer, reflect.Interface, reflect.Slice, reflect.Map, reflect.Func:
+			v.SetZero()
+			return nil
+		}
+		return cantConvert(obj, typ)
+	}
+
+	switch typ.Kind() {
+	case reflect.Interface:
+		if typ.NumMethod() != 0 {
+			return cantConvert(obj, typ)
+		}
+		val, err := natural(obj)
+		if err != nil {
+			return err
+		}
+		if val == nil {
+			v.SetZero()
+		} else {
+			v.Set(reflect.ValueOf(val))
+		}
+		return nil
+	case reflect.Pointer:
+		elem := reflect.New(typ.Elem())
+		if err := fromObject(obj, elem.Elem()); err != nil {
+			return err
+		}
+		v.Set(elem)
+		return nil
+	case reflect.Func:
+		switch obj.(type) {
+		case *object.Function, *object.Builtin:
+			v.Set(goFunc(obj, typ))
+			return nil
+		}
+		return cantConvert(obj, typ)
+	}
+
+	switch obj := obj.(type) {
+	case *object.Integer:
+		switch typ.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			if v.OverflowInt(obj.Value) {
+				return fmt.Errorf("can't convert %d to %s: out of range", obj.Value, typ)
+			}
+			v.SetInt(obj.Value)
+			return nil
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+			if obj.Value < 0 || v.OverflowUint(uint64(obj.Value)) {
+				return fmt.Errorf("can't convert %d to %s: out of range", obj.Value, typ)
+			}
+			v.SetUint(uint64(obj.Value))
+			return nil
+		case reflect.Float32, reflect.Float64:
+			v.SetFloat(float64(obj.Value))
+			return nil
+		}
+	case *object.String:
+		if typ.Kind() == reflect.String {
+			v.SetString(obj.Value)
+			return nil
+		}
+	case *object.Boolean:
+		if typ.Kind() == reflect.Bool {
+			v.SetBool(obj.Value)
+			return nil
+		}
+	case *object.Array:
+		switch typ.Kind() {
+		case reflect.Slice:
+			v.Set(reflect.MakeSlice(typ, obj.Len(), obj.Len()))
+		case reflect.Array:
+			if typ.Len() != obj.Len() {
+				return fmt.Errorf("can't convert array of %d items to %s", obj.Len(), typ)
+			}
+		default:
+			return cantConvert(obj, typ)
+		}
+		for ix, item := range obj.Items() {
+			if err := fromObject(item, v.Index(ix)); err != nil {
+				return fmt.Errorf("item %d: %w", ix, err)
+			}
+		}
+		return nil
+	case *object.Hash:
+		switch typ.Kind() {
+		case reflect.Map:
+			m := reflect.MakeMapWithSize(typ, obj.Len())
+			for _, pair := range obj.Items() {
+				key := reflect.New(typ.Key()).Elem()
+				if err := fromObject(pair.Key, key); err != nil {
+					return fmt.Errorf("key %s: %w", object.Repr(pair.Key), err)
+				}
+				val := reflect.New(typ.Elem()).Elem()
+				if err := fromObject(pair.Value, val); err != nil {
+					return fmt.Errorf("key %s: %w", object.Repr(pair.Key), err)
+				}
+				m.SetMapIndex(key, val)
+			}
+			v.Set(m)
+			return nil
+		case reflect.Struct:
+			for _, f := range fields(typ) {
+				val, ok := obj.Get(&object.String{Value: f.name})
+				if !ok {
+					continue
+				}
+				if err := fromObject(val, v.FieldByIndex(f.index)); err != nil {
+					return fmt.Errorf("field %s: %w", f.name, err)
+				}
+			}
+			return nil
+		}
+	}
+	return cantConvert(obj, typ)
+}
+
+// natural converts obj into the Go value used for it when the target type is unknown.
+func natural(obj object.Object) (any, error) {
+	switch obj := obj.(type) {
+	case *object.Null:
+		return nil, nil
+	case *object.Integer:
+		return obj.Value, nil
+	case *object.String:
+		return obj.Value, nil
+	case *object.Boolean:
+		return obj.Value, nil
+	case *object.Array:
+		out := make([]any, obj.Len())
+		for ix, item := range obj.Items() {
+			val, err := natural(item)
+			if err != nil {
+				return nil, err
+			}
+			out[ix] = val
+		}
+		return out, nil
+	case *object.Hash:
+		out := make(map[any]any, obj.Len())
+		for _, pair := range obj.Items() {
+			if _, ok := pair.Key.(*object.Array); ok {
+				return nil, fmt.Errorf("can't use ARRAY as map key")
+			}
+			key, _ := natural(pair.Key)
+			val, err := natural(pair.Value)
+			if err != nil {
+				return nil, err
+			}
+			out[key] = val
+		}
+		return out, nil
+	default:
+		return obj, nil
+	}
+}
+
+// goFunc returns a func of type typ that calls fn, converting its arguments and result.
+func goFunc(fn object.Object, typ reflect.Type) reflect.Value {
+	return reflect.MakeFunc(typ, func(in []reflect.Value) []reflect.Value {
+		out := make([]reflect.Value, typ.NumOut())
+		for ix := range out {
+			out[ix] = reflect.New(typ.Out(ix)).Elem()
+		}
+		fail := func(err error) []reflect.Value {
+			if typ.NumOut() == 0 || typ.Out(typ.NumOut()-1) != errorType {
+				panic(err)
+			}
+			out[len(out)-1] = reflect.ValueOf(&err).Elem()
+			return out
+		}
+
+		args := make([]object.Object, 0, len(in))
+		for ix, arg := range in {
+			if typ.IsVariadic() && ix == len(in)-1 {
+				for jx := range arg.Len() {
+					obj, err := toObject(arg.Index(jx))
+					if err != nil {
+						return fail(err)
+					}
+					args = append(args, obj)
+				}
+				break
+			}
+			obj, err := toObject(arg)
+			if err != nil {
+				return fail(err)
+			}
+			args = append(args, obj)
+		}
+
+		res, err := Call(fn, args...)
+		if err != nil {
+			return fail(err)
+		}
+		if typ.NumOut() > 0 && typ.Out(0) != errorType {
+			if err := fromObject(res, out[0]); err != nil {
+				return fail(err)
+			}
+		}
+		return out
+	})
+}
+
+func cantConvert(obj object.Object, typ reflect.Type) error {
+	return fmt.Errorf("can't convert %s to %s", obj.Type(), typ)
+}
+
+type field struct {
+	name  string
+	index []int
+}
+
+// fields returns the exported fields of a struct type that are converted to and from hashes.
+func fields(typ reflect.Type) []field {
+	var out []field
+	for _, f := range reflect.VisibleFields(typ) {
+		if !f.IsExported() || f.Anonymous || throughPointer(typ, f.Index) {
+			continue
+		}
+		name := f.Name
+		if tag, ok := f.Tag.Lookup("monkey"); ok {
+			if tag == "-" {
+				continue
+			}
+			if tag != "" {
+				name = tag
+			}
+		}
+		out = append(out, field{name: name, index: f.Index})
+	}
+	return out
+}
+
+// throughPointer reports whether a promoted field is reached through an embedded pointer, which
+// might be nil.
+func throughPointer(typ reflect.Type, index []int) bool {
+	for _, ix := range index[:len(index)-1] {
+		typ = typ.Field(ix).Type
+		if typ.Kind() == reflect.Pointer {
+			return true
+		}
+	}
+	return false
+}
+
+// sortedKeys returns the keys of a map in a stable order, so converted hashes are deterministic.
+func sortedKeys(m reflect.Value) []reflect.Value {
+	keys := m.MapKeys()
+	sort.Slice(keys, func(i, j int) bool {
+		a, b := keys[i], keys[j]
+		for a.Kind() == reflect.Interface && !a.IsNil() {
+			a = a.Elem()
+		}
+		for b.Kind() == reflect.Interface && !b.IsNil() {
+			b = b.Elem()
+		}
+		if a.Kind() == b.Kind() {
+			switch a.Kind() {
+			case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+				return a.Int() < b.Int()
+			case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+				return a.Uint() < b.Uint()
+			case reflect.Float32, reflect.Float64:
+				return a.Float() < b.Float()
+			case reflect.String:
+				return a.String() < b.String()
+			case reflect.Bool:
+				return !a.Bool() && b.Bool()
+			}
+		}
+		return fmt.Sprint(a) < fmt.Sprint(b)
+	})
+	return keys
+}
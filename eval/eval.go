@@ -1,12 +1,27 @@
+// Package eval evaluates Monkey programs.
+//
+// The evaluator keeps no mutable global state: everything that changes while code runs lives in the
+// environments and their object.Runtime, so goroutines can evaluate programs at the same time as long
+// as each uses its own global environment. The only shared values are the default builtins and the
+// NULL, TRUE and FALSE singletons, which are never modified.
+//
+// Parsed programs are annotated by the resolver the first time they are evaluated, so a program must
+// be resolved (see resolver.Resolve) before evaluating it from many goroutines. after that the
+// evaluator only reads it.
 package eval
 
 import (
 	"fmt"
+	"strings"
+	"unicode/utf8"
 
 	"github.com/manuelpepe/interpreter/ast"
 	"github.com/manuelpepe/interpreter/object"
+	"github.com/manuelpepe/interpreter/resolver"
 )
 
+// NULL, TRUE and FALSE are shared by all evaluations, which compare values against them by pointer.
+// they are immutable: their fields must never be modified.
 var (
 	NULL  = &object.Null{}
 	TRUE  = &object.Boolean{Value: true}
@@ -17,13 +32,21 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 	switch node := node.(type) {
 	// Statements
 	case *ast.Program:
+		resolver.Resolve(node)
 		return evalProgram(node, env)
 	case *ast.LetStatement:
 		val := Eval(node.Value, env)
 		if isError(val) {
 			return val
 		}
-		env.Set(node.Name.String(), val)
+		if fn, ok := val.(*object.Function); ok && fn.Name == "" {
+			fn.Name = node.Name.Value
+		}
+		if node.Name.Resolved && node.Name.Depth == 0 {
+			env.SetSlot(node.Name.Slot, val)
+		} else {
+			env.Set(node.Name.Value, val)
+		}
 	case *ast.ExpressionStatement:
 		return Eval(node.Expression, env)
 	case *ast.BlockStatement:
@@ -42,6 +65,8 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 		return &object.Integer{Value: node.Value}
 	case *ast.StringLiteral:
 		return &object.String{Value: node.Value}
+	case *ast.InterpolatedString:
+		return evalInterpolatedString(node, env)
 	case *ast.Boolean:
 		return evalBoolean(node.Value)
 	case *ast.ArrayLiteral:
@@ -49,7 +74,7 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 		if len(items) == 1 && isError(items[0]) {
 			return items[0]
 		}
-		return &object.Array{Items: items}
+		return object.NewArray(items)
 	case *ast.HashLiteral:
 		return evalHashLiteral(node, env)
 	case *ast.IndexExpression:
@@ -62,6 +87,8 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 			return ix
 		}
 		return evalIndexExpression(arr, ix)
+	case *ast.SliceExpression:
+		return evalSliceExpression(node, env)
 	case *ast.PrefixExpression:
 		right := Eval(node.Right, env)
 		if isError(right) {
@@ -81,9 +108,13 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 	case *ast.IfExpression:
 		return evalIfExpression(node.Condition, node.Consequence, node.Alternative, env)
 	case *ast.FunctionLiteral:
+		if node.Locals == nil {
+			resolver.ResolveFunction(node)
+		}
 		return &object.Function{
 			Parameters: node.Parameters,
 			Body:       node.Body,
+			Locals:     node.Locals,
 			Env:        env,
 		}
 	case *ast.CallExpression:
@@ -108,20 +139,31 @@ func evalIndexExpression(arr object.Object, ix object.Object) object.Object {
 		if !ok {
 			return newError("expected integer, got %s", ix.Type())
 		}
-		if cIx.Value < 0 || int(cIx.Value) >= len(cArr.Items) {
+		pos, ok := normalizeIndex(cIx.Value, cArr.Len())
+		if !ok {
 			return NULL
 		}
-		return cArr.Items[cIx.Value]
-	case *object.Hash:
-		cIx, ok := ix.(object.Hashable)
+		return cArr.Get(pos)
+	case *object.String:
+		cIx, ok := ix.(*object.Integer)
 		if !ok {
+			return newError("expected integer, got %s", ix.Type())
+		}
+		runes := []rune(cArr.Value)
+		pos, ok := normalizeIndex(cIx.Value, len(runes))
+		if !ok {
+			return NULL
+		}
+		return &object.String{Value: string(runes[pos])}
+	case *object.Hash:
+		if _, ok := object.HashKeyOf(ix); !ok {
 			return newError("unusable as hash key: %s", ix.Type())
 		}
-		val, found := cArr.Pairs[cIx.HashKey()]
+		val, found := cArr.Get(ix)
 		if !found {
 			return NULL
 		}
-		return val.Value
+		return val
 	default:
 		return newError("index operator not supported: %s", arr.Type())
 
@@ -129,6 +171,92 @@ func evalIndexExpression(arr object.Object, ix object.Object) object.Object {
 
 }
 
+func evalInterpolatedString(node *ast.InterpolatedString, env *object.Environment) object.Object {
+	var out strings.Builder
+	for _, part := range node.Parts {
+		if text, ok := part.(*ast.TemplateText); ok {
+			out.WriteString(text.Value)
+			continue
+		}
+		val := Eval(part, env)
+		if isError(val) {
+			return val
+		}
+		out.WriteString(val.Inspect())
+	}
+	return &object.String{Value: out.String()}
+}
+
+// normalizeIndex resolves negative indexes from the end of a sequence of the given length,
+// reporting whether the resulting position is within bounds.
+func normalizeIndex(ix int64, length int) (int, bool) {
+	if ix < 0 {
+		ix += int64(length)
+	}
+	if ix < 0 || ix >= int64(length) {
+		return 0, false
+	}
+	return int(ix), true
+}
+
+func evalSliceExpression(node *ast.SliceExpression, env *object.Environment) object.Object {
+	left := Eval(node.Left, env)
+	if isError(left) {
+		return left
+	}
+
+	var length int
+	switch left := left.(type) {
+	case *object.Array:
+		length = left.Len()
+	case *object.String:
+		length = utf8.RuneCountInString(left.Value)
+	default:
+		return newError("slice operator not supported: %s", left.Type())
+	}
+
+	start, err := evalSliceBound(node.Start, env, 0, length)
+	if err != nil {
+		return err
+	}
+	end, err := evalSliceBound(node.End, env, length, length)
+	if err != nil {
+		return err
+	}
+	if end < start {
+		end = start
+	}
+
+	switch left := left.(type) {
+	case *object.Array:
+		return left.Slice(start, end)
+	default:
+		runes := []rune(left.(*object.String).Value)
+		return &object.String{Value: string(runes[start:end])}
+	}
+}
+
+// evalSliceBound evaluates one of the bounds of a slice expression, using def if the bound was omitted.
+// negative bounds count from the end and the result is clamped to [0, length].
+func evalSliceBound(expr ast.Expression, env *object.Environment, def int, length int) (int, object.Object) {
+	if expr == nil {
+		return def, nil
+	}
+	val := Eval(expr, env)
+	if isError(val) {
+		return 0, val
+	}
+	n, ok := val.(*object.Integer)
+	if !ok {
+		return 0, newError("expected integer, got %s", val.Type())
+	}
+	bound := n.Value
+	if bound < 0 {
+		bound += int64(length)
+	}
+	return int(max(0, min(bound, int64(length)))), nil
+}
+
 func evalExpressionList(lst []ast.Expression, env *object.Environment) []object.Object {
 	out := make([]object.Object, len(lst))
 	for ix, item := range lst {
@@ -142,33 +270,29 @@ func evalExpressionList(lst []ast.Expression, env *object.Environment) []object.
 }
 
 func evalHashLiteral(node *ast.HashLiteral, env *object.Environment) object.Object {
-	hash := &object.Hash{Pairs: make(map[object.HashKey]object.HashPair)}
-	for keyExpr, valExpr := range node.Items {
-		key := Eval(keyExpr, env)
+	hash := object.NewHash()
+	for _, item := range node.Items {
+		key := Eval(item.Key, env)
 		if isError(key) {
 			return key
 		}
-		hashedKey, ok := key.(object.Hashable)
-		if !ok {
-			return newError("object is not hashable, got=%T", hash)
+		if _, ok := object.HashKeyOf(key); !ok {
+			return newError("unusable as hash key: %s", key.Type())
 		}
-		val := Eval(valExpr, env)
+		val := Eval(item.Value, env)
 		if isError(val) {
 			return val
 		}
-		hash.Pairs[hashedKey.HashKey()] = object.HashPair{
-			Key:   key,
-			Value: val,
-		}
+		hash.Set(key, val)
 	}
 	return hash
 
 }
 
 func extendFunctionEnv(fn *object.Function, args []object.Object) *object.Environment {
-	funcEnv := fn.Env.Enclose()
+	funcEnv := fn.Env.Enclose(fn.Locals)
 	for ix, arg := range args {
-		funcEnv.Set(fn.Parameters[ix].Value, arg)
+		funcEnv.SetSlot(fn.Parameters[ix].Slot, arg)
 	}
 	return funcEnv
 }
@@ -180,27 +304,65 @@ func unwrapReturnValue(val object.Object) object.Object {
 	return val
 }
 
+// Apply calls a function or builtin with the given arguments.
+func Apply(fn object.Object, args ...object.Object) object.Object {
+	return applyFunction(fn, args)
+}
+
 func applyFunction(fn object.Object, args []object.Object) object.Object {
 	switch fn := fn.(type) {
 	case *object.Builtin:
-		return fn.Fn.Do(args...)
+		return fn.Call(args...)
 	case *object.Function:
 		if len(fn.Parameters) != len(args) {
 			return newError("expected %d arguments, got %d", len(fn.Parameters), len(args))
 		}
+		rt := fn.Env.Runtime()
+		if rt.MaxDepth > 0 && rt.Depth >= rt.MaxDepth {
+			return newError("maximum call depth exceeded: %d", rt.MaxDepth)
+		}
+		rt.Depth++
+		defer func() { rt.Depth-- }()
+
 		newEnv := extendFunctionEnv(fn, args)
-		ret := Eval(fn.Body, newEnv)
-		return unwrapReturnValue(ret)
+		if rt.Debugger != nil {
+			rt.Debugger.Enter(fn, newEnv)
+		}
+		ret := unwrapReturnValue(Eval(fn.Body, newEnv))
+		if rt.Debugger != nil {
+			rt.Debugger.Leave(fn, ret)
+		}
+		return ret
 	default:
 		return newError("not a function: %s", fn.Type())
 	}
 }
 
 func evalIdentifier(node *ast.Identifier, env *object.Environment) object.Object {
-	if val, ok := env.Get(node.Value); ok {
-		return val
+	switch {
+	case node.Resolved && node.Depth == ast.GlobalDepth:
+		if val, ok := env.GetGlobal(node.Value); ok {
+			return val
+		}
+	case node.Resolved:
+		if val, ok := env.GetSlot(node.Depth, node.Slot); ok {
+			return val
+		}
+		// the slot is not set yet, eg. the binding is used before its let statement runs,
+		// so fall back to searching by name as if it wasn't resolved.
+		if val, ok := env.Get(node.Value); ok {
+			return val
+		}
+	default:
+		if val, ok := env.Get(node.Value); ok {
+			return val
+		}
+	}
+	registry := env.Runtime().Builtins
+	if registry == nil {
+		registry = builtins
 	}
-	if builtin, ok := builtins[node.Value]; ok {
+	if builtin, ok := registry.Lookup(node.Value); ok {
 		return builtin
 	}
 	return newError("identifier not found: %s", node.Value)
@@ -318,6 +480,9 @@ func evalMinusOperatorExpression(right object.Object) object.Object {
 func evalProgram(p *ast.Program, env *object.Environment) object.Object {
 	var result object.Object
 	for _, s := range p.Statements {
+		if err := step(s, env); err != nil {
+			return err
+		}
 		result = Eval(s, env)
 		switch result := result.(type) {
 		case *object.ReturnValue:
@@ -332,6 +497,9 @@ func evalProgram(p *ast.Program, env *object.Environment) object.Object {
 func evalBlockStatements(b *ast.BlockStatement, env *object.Environment) object.Object {
 	var result object.Object
 	for _, s := range b.Statements {
+		if err := step(s, env); err != nil {
+			return err
+		}
 		result = Eval(s, env)
 		if result != nil {
 			rt := result.Type()
@@ -343,6 +511,25 @@ func evalBlockStatements(b *ast.BlockStatement, env *object.Environment) object.
 	return result
 }
 
+// step is run before each statement, counting it against the limit of the runtime, checking its
+// context and notifying its debugger.
+func step(stmt ast.Statement, env *object.Environment) *object.Error {
+	rt := env.Runtime()
+	rt.Steps++
+	if rt.MaxSteps > 0 && rt.Steps > rt.MaxSteps {
+		return newError("step limit exceeded: %d", rt.MaxSteps)
+	}
+	if rt.Context != nil {
+		if err := rt.Context.Err(); err != nil {
+			return newError("evaluation stopped: %s", err)
+		}
+	}
+	if rt.Debugger != nil {
+		rt.Debugger.Statement(stmt, env)
+	}
+	return nil
+}
+
 func evalBoolean(b bool) *object.Boolean {
 	if b {
 		return TRUE
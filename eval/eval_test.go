@@ -1,8 +1,13 @@
 package eval
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
 	"testing"
 
+	"github.com/manuelpepe/interpreter/ast"
 	"github.com/manuelpepe/interpreter/lexer"
 	"github.com/manuelpepe/interpreter/object"
 	"github.com/manuelpepe/interpreter/parser"
@@ -41,6 +46,26 @@ func TestStringLiteral(t *testing.T) {
 	testStringLiteral(t, evaluated, "Hello World!")
 }
 
+func TestInterpolatedStrings(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`"plain ${"text"}"`, "plain text"},
+		{`let name = "Monkey"; let age = 1; "Hello ${name}, you are ${age + 1}"`, "Hello Monkey, you are 2"},
+		{`"${1 < 2} ${[1, "a"]}"`, "true [1, a]"},
+		{`let f = fn(x) { "<${x}>" }; "${f("a")}${f("b")}"`, "<a><b>"},
+		{`"a ${ "b ${1 + 1}" } c"`, "a b 2 c"},
+		{`"n=" + str(5)`, "n=5"},
+		{`str("s")`, "s"},
+		{`str([1, true])`, "[1, true]"},
+	}
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		testStringLiteral(t, evaluated, tt.expected)
+	}
+}
+
 func TestEvalBooleanExpression(t *testing.T) {
 	tests := []struct {
 		input    string
@@ -192,10 +217,34 @@ func TestErrorHandling(t *testing.T) {
 			"foobar",
 			"identifier not found: foobar",
 		},
+		{
+			`"a ${foobar} b"`,
+			"identifier not found: foobar",
+		},
 		{
 			`{"name": "Monkey"}[fn(x) { x }];`,
 			"unusable as hash key: FUNCTION",
 		},
+		{
+			`{[fn(x) { x }]: 2}`,
+			"unusable as hash key: ARRAY",
+		},
+		{
+			`{"a": 1}[[1, {}]]`,
+			"unusable as hash key: ARRAY",
+		},
+		{
+			`"abc"["a"]`,
+			"expected integer, got STRING",
+		},
+		{
+			`[1, 2]["a":]`,
+			"expected integer, got STRING",
+		},
+		{
+			`5[1:2]`,
+			"slice operator not supported: INTEGER",
+		},
 	}
 	for _, tt := range tests {
 		evaluated := testEval(tt.input)
@@ -292,6 +341,60 @@ apply(
 	}
 }
 
+func TestScoping(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected any
+	}{
+		// closures can see bindings declared after them
+		{`let f = fn() { let g = fn() { x }; let x = 5; g() }; f()`, 5},
+		// using a binding before its let falls back to outer scopes
+		{`let x = 1; let f = fn() { let a = x; let x = 2; [a, x] }; f()`, []any{1, 2}},
+		// recursion through a local binding
+		{`let f = fn(n) { let loop = fn(i) { if (i == 0) { 0 } else { i + loop(i - 1) } }; loop(n) }; f(4)`, 10},
+		// parameters shadow globals and builtins
+		{`let x = 1; let f = fn(x) { x }; [f(2), x]`, []any{2, 1}},
+		{`let f = fn(len) { len }; f(3)`, 3},
+		// lets inside blocks share the function scope
+		{`let f = fn(c) { if (c) { let y = 1; } y }; f(true)`, 1},
+		{`let f = fn(c) { if (c) { let y = 1; } y }; f(false)`, &object.Error{Message: "identifier not found: y"}},
+		// each call gets its own slots
+		{`let mk = fn(x) { fn() { x } }; let a = mk(1); let b = mk(2); [a(), b()]`, []any{1, 2}},
+		// redeclaring reuses the same slot
+		{`let f = fn() { let a = 1; let a = a + 1; a }; f()`, 2},
+		// globals declared after the function
+		{`let f = fn() { later }; let later = 3; f()`, 3},
+	}
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if expected, ok := tt.expected.(*object.Error); ok {
+			testErrorObject(t, evaluated, expected.Message)
+			continue
+		}
+		testObject(t, evaluated, tt.expected)
+	}
+}
+
+func TestEvalUnresolvedNodes(t *testing.T) {
+	// nodes evaluated outside of a program (eg. in a function's environment) are looked up by name
+	env := object.NewEnvironment()
+	Eval(parser.New(lexer.NewLexer(`let g = 10;`)).ParseProgram(), env)
+
+	fn := Eval(parser.New(lexer.NewLexer(`fn(a) { let b = a * 2; b }`)).ParseProgram(), env).(*object.Function)
+	funcEnv := extendFunctionEnv(fn, []object.Object{&object.Integer{Value: 3}})
+
+	prog := parser.New(lexer.NewLexer(`let c = fn(x) { x + a + g }; c(1)`)).ParseProgram()
+	for _, stmt := range prog.Statements {
+		res := Eval(stmt, funcEnv)
+		if isError(res) {
+			t.Fatalf("unexpected error: %s", res.Inspect())
+		}
+		if _, ok := stmt.(*ast.ExpressionStatement); ok {
+			testIntegerObject(t, res, 14)
+		}
+	}
+}
+
 func TestBuiltinFunctions(t *testing.T) {
 	tests := []struct {
 		input    string
@@ -300,6 +403,8 @@ func TestBuiltinFunctions(t *testing.T) {
 		{`len("")`, 0},
 		{`len("four")`, 4},
 		{`len("hello world")`, 11},
+		{`len("héllo")`, 5},
+		{`len("世界")`, 2},
 		{`len(1)`, "argument to `len` not supported, got INTEGER"},
 		{`len("one", "two")`, "wrong number of arguments. got=2, want=1"},
 		{`len([])`, 0},
@@ -327,6 +432,153 @@ func TestBuiltinFunctions(t *testing.T) {
 	}
 }
 
+func TestStringConversionBuiltins(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected any
+	}{
+		{`bytes("")`, []any{}},
+		{`bytes("hé")`, []any{104, 195, 169}},
+		{`bytes([104, 195, 169])`, "hé"},
+		{`runes("hé")`, []any{104, 233}},
+		{`runes([19990, 30028])`, "世界"},
+		{`let ñ = "ñ"; len(bytes(ñ))`, 2},
+		{`let ñ = "ñ"; len(runes(ñ))`, 1},
+	}
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		testObject(t, evaluated, tt.expected)
+	}
+}
+
+func TestStringBuiltins(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected any
+	}{
+		{`split("a,b,,c", ",")`, []any{"a", "b", "", "c"}},
+		{`split("  a b   c ")`, []any{"a", "b", "c"}},
+		{`split("abc", "")`, []any{"a", "b", "c"}},
+		{`split(1, ",")`, &object.Error{Message: "argument 1 to `split` must be STRING, got INTEGER"}},
+		{`join(["a", "b", "c"], ", ")`, "a, b, c"},
+		{`join(["a", "b"])`, "ab"},
+		{`join([])`, ""},
+		{`join(["a", 1])`, &object.Error{Message: "argument 1 to `join` must contain only STRING, got INTEGER"}},
+		{`join("ab")`, &object.Error{Message: "argument 1 to `join` must be ARRAY, got STRING"}},
+		{`trim("  a b  ")`, "a b"},
+		{`trim("xxaxx", "x")`, "a"},
+		{`upper("héllo")`, "HÉLLO"},
+		{`lower("HeLLo")`, "hello"},
+		{`replace("a.b.c", ".", "-")`, "a-b-c"},
+		{`replace("a.b.c", ".", "-", 1)`, "a-b.c"},
+		{`replace("a", "b")`, &object.Error{Message: "wrong number of arguments. got=2, want=3..4"}},
+		{`contains("monkey", "key")`, true},
+		{`contains("monkey", "dog")`, false},
+		{`starts_with("monkey", "mon")`, true},
+		{`starts_with("monkey", "key")`, false},
+		{`ends_with("monkey", "key")`, true},
+		{`ends_with("monkey", "mon")`, false},
+		{`index_of("monkey", "key")`, 3},
+		{`index_of("héllo", "llo")`, 2},
+		{`index_of("monkey", "dog")`, -1},
+		{`repeat("ab", 3)`, "ababab"},
+		{`repeat("ab", 0)`, ""},
+		{`repeat("ab", -1)`, &object.Error{Message: "argument 2 to `repeat` must not be negative, got -1"}},
+		{`repeat("ab", "c")`, &object.Error{Message: "argument 2 to `repeat` must be INTEGER, got STRING"}},
+		{`repeat("ab", 9223372036854775807)`, &object.Error{Message: "result of `repeat` would exceed 268435456 bytes"}},
+		{`repeat("", 9223372036854775807)`, ""},
+		{`pad_left("7", 3)`, "  7"},
+		{`pad_left("7", 3, "0")`, "007"},
+		{`pad_right("ab", 5, "xy")`, "abxyx"},
+		{`pad_right("héllo", 6)`, "héllo "},
+		{`pad_left("long", 2)`, "long"},
+		{`pad_left("a", 2, "")`, &object.Error{Message: "argument 3 to `pad_left` must not be empty"}},
+		{`pad_left("a", 3000000000000)`, &object.Error{Message: "result of `pad_left` would exceed 268435456 bytes"}},
+		{`pad_right("a", 200000000, "ñññ")`, &object.Error{Message: "result of `pad_right` would exceed 268435456 bytes"}},
+		{`pad_left("", 268435457)`, &object.Error{Message: "result of `pad_left` would exceed 268435456 bytes"}},
+		{`pad_left("x", 6, "ñé")`, "ñéñéñx"},
+		{`pad_right("x", 4, "añé")`, "xañé"},
+		{`chars("héy")`, []any{"h", "é", "y"}},
+		{`chars("")`, []any{}},
+		{`format("%s is %d years old", "Monkey", 1)`, "Monkey is 1 years old"},
+		{`format("%5s|%-3d|%t", "ab", 7, true)`, "   ab|7  |true"},
+		{`format("%v", [1, "a"])`, "[1, a]"},
+		{`format("no args")`, "no args"},
+		{`format()`, &object.Error{Message: "wrong number of arguments. got=0, want=1+"}},
+	}
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if expected, ok := tt.expected.(*object.Error); ok {
+			testErrorObject(t, evaluated, expected.Message)
+			continue
+		}
+		testObject(t, evaluated, tt.expected)
+	}
+}
+
+func TestCollectionBuiltins(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected any
+	}{
+		{`map([1, 2, 3], fn(x) { x * 2 })`, []any{2, 4, 6}},
+		{`map([], fn(x) { x * 2 })`, []any{}},
+		{`map(["a", "b"], upper)`, []any{"A", "B"}},
+		{`map([1, "a"], fn(x) { x * 2 })`, &object.Error{Message: "type mismatch: STRING * INTEGER"}},
+		{`map([1], 1)`, &object.Error{Message: "argument 2 to `map` must be FUNCTION, got INTEGER"}},
+		{`filter([1, 2, 3, 4], fn(x) { x > 2 })`, []any{3, 4}},
+		{`reduce([1, 2, 3, 4], 0, fn(acc, x) { acc + x })`, 10},
+		{`reduce([], "empty", fn(acc, x) { acc + x })`, "empty"},
+		{`reduce(["a", "b"], "", fn(acc, x) { x + acc })`, "ba"},
+		{`sort([3, 1, 2])`, []any{1, 2, 3}},
+		{`sort(["b", "c", "a"])`, []any{"a", "b", "c"}},
+		{`sort([3, 1, 2], fn(a, b) { a > b })`, []any{3, 2, 1}},
+		{`let a = [2, 1]; sort(a); a`, []any{2, 1}},
+		{`sort([1, "a"])`, &object.Error{Message: "can't compare STRING with INTEGER"}},
+		{`reverse([1, 2, 3])`, []any{3, 2, 1}},
+		{`reverse("héllo")`, "olléh"},
+		{`range(3)`, []any{0, 1, 2}},
+		{`range(2, 5)`, []any{2, 3, 4}},
+		{`range(5, 0, -2)`, []any{5, 3, 1}},
+		{`range(5, 0)`, []any{}},
+		{`range(0, 5, 0)`, &object.Error{Message: "argument 3 to `range` must not be zero"}},
+		{`range(9223372036854775805, 9223372036854775807, 5)`, []any{9223372036854775805}},
+		{`range(-9223372036854775807 - 1, 9223372036854775807, 9223372036854775807)`,
+			[]any{-9223372036854775807 - 1, -1, 9223372036854775806}},
+		{`range(0, 9223372036854775807)`,
+			&object.Error{Message: "`range` would have 9223372036854775807 items, more than the maximum of 16777216"}},
+		{`range(9223372036854775807, -9223372036854775807 - 1, -1)`,
+			&object.Error{Message: "`range` would have 18446744073709551615 items, more than the maximum of 16777216"}},
+		{`zip([1, 2, 3], ["a", "b"])`, []any{[]any{1, "a"}, []any{2, "b"}}},
+		{`enumerate(["a", "b"])`, []any{[]any{0, "a"}, []any{1, "b"}}},
+		{`any([0, false])`, true},
+		{`any([false])`, false},
+		{`any([1, 2], fn(x) { x > 1 })`, true},
+		{`all([1, 2], fn(x) { x > 1 })`, false},
+		{`all([1, 2], fn(x) { x > 0 })`, true},
+		{`all([])`, true},
+		{`flatten([1, [2, [3, [4]]], []])`, []any{1, 2, 3, 4}},
+		{`unique([1, 2, 1, "a", "a", true])`, []any{1, 2, "a", true}},
+		{`unique([[1, 2], [1, 2], [2, 1]])`, []any{[]any{1, 2}, []any{2, 1}}},
+		{`unique([{}])`, &object.Error{Message: "unusable as hash key: HASHMAP"}},
+		{`min([3, 1, 2])`, 1},
+		{`max([3, 1, 2])`, 3},
+		{`max("a", "c", "b")`, "c"},
+		{`min([])`, nil},
+		{`sum([1, 2, 3])`, 6},
+		{`sum([])`, 0},
+		{`sum(map(range(1, 4), fn(x) { x * x }))`, 14},
+	}
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if expected, ok := tt.expected.(*object.Error); ok {
+			testErrorObject(t, evaluated, expected.Message)
+			continue
+		}
+		testObject(t, evaluated, tt.expected)
+	}
+}
+
 func TestArrayLiterals(t *testing.T) {
 	tests := []struct {
 		input    string
@@ -352,8 +604,8 @@ func TestEmptyArrayLiteral(t *testing.T) {
 	if !ok {
 		t.Fatalf("object is not Array. got=%T (%+v)", evaluated, evaluated)
 	}
-	if len(result.Items) != 0 {
-		t.Fatalf("array has wrong num of elements. got=%d", len(result.Items))
+	if result.Len() != 0 {
+		t.Fatalf("array has wrong num of elements. got=%d", result.Len())
 	}
 }
 
@@ -400,6 +652,14 @@ func TestArrayIndexExpressions(t *testing.T) {
 		},
 		{
 			"[1, 2, 3][-1]",
+			3,
+		},
+		{
+			"[1, 2, 3][-3]",
+			1,
+		},
+		{
+			"[1, 2, 3][-4]",
 			nil,
 		},
 	}
@@ -409,6 +669,51 @@ func TestArrayIndexExpressions(t *testing.T) {
 	}
 }
 
+func TestStringIndexExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`"abc"[0]`, "a"},
+		{`"abc"[2]`, "c"},
+		{`"abc"[3]`, nil},
+		{`"abc"[-1]`, "c"},
+		{`"abc"[-4]`, nil},
+		{`"héllo"[1]`, "é"},
+		{`let s = "世界"; s[len(s) - 1]`, "界"},
+	}
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		testObject(t, evaluated, tt.expected)
+	}
+}
+
+func TestSliceExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"[1, 2, 3, 4][1:3]", []any{2, 3}},
+		{"[1, 2, 3, 4][:2]", []any{1, 2}},
+		{"[1, 2, 3, 4][2:]", []any{3, 4}},
+		{"[1, 2, 3, 4][:]", []any{1, 2, 3, 4}},
+		{"[1, 2, 3, 4][-2:]", []any{3, 4}},
+		{"[1, 2, 3, 4][:-1]", []any{1, 2, 3}},
+		{"[1, 2, 3, 4][3:1]", []any{}},
+		{"[1, 2, 3, 4][1:100]", []any{2, 3, 4}},
+		{"[1, 2, 3, 4][-100:1]", []any{1}},
+		{"let a = [1, 2, 3]; let i = 1; a[i:i + 1]", []any{2}},
+		{`"hello"[1:3]`, "el"},
+		{`"hello"[:-1]`, "hell"},
+		{`"héllo"[1:]`, "éllo"},
+		{`"hello"[5:]`, ""},
+	}
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		testObject(t, evaluated, tt.expected)
+	}
+}
+
 func TestHashLiterals(t *testing.T) {
 	input := `
 let two = "two";
@@ -429,27 +734,93 @@ let two = "two";
 		t.Fatalf("Eval didn't return Hash. got=%T (%+v)", evaluated, evaluated)
 	}
 
-	expected := map[object.HashKey]any{
-		(&object.String{Value: "one"}).HashKey():   1,
-		(&object.String{Value: "two"}).HashKey():   2,
-		(&object.String{Value: "three"}).HashKey(): 3,
-		(&object.Integer{Value: 4}).HashKey():      4,
-		TRUE.HashKey():                             5,
-		FALSE.HashKey():                            6,
-		(&object.String{Value: "str"}).HashKey():   "some string",
-		(&object.String{Value: "bool"}).HashKey():  true,
+	expected := []struct {
+		key   object.Object
+		value any
+	}{
+		{&object.String{Value: "one"}, 1},
+		{&object.String{Value: "two"}, 2},
+		{&object.String{Value: "three"}, 3},
+		{&object.Integer{Value: 4}, 4},
+		{TRUE, 5},
+		{FALSE, 6},
+		{&object.String{Value: "str"}, "some string"},
+		{&object.String{Value: "bool"}, true},
 	}
 
-	if len(result.Pairs) != len(expected) {
-		t.Fatalf("Hash has wrong num of pairs. got=%d", len(result.Pairs))
+	if result.Len() != len(expected) {
+		t.Fatalf("Hash has wrong num of pairs. got=%d", result.Len())
+	}
+
+	for ix, pair := range result.Items() {
+		if !object.Equal(pair.Key, expected[ix].key) {
+			t.Errorf("wrong key at position %d. got=%s", ix, pair.Key.Inspect())
+		}
 	}
 
-	for expectedKey, expectedValue := range expected {
-		pair, ok := result.Pairs[expectedKey]
+	for _, exp := range expected {
+		value, ok := result.Get(exp.key)
 		if !ok {
 			t.Errorf("no pair for given key in Pairs")
 		}
-		testObject(t, pair.Value, expectedValue)
+		testObject(t, value, exp.value)
+	}
+}
+
+func TestHashInspectOrder(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`{"b": 1, "a": 2, 3: 3, true: 4}`, `{b: 1, a: 2, 3: 3, true: 4}`},
+		{`{"b": 1, "a": 2, "b": 3}`, `{b: 3, a: 2}`},
+		{`merge({"z": 1, "a": 2}, {"m": 3, "z": 4})`, `{z: 4, a: 2, m: 3}`},
+		{`delete({"z": 1, "a": 2, "m": 3}, "a")`, `{z: 1, m: 3}`},
+	}
+	for _, tt := range tests {
+		for i := 0; i < 10; i++ { // map iteration order is random, so repeat to catch flakiness
+			evaluated := testEval(tt.input)
+			if evaluated.Inspect() != tt.expected {
+				t.Fatalf("wrong Inspect. expected=%q, got=%q", tt.expected, evaluated.Inspect())
+			}
+		}
+	}
+}
+
+func TestHashBuiltins(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected any
+	}{
+		{`keys({"b": 1, "a": 2})`, []any{"b", "a"}},
+		{`keys({})`, []any{}},
+		{`values({"b": 1, "a": 2})`, []any{1, 2}},
+		{`items({"b": 1, 2: "a"})`, []any{[]any{"b", 1}, []any{2, "a"}}},
+		{`has({"a": 1}, "a")`, true},
+		{`has({"a": 1}, "b")`, false},
+		{`has({"a": 1}, {})`, &object.Error{Message: "unusable as hash key: HASHMAP"}},
+		{`has({[1, "a"]: 1}, [1, "a"])`, true},
+		{`let h = {"a": 1}; let d = delete(h, "a"); [len(keys(h)), len(keys(d))]`, []any{1, 0}},
+		{`delete({"a": 1}, "b")["a"]`, 1},
+		{`merge({"a": 1}, {"a": 2, "b": 3})["a"]`, 2},
+		{`merge({"a": 1}, 1)`, &object.Error{Message: "argument 2 to `merge` must be HASHMAP, got INTEGER"}},
+		{`let h = {"a": 1}; let s = set(h, "a", 2); [h["a"], s["a"]]`, []any{1, 2}},
+		{`keys(set({"a": 1}, "b", 2))`, []any{"a", "b"}},
+		{`let a = [1, 2, 3]; let b = set(a, -1, 4); [a, b]`, []any{[]any{1, 2, 3}, []any{1, 2, 4}}},
+		{`set([1], 1, 2)`, &object.Error{Message: "index out of range: 1"}},
+		{`set(1, 1, 2)`, &object.Error{Message: "argument to `set` not supported, got INTEGER"}},
+		{`get({"a": 1}, "a")`, 1},
+		{`get({"a": 1}, "b")`, nil},
+		{`get({"a": 1}, "b", 0)`, 0},
+		{`get([], "b")`, &object.Error{Message: "argument 1 to `get` must be HASHMAP, got ARRAY"}},
+	}
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if expected, ok := tt.expected.(*object.Error); ok {
+			testErrorObject(t, evaluated, expected.Message)
+			continue
+		}
+		testObject(t, evaluated, tt.expected)
 	}
 }
 
@@ -462,6 +833,22 @@ func TestHashIndexExpressions(t *testing.T) {
 			`{"foo": 5}["foo"]`,
 			5,
 		},
+		{
+			`{[1, 2]: 5}[[1, 2]]`,
+			5,
+		},
+		{
+			`{[1, [2, "a"]]: 5}[[1, [2, "a"]]]`,
+			5,
+		},
+		{
+			`{[1, 2]: 5}[[2, 1]]`,
+			nil,
+		},
+		{
+			`{[]: 5}[[]]`,
+			5,
+		},
 		{
 			`{"foo": 5}["bar"]`,
 			nil,
@@ -493,6 +880,375 @@ func TestHashIndexExpressions(t *testing.T) {
 	}
 }
 
+type tracer struct {
+	events []string
+}
+
+func (tr *tracer) Statement(stmt ast.Statement, env *object.Environment) {
+	tr.events = append(tr.events, "statement "+stmt.String())
+}
+
+func (tr *tracer) Enter(fn *object.Function, env *object.Environment) {
+	tr.events = append(tr.events, "enter "+fn.Name)
+}
+
+func (tr *tracer) Leave(fn *object.Function, result object.Object) {
+	tr.events = append(tr.events, "leave "+fn.Name+" "+result.Inspect())
+}
+
+func TestDebuggerHook(t *testing.T) {
+	input := `let double = fn(x) { x * 2 }; map([1], double)`
+	l := lexer.NewLexer(input)
+	p := parser.New(l)
+	prog := p.ParseProgram()
+
+	tr := &tracer{}
+	env := object.NewEnvironment()
+	env.SetDebugger(tr)
+	testArrayObject(t, Eval(prog, env), []any{2})
+
+	expected := []string{
+		"statement let double = fn(x) { (x * 2); };",
+		"statement map([1], double)",
+		"enter double",
+		"statement (x * 2)",
+		"leave double 2",
+	}
+	if len(tr.events) != len(expected) {
+		t.Fatalf("wrong events. expected=%q, got=%q", expected, tr.events)
+	}
+	for ix := range expected {
+		if tr.events[ix] != expected[ix] {
+			t.Errorf("wrong event %d. expected=%q, got=%q", ix, expected[ix], tr.events[ix])
+		}
+	}
+}
+
+type point struct {
+	X, Y  int
+	Label string `monkey:"label"`
+	Note  string `monkey:"-"`
+	hide  int
+}
+
+func TestToObject(t *testing.T) {
+	var nilMap map[string]int
+	tests := []struct {
+		value    any
+		expected string
+	}{
+		{nil, "null"},
+		{nilMap, "null"},
+		{true, "true"},
+		{uint8(200), "200"},
+		{3.0, "3"},
+		{"hi", `"hi"`},
+		{[]any{1, "a", []int{2}}, `[1, "a", [2]]`},
+		{map[string]int{"b": 2, "a": 1}, `{"a": 1, "b": 2}`},
+		{map[int]bool{10: true, 9: false}, `{9: false, 10: true}`},
+		{&point{X: 1, Y: 2, Label: "p", Note: "skipped"}, `{"X": 1, "Y": 2, "label": "p"}`},
+		{&object.Integer{Value: 5}, "5"},
+	}
+	for _, tt := range tests {
+		obj, err := ToObject(tt.value)
+		if err != nil {
+			t.Errorf("unexpected error converting %#v: %s", tt.value, err)
+			continue
+		}
+		if got := object.Repr(obj); got != tt.expected {
+			t.Errorf("wrong conversion of %#v. expected=%s, got=%s", tt.value, tt.expected, got)
+		}
+	}
+
+	type node struct {
+		Value int
+		Next  *node
+	}
+	shared := &node{Value: 1}
+	obj, err := ToObject([]*node{shared, {Value: 2, Next: shared}})
+	if err != nil {
+		t.Errorf("unexpected error converting shared pointers: %s", err)
+	} else if got := object.Repr(obj); got != `[{"Value": 1, "Next": null}, {"Value": 2, "Next": {"Value": 1, "Next": null}}]` {
+		t.Errorf("wrong conversion of shared pointers. got=%s", got)
+	}
+
+	cyclic := &node{}
+	cyclic.Next = cyclic
+	cyclicSlice := []any{nil}
+	cyclicSlice[0] = cyclicSlice
+	cyclicMap := map[string]any{}
+	cyclicMap["self"] = cyclicMap
+
+	errs := []struct {
+		value    any
+		expected string
+	}{
+		{1.5, "can't convert 1.5 to INTEGER: not a whole number"},
+		{uint64(1 << 63), "can't convert 9223372036854775808 to INTEGER: out of range"},
+		{make(chan int), "can't convert chan int to a Monkey value"},
+		{cyclic, "field Next: can't convert cyclic value of type *eval.node"},
+		{cyclicSlice, "can't convert cyclic value of type []interface {}"},
+		{cyclicMap, "can't convert cyclic value of type map[string]interface {}"},
+	}
+	for _, tt := range errs {
+		if _, err := ToObject(tt.value); err == nil || err.Error() != tt.expected {
+			t.Errorf("wrong error converting %T. expected=%q, got=%v", tt.value, tt.expected, err)
+		}
+	}
+}
+
+func TestFromObject(t *testing.T) {
+	var p point
+	if err := FromObject(testEval(`{"X": 1, "Y": -2, "label": "p", "other": 3}`), &p); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if p != (point{X: 1, Y: -2, Label: "p"}) {
+		t.Errorf("wrong struct: %#v", p)
+	}
+
+	var m map[string][]int
+	if err := FromObject(testEval(`{"a": [1, 2], "b": []}`), &m); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(m) != 2 || len(m["a"]) != 2 || m["a"][1] != 2 || m["b"] == nil {
+		t.Errorf("wrong map: %#v", m)
+	}
+
+	var v any
+	if err := FromObject(testEval(`[1, "a", true, {"k": first([])}]`), &v); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if fmt.Sprintf("%#v", v) != `[]interface {}{1, "a", true, map[interface {}]interface {}{"k":interface {}(nil)}}` {
+		t.Errorf("wrong value: %#v", v)
+	}
+
+	var double func(int) (int, error)
+	if err := FromObject(testEval(`fn(x) { x * 2 }`), &double); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if n, err := double(21); n != 42 || err != nil {
+		t.Errorf("wrong result calling function: %d, %v", n, err)
+	}
+	var fail func() error
+	FromObject(testEval(`fn() { 1 + "a" }`), &fail)
+	if err := fail(); err == nil || err.Error() != "type mismatch: INTEGER + STRING" {
+		t.Errorf("wrong error calling function: %v", err)
+	}
+
+	var small int8
+	var str string
+	var arr [2]int
+	errs := []struct {
+		input    string
+		target   any
+		expected string
+	}{
+		{`300`, &small, "can't convert 300 to int8: out of range"},
+		{`1`, &str, "can't convert INTEGER to string"},
+		{`first([])`, &str, "can't convert NULL to string"},
+		{`[1, 2, 3]`, &arr, "can't convert array of 3 items to [2]int"},
+		{`{"a": ["x"]}`, &m, `key "a": item 0: can't convert STRING to int`},
+	}
+	for _, tt := range errs {
+		if err := FromObject(testEval(tt.input), tt.target); err == nil || err.Error() != tt.expected {
+			t.Errorf("wrong error converting %s. expected=%q, got=%v", tt.input, tt.expected, err)
+		}
+	}
+}
+
+func TestWrapFunc(t *testing.T) {
+	registry := Builtins()
+	wrap := func(name string, fn any) {
+		b, err := WrapFunc(name, fn)
+		if err != nil {
+			t.Fatalf("unexpected error wrapping %s: %s", name, err)
+		}
+		registry.MustRegister(b.Fn, b.Spec)
+	}
+	wrap("strings.fields", strings.Fields)
+	wrap("strings.join", func(sep string, parts ...string) string { return strings.Join(parts, sep) })
+	wrap("div", func(a, b int) (int, error) {
+		if b == 0 {
+			return 0, fmt.Errorf("division by zero")
+		}
+		return a / b, nil
+	})
+	wrap("apply", func(f func(int) int, x int) int { return f(x) })
+	wrap("area", func(p point) int { return p.X * p.Y })
+	wrap("nothing", func() {})
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`strings["fields"](" a  b ")`, `["a", "b"]`},
+		{`strings["join"]("-", "a", "b", "c")`, `"a-b-c"`},
+		{`strings["join"]("-")`, `""`},
+		{`div(7, 2)`, "3"},
+		{`div(1, 0)`, "ERROR: div: division by zero"},
+		{`div(1, "a")`, "ERROR: argument 2 to `div` must be INTEGER, got STRING"},
+		{`div(1)`, "ERROR: wrong number of arguments. got=1, want=2"},
+		{`apply(fn(x) { x + 1 }, 1)`, "2"},
+		{`apply(fn(x) { x + "a" }, 1)`, "ERROR: apply: type mismatch: INTEGER + STRING"},
+		{`area({"X": 2, "Y": 3})`, "6"},
+		{`nothing()`, "null"},
+	}
+	for _, tt := range tests {
+		env := object.NewEnvironment()
+		env.Runtime().Builtins = registry
+		res := Eval(parser.New(lexer.NewLexer(tt.input)).ParseProgram(), env)
+		if got := object.Repr(res); got != tt.expected {
+			t.Errorf("wrong result for %s. expected=%s, got=%s", tt.input, tt.expected, got)
+		}
+	}
+
+	if b, _ := registry.Get("strings.join"); b.Signature() != "strings.join(arg1: STRING, ...arg2: STRING): STRING" {
+		t.Errorf("wrong signature: %s", b.Signature())
+	}
+	for _, fn := range []any{1, func() (int, int) { return 0, 0 }} {
+		if _, err := WrapFunc("f", fn); err == nil {
+			t.Errorf("expected error wrapping %T", fn)
+		}
+	}
+}
+
+// callBuiltin calls its first argument with the rest, like host code would from a builtin.
+type callBuiltin struct{}
+
+func (cb callBuiltin) Name() string { return "call" }
+func (cb callBuiltin) Do(args ...object.Object) object.Object {
+	res, err := Call(args[0], args[1:]...)
+	if err != nil {
+		return &object.Error{Message: "call failed: " + err.Error()}
+	}
+	return res
+}
+
+// cancelBuiltin cancels a context when called.
+type cancelBuiltin struct{ cancel context.CancelFunc }
+
+func (cb cancelBuiltin) Name() string { return "cancel" }
+func (cb cancelBuiltin) Do(args ...object.Object) object.Object {
+	cb.cancel()
+	return NULL
+}
+
+func TestCall(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	env := object.NewEnvironment()
+	env.Runtime().Builtins = Builtins()
+	env.Runtime().Builtins.MustRegister(callBuiltin{}, nil)
+	env.Runtime().Builtins.MustRegister(cancelBuiltin{cancel}, nil)
+	env.Runtime().MaxSteps = 100
+	Eval(parser.New(lexer.NewLexer(`
+let add = fn(a, b) { a + b };
+let nothing = fn() { let x = 1; };
+let fail = fn() { 1 + "a" };
+let spin = fn(n) { if (n == 0) { 0 } else { spin(n - 1) } };
+let stop = fn() { cancel(); 1; 2 };
+`)).ParseProgram(), env)
+	get := func(name string) object.Object {
+		fn, _ := env.Get(name)
+		return fn
+	}
+
+	if res, err := Call(get("add"), &object.Integer{Value: 1}, &object.Integer{Value: 2}); err != nil || object.Repr(res) != "3" {
+		t.Errorf("wrong result calling function: %v, %v", res, err)
+	}
+	if res, err := Call(get("nothing")); err != nil || res != NULL {
+		t.Errorf("expected null calling function without value, got %v, %v", res, err)
+	}
+	lenFn, _ := Builtins().Get("len")
+	if res, err := Call(lenFn, &object.String{Value: "abc"}); err != nil || object.Repr(res) != "3" {
+		t.Errorf("wrong result calling builtin: %v, %v", res, err)
+	}
+
+	_, err := Call(get("fail"))
+	var objErr *object.Error
+	if !errors.As(err, &objErr) || objErr.Message != "type mismatch: INTEGER + STRING" {
+		t.Errorf("wrong error: %v", err)
+	}
+	if _, err := Call(&object.Integer{Value: 1}); err == nil || err.Error() != "not a function: INTEGER" {
+		t.Errorf("wrong error calling integer: %v", err)
+	}
+
+	// calls from builtins run within the limits of the caller
+	res := Eval(parser.New(lexer.NewLexer(`call(add, 1, 2)`)).ParseProgram(), env)
+	if object.Repr(res) != "3" {
+		t.Errorf("wrong result calling from builtin: %s", object.Repr(res))
+	}
+	env.Runtime().Steps = 0
+	res = Eval(parser.New(lexer.NewLexer(`call(spin, 200)`)).ParseProgram(), env)
+	if object.Repr(res) != "ERROR: call failed: step limit exceeded: 100" {
+		t.Errorf("wrong result calling from builtin: %s", object.Repr(res))
+	}
+
+	env.Runtime().Steps = 0
+	if _, err := CallContext(ctx, get("stop")); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+	if env.Runtime().Context != nil {
+		t.Errorf("expected context to be restored")
+	}
+	if _, err := CallContext(ctx, lenFn, &object.String{Value: "abc"}); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled calling builtin, got %v", err)
+	}
+}
+
+func BenchmarkFibonacci(b *testing.B) {
+	input := `
+let fibonacci = fn(x) {
+	if (x < 2) {
+		x
+	} else {
+		fibonacci(x - 1) + fibonacci(x - 2)
+	}
+};
+fibonacci(20);
+`
+	for i := 0; i < b.N; i++ {
+		testEval(input)
+	}
+}
+
+func BenchmarkClosures(b *testing.B) {
+	input := `
+let counter = fn(n) {
+	let step = 1;
+	let loop = fn(i, acc) {
+		if (i == n) {
+			acc
+		} else {
+			loop(i + step, acc + i)
+		}
+	};
+	loop(0, 0)
+};
+counter(5000);
+`
+	for i := 0; i < b.N; i++ {
+		testEval(input)
+	}
+}
+
+func BenchmarkPush(b *testing.B) {
+	input := `
+let build = fn(arr, n) {
+	if (n == 0) {
+		arr
+	} else {
+		build(push(arr, n), n - 1)
+	}
+};
+len(build([], 2000));
+`
+	for i := 0; i < b.N; i++ {
+		testEval(input)
+	}
+}
+
 ///////// HELPERS ////////
 
 func testEval(input string) object.Object {
@@ -544,6 +1300,19 @@ func testStringLiteral(t *testing.T, obj object.Object, exp string) bool {
 	return true
 }
 
+func testErrorObject(t *testing.T, obj object.Object, expected string) bool {
+	errObj, ok := obj.(*object.Error)
+	if !ok {
+		t.Errorf("object is not Error. got=%T (%+v)", obj, obj)
+		return false
+	}
+	if errObj.Message != expected {
+		t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Message)
+		return false
+	}
+	return true
+}
+
 func testNullObject(t *testing.T, obj object.Object) bool {
 	if obj != NULL {
 		t.Errorf("object is not NULL. got=%T (%+v)", obj, obj)
@@ -558,11 +1327,11 @@ func testArrayObject(t *testing.T, obj object.Object, expected []any) {
 		t.Fatalf("object is not Array. got=%T (%+v)", obj, obj)
 	}
 
-	if len(result.Items) != len(expected) {
-		t.Fatalf("array has wrong num of elements. got=%d", len(result.Items))
+	if result.Len() != len(expected) {
+		t.Fatalf("array has wrong num of elements. got=%d", result.Len())
 	}
 
-	for ix, item := range result.Items {
+	for ix, item := range result.Items() {
 		testObject(t, item, expected[ix])
 	}
 }
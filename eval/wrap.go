@@ -0,0 +1,141 @@
+package eval
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/manuelpepe/interpreter/object"
+)
+
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+
+// WrapFunc turns a Go function into a builtin called name, with a spec derived from its parameters
+// so calls are checked before converting the arguments with FromObject. the function can return
+// nothing, a value, an error, or a value and an error. values are converted with ToObject, while a non
+// nil error or a panic inside fn are returned as an object.Error.
+//
+//	b, err := eval.WrapFunc("strings.fields", strings.Fields)
+//	registry.Register(b.Fn, b.Spec)
+func WrapFunc(name string, fn any) (*object.Builtin, error) {
+	v := reflect.ValueOf(fn)
+	if v.Kind() != reflect.Func || v.IsNil() {
+		return nil, fmt.Errorf("can't wrap %T as a builtin: not a function", fn)
+	}
+	return wrapFunc(name, v)
+}
+
+func wrapFunc(name string, fn reflect.Value) (*object.Builtin, error) {
+	typ := fn.Type()
+	switch {
+	case typ.NumOut() > 2,
+		typ.NumOut() == 2 && (typ.Out(0) == errorType || typ.Out(1) != errorType):
+		return nil, fmt.Errorf("can't wrap %s as a builtin: must return at most a value and an error", typ)
+	}
+
+	spec := &object.BuiltinSpec{Variadic: typ.IsVariadic()}
+	for ix := range typ.NumIn() {
+		in := typ.In(ix)
+		if spec.Variadic && ix == typ.NumIn()-1 {
+			in = in.Elem()
+			spec.Optional = 1
+		}
+		spec.Params = append(spec.Params, object.BuiltinParam{
+			Name:  fmt.Sprintf("arg%d", ix+1),
+			Types: objectTypes(in),
+		})
+	}
+	if typ.NumOut() > 0 && typ.Out(0) != errorType {
+		// results that can be null, like slices or pointers, can't be described by a single type
+		if types := objectTypes(typ.Out(0)); len(types) == 1 {
+			spec.Result = types[0]
+		}
+	}
+	return &object.Builtin{Fn: &funcBuiltin{name: name, fn: fn}, Spec: spec}, nil
+}
+
+// objectTypes returns the types of the Monkey values that can be converted into typ, or nil if
+// it accepts any.
+func objectTypes(typ reflect.Type) []object.ObjectType {
+	var types []object.ObjectType
+	switch typ.Kind() {
+	case reflect.Bool:
+		types = []object.ObjectType{object.BOOLEAN_OBJ}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64:
+		types = []object.ObjectType{object.INTEGER_OBJ}
+	case reflect.String:
+		types = []object.ObjectType{object.STRING_OBJ}
+	case reflect.Slice, reflect.Array:
+		types = []object.ObjectType{object.ARRAY_OBJ}
+	case reflect.Map, reflect.Struct:
+		types = []object.ObjectType{object.HASH_OBJ}
+	case reflect.Func:
+		types = []object.ObjectType{object.FUNCTION_OBJ, object.BUILTIN_OBJ}
+	case reflect.Pointer:
+		types = objectTypes(typ.Elem())
+	default:
+		return nil
+	}
+	if len(types) != 0 {
+		switch typ.Kind() {
+		case reflect.Pointer, reflect.Slice, reflect.Map, reflect.Func:
+			types = append(types, object.NULL_OBJ)
+		}
+	}
+	return types
+}
+
+// funcBuiltin calls a Go function through reflection, see WrapFunc.
+type funcBuiltin struct {
+	name string
+	fn   reflect.Value
+}
+
+func (fb *funcBuiltin) Name() string {
+	return fb.name
+}
+
+func (fb *funcBuiltin) Do(args ...object.Object) (res object.Object) {
+	typ := fb.fn.Type()
+	if typ.IsVariadic() && len(args) < typ.NumIn()-1 {
+		return newError("wrong number of arguments. got=%d, want=%d+", len(args), typ.NumIn()-1)
+	}
+	if !typ.IsVariadic() && len(args) != typ.NumIn() {
+		return newError("wrong number of arguments. got=%d, want=%d", len(args), typ.NumIn())
+	}
+
+	in := make([]reflect.Value, len(args))
+	for ix, arg := range args {
+		argType := typ.In(min(ix, typ.NumIn()-1))
+		if typ.IsVariadic() && ix >= typ.NumIn()-1 {
+			argType = argType.Elem()
+		}
+		in[ix] = reflect.New(argType).Elem()
+		if err := fromObject(arg, in[ix]); err != nil {
+			return newError("argument %d to `%s`: %s", ix+1, fb.name, err)
+		}
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			res = newError("%s: %v", fb.name, r)
+		}
+	}()
+	out := fb.fn.Call(in)
+
+	if len(out) > 0 && out[len(out)-1].Type() == errorType {
+		if err, _ := out[len(out)-1].Interface().(error); err != nil {
+			return newError("%s: %s", fb.name, err)
+		}
+		out = out[:len(out)-1]
+	}
+	if len(out) == 0 {
+		return NULL
+	}
+	obj, err := toObject(out[0])
+	if err != nil {
+		return newError("%s: %s", fb.name, err)
+	}
+	return obj
+}
@@ -0,0 +1,111 @@
+package format
+
+import (
+	"strings"
+
+	"github.com/manuelpepe/interpreter/ast"
+	"github.com/manuelpepe/interpreter/parser"
+	"github.com/manuelpepe/interpreter/token"
+)
+
+// expression prints expr starting at the current position of a line indented at depth.
+func (pr *printer) expression(expr ast.Expression, depth int) string {
+	switch expr := expr.(type) {
+	case nil:
+		return ""
+	case *ast.Identifier:
+		return expr.Value
+	case *ast.IntegerLiteral:
+		return expr.Token.Literal
+	case *ast.Boolean:
+		return expr.Token.Literal
+	case *ast.StringLiteral:
+		return `"` + expr.Value + `"`
+	case *ast.InterpolatedString:
+		var out strings.Builder
+		out.WriteString(`"`)
+		for _, part := range expr.Parts {
+			if text, ok := part.(*ast.TemplateText); ok {
+				out.WriteString(text.Value)
+			} else {
+				out.WriteString("${" + pr.expression(part, depth) + "}")
+			}
+		}
+		out.WriteString(`"`)
+		return out.String()
+	case *ast.PrefixExpression:
+		return expr.Operator + pr.operand(expr.Right, parser.PREFIX, depth)
+	case *ast.InfixExpression:
+		prec := parser.Precedence(token.TokenType(expr.Operator))
+		// operators are left associative, so the right operand needs parentheses on equal precedence
+		return pr.operand(expr.Left, prec, depth) + " " + expr.Operator + " " + pr.operand(expr.Right, prec+1, depth)
+	case *ast.IfExpression:
+		return pr.ifExpression(expr, depth, true)
+	case *ast.FunctionLiteral:
+		return pr.functionLiteral(expr, depth)
+	case *ast.CallExpression:
+		args := make([]string, len(expr.Arguments))
+		for ix, arg := range expr.Arguments {
+			args[ix] = pr.expression(arg, depth)
+		}
+		return pr.operand(expr.Function, parser.CALL, depth) + "(" + strings.Join(args, ", ") + ")"
+	case *ast.IndexExpression:
+		return pr.operand(expr.Left, parser.INDEX, depth) + "[" + pr.expression(expr.Index, depth) + "]"
+	case *ast.SliceExpression:
+		return pr.operand(expr.Left, parser.INDEX, depth) +
+			"[" + pr.expression(expr.Start, depth) + ":" + pr.expression(expr.End, depth) + "]"
+	case *ast.ArrayLiteral:
+		items := make([]string, len(expr.Items))
+		for ix, item := range expr.Items {
+			items[ix] = pr.expression(item, depth+1)
+		}
+		return list("[", items, "]", depth)
+	case *ast.HashLiteral:
+		items := make([]string, len(expr.Items))
+		for ix, item := range expr.Items {
+			items[ix] = pr.expression(item.Key, depth+1) + ": " + pr.expression(item.Value, depth+1)
+		}
+		return list("{", items, "}", depth)
+	}
+	return expr.String()
+}
+
+// operand prints expr wrapped in parentheses if it binds less tightly than prec.
+func (pr *printer) operand(expr ast.Expression, prec int, depth int) string {
+	out := pr.expression(expr, depth)
+	if precedence(expr) < prec {
+		return "(" + out + ")"
+	}
+	return out
+}
+
+func precedence(expr ast.Expression) int {
+	switch expr := expr.(type) {
+	case *ast.InfixExpression:
+		return parser.Precedence(token.TokenType(expr.Operator))
+	case *ast.PrefixExpression:
+		return parser.PREFIX
+	case *ast.CallExpression:
+		return parser.CALL
+	case *ast.IfExpression, *ast.FunctionLiteral:
+		// they extend as far right as possible, so they need parentheses as operands
+		return parser.LOWEST
+	}
+	return parser.INDEX
+}
+
+// list prints the items of an array or hash literal on one line if they fit, or one per line otherwise.
+// items are expected to be printed at depth+1.
+func list(open string, items []string, close string, depth int) string {
+	oneLine := open + strings.Join(items, ", ") + close
+	multiline := false
+	for _, item := range items {
+		multiline = multiline || strings.Contains(item, "\n")
+	}
+	if !multiline && len(oneLine)+len(indentation)*depth <= maxLine {
+		return oneLine
+	}
+
+	indent := strings.Repeat(indentation, depth+1)
+	return open + "\n" + indent + strings.Join(items, ",\n"+indent) + "\n" + strings.Repeat(indentation, depth) + close
+}
@@ -0,0 +1,230 @@
+// Package format prints Monkey programs in their canonical form.
+//
+// Statements go on their own lines, blocks are indented with four spaces, and operators are
+// separated by single spaces. Parentheses are only kept where precedence requires them. Function
+// bodies, and conditionals used as values, stay on one line when they hold a single short expression.
+// Comments and single blank lines between statements are preserved. Comments inside expressions are
+// moved after the statement that contains them.
+package format
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/manuelpepe/interpreter/ast"
+	"github.com/manuelpepe/interpreter/lexer"
+	"github.com/manuelpepe/interpreter/parser"
+	"github.com/manuelpepe/interpreter/token"
+)
+
+const (
+	indentation = "    "
+
+	// maxInline is the longest expression kept on one line inside braces, eg. `fn(x) { x * 2 }`
+	maxInline = 60
+
+	// maxLine is the longest array or hash literal kept on one line
+	maxLine = 80
+)
+
+// Source formats a program, returning an error if it can't be parsed.
+func Source(src string) (string, error) {
+	p := parser.New(lexer.NewLexer(src))
+	prog := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		return "", errors.New(strings.Join(p.Errors(), "\n"))
+	}
+
+	pr := newPrinter(src)
+	lines := pr.statements(prog.Statements, 0, pos{line: len(pr.src) + 1})
+	if len(lines) == 0 {
+		return "", nil
+	}
+	return strings.Join(lines, "\n") + "\n", nil
+}
+
+type pos struct {
+	line, col int
+}
+
+func posOf(tok token.Token) pos {
+	return pos{line: tok.Line, col: tok.Column}
+}
+
+func (p pos) before(other pos) bool {
+	return p.line < other.line || p.line == other.line && p.col < other.col
+}
+
+type printer struct {
+	src []string // lines of the source, to find blank lines
+
+	comments []token.Token
+	trailing map[int]bool // indexes of comments that follow code in the same line
+	next     int          // index of the next comment to print
+}
+
+func newPrinter(src string) *printer {
+	pr := &printer{
+		src:      strings.Split(src, "\n"),
+		trailing: make(map[int]bool),
+	}
+
+	l := lexer.NewLexer(src)
+	firstCode := make(map[int]int) // line to column of the first token in it
+	for tok := l.NextToken(); tok.Type != token.EOF; tok = l.NextToken() {
+		if _, ok := firstCode[tok.Line]; !ok {
+			firstCode[tok.Line] = tok.Column
+		}
+	}
+
+	pr.comments = l.Comments()
+	for ix, c := range pr.comments {
+		if col, ok := firstCode[c.Line]; ok && col < c.Column {
+			pr.trailing[ix] = true
+		}
+	}
+	return pr
+}
+
+// blankBefore reports if the source line before line is empty.
+func (pr *printer) blankBefore(line int) bool {
+	return line > 1 && line-2 < len(pr.src) && strings.TrimSpace(pr.src[line-2]) == ""
+}
+
+// hasComments reports if there are comments between from and to.
+func (pr *printer) hasComments(from pos, to pos) bool {
+	for _, c := range pr.comments[pr.next:] {
+		if from.before(posOf(c)) && posOf(c).before(to) {
+			return true
+		}
+	}
+	return false
+}
+
+// statements prints a list of statements at the given depth, one per line (statements spanning
+// multiple lines are returned as a single string). comments found before end are printed among them.
+func (pr *printer) statements(stmts []ast.Statement, depth int, end pos) []string {
+	indent := strings.Repeat(indentation, depth)
+	lines := make([]string, 0, len(stmts))
+
+	ownLineComments := func(upto pos) {
+		for pr.next < len(pr.comments) && posOf(pr.comments[pr.next]).before(upto) {
+			c := pr.comments[pr.next]
+			if len(lines) > 0 && pr.blankBefore(c.Line) {
+				lines = append(lines, "")
+			}
+			lines = append(lines, indent+c.Literal)
+			pr.next += 1
+		}
+	}
+
+	for ix, stmt := range stmts {
+		start := posOf(ast.StatementToken(stmt))
+		ownLineComments(start)
+		if len(lines) > 0 && pr.blankBefore(start.line) {
+			lines = append(lines, "")
+		}
+
+		line := indent + pr.statement(stmt, depth)
+
+		boundary := end
+		if ix+1 < len(stmts) {
+			boundary = posOf(ast.StatementToken(stmts[ix+1]))
+		}
+		for pr.next < len(pr.comments) && pr.trailing[pr.next] && posOf(pr.comments[pr.next]).before(boundary) {
+			line += " " + pr.comments[pr.next].Literal
+			pr.next += 1
+		}
+		lines = append(lines, line)
+	}
+	ownLineComments(end)
+
+	return lines
+}
+
+func (pr *printer) statement(stmt ast.Statement, depth int) string {
+	switch stmt := stmt.(type) {
+	case *ast.LetStatement:
+		return "let " + declaration(stmt.Name) + " = " + pr.expression(stmt.Value, depth) + ";"
+	case *ast.ReturnStatement:
+		return "return " + pr.expression(stmt.ReturnValue, depth) + ";"
+	case *ast.ExpressionStatement:
+		if ie, ok := stmt.Expression.(*ast.IfExpression); ok {
+			return pr.ifExpression(ie, depth, false)
+		}
+		return pr.expression(stmt.Expression, depth) + ";"
+	}
+	return stmt.String()
+}
+
+// block prints a block starting at the current position of a line indented at depth.
+func (pr *printer) block(b *ast.BlockStatement, depth int) string {
+	end := posOf(b.End)
+	if len(b.Statements) == 0 && !pr.hasComments(posOf(b.Token), end) {
+		return "{}"
+	}
+	lines := pr.statements(b.Statements, depth+1, end)
+	return "{\n" + strings.Join(lines, "\n") + "\n" + strings.Repeat(indentation, depth) + "}"
+}
+
+// inlineBlock prints a block holding a single short expression on one line, reporting false
+// if the block doesn't qualify.
+func (pr *printer) inlineBlock(b *ast.BlockStatement, depth int) (string, bool) {
+	if len(b.Statements) != 1 || pr.hasComments(posOf(b.Token), posOf(b.End)) {
+		return "", false
+	}
+	es, ok := b.Statements[0].(*ast.ExpressionStatement)
+	if !ok || es.Expression == nil {
+		return "", false
+	}
+	if _, ok := es.Expression.(*ast.IfExpression); ok {
+		return "", false
+	}
+	expr := pr.expression(es.Expression, depth)
+	if strings.Contains(expr, "\n") || len(expr) > maxInline {
+		return "", false
+	}
+	return "{ " + expr + " }", true
+}
+
+func (pr *printer) ifExpression(ie *ast.IfExpression, depth int, inline bool) string {
+	cond := "if (" + pr.expression(ie.Condition, depth) + ") "
+	if inline {
+		cons, ok := pr.inlineBlock(ie.Consequence, depth)
+		if ok && ie.Alternative == nil {
+			return cond + cons
+		}
+		if ok {
+			if alt, ok := pr.inlineBlock(ie.Alternative, depth); ok {
+				return cond + cons + " else " + alt
+			}
+		}
+	}
+	out := cond + pr.block(ie.Consequence, depth)
+	if ie.Alternative != nil {
+		out += " else " + pr.block(ie.Alternative, depth)
+	}
+	return out
+}
+
+func (pr *printer) functionLiteral(fl *ast.FunctionLiteral, depth int) string {
+	params := make([]string, len(fl.Parameters))
+	for ix, param := range fl.Parameters {
+		params[ix] = declaration(param)
+	}
+	out := "fn(" + strings.Join(params, ", ") + ")"
+	if fl.ReturnType != nil {
+		out += ": " + fl.ReturnType.String()
+	}
+	if body, ok := pr.inlineBlock(fl.Body, depth); ok {
+		return out + " " + body
+	}
+	return out + " " + pr.block(fl.Body, depth)
+}
+
+func declaration(ident *ast.Identifier) string {
+	if ident.Type == nil {
+		return ident.Value
+	}
+	return ident.Value + ": " + ident.Type.String()
+}
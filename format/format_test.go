@@ -0,0 +1,138 @@
+package format
+
+import (
+	"testing"
+
+	"github.com/manuelpepe/interpreter/interpreter"
+)
+
+func TestSource(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let   x=1+2*3", "let x = 1 + 2 * 3;\n"},
+		{"let x: int = (1 + 2) * 3;", "let x: int = (1 + 2) * 3;\n"},
+		{"a - (b - c) - d; (a - b) - c", "a - (b - c) - d;\na - b - c;\n"},
+		{"-(1 + 2); !(a == b); --a", "-(1 + 2);\n!(a == b);\n--a;\n"},
+		{"(fn(x) { x })(1); f(1)(2)", "(fn(x) { x })(1);\nf(1)(2);\n"},
+		{"a[1:]; a[:2]; a[:]; (a + b)[0]", "a[1:];\na[:2];\na[:];\n(a + b)[0];\n"},
+		{`"hello ${ name + "!" } bye"`, "\"hello ${name + \"!\"} bye\";\n"},
+		{"let f = fn(a:int,b:[string]):bool{true}", "let f = fn(a: int, b: [string]): bool { true };\n"},
+		{"let f = fn() {}", "let f = fn() {};\n"},
+		{"let f = fn(x) { let y = x; y }",
+			"let f = fn(x) {\n    let y = x;\n    y;\n};\n"},
+		{"if (x) { 1 } else { 2 }", "if (x) {\n    1;\n} else {\n    2;\n}\n"},
+		{"let m = if (x) { 1 } else { 2 }", "let m = if (x) { 1 } else { 2 };\n"},
+		{"let m = if (x) { let y = 1; y }", "let m = if (x) {\n    let y = 1;\n    y;\n};\n"},
+		{"{}; {\"a\": 1}; []", "{};\n{\"a\": 1};\n[];\n"},
+		{`let h = {"one": 1111111111, "two": 2222222222, "three": 3333333333, "four": 4444444444, "five": 5}`,
+			"let h = {\n    \"one\": 1111111111,\n    \"two\": 2222222222,\n    \"three\": 3333333333,\n    \"four\": 4444444444,\n    \"five\": 5\n};\n"},
+		{"let a = [fn(x) { let y = x; y }]",
+			"let a = [\n    fn(x) {\n        let y = x;\n        y;\n    }\n];\n"},
+		{"map(a, fn(x) { let y = x * 2; y })",
+			"map(a, fn(x) {\n    let y = x * 2;\n    y;\n});\n"},
+		{"let a = 1;\n\n\n\nlet b = 2;\nlet c = 3;", "let a = 1;\n\nlet b = 2;\nlet c = 3;\n"},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		out, err := Source(tt.input)
+		if err != nil {
+			t.Errorf("unexpected error formatting %q: %s", tt.input, err)
+			continue
+		}
+		if out != tt.expected {
+			t.Errorf("wrong format for %q.\nexpected=%q\ngot=     %q", tt.input, tt.expected, out)
+		}
+		testIdempotent(t, out)
+	}
+}
+
+func TestSourceComments(t *testing.T) {
+	input := `// header
+
+let a = 1;   // trailing
+// before b
+let b = fn(x) { // after brace
+  x // inside
+  // end of body
+};
+let h = {
+    "a": 1 // inside hash
+};
+if (a) {
+} // after if
+// end`
+
+	expected := `// header
+
+let a = 1; // trailing
+// before b
+let b = fn(x) {
+    // after brace
+    x; // inside
+    // end of body
+};
+let h = {"a": 1}; // inside hash
+if (a) {} // after if
+// end
+`
+	out, err := Source(input)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if out != expected {
+		t.Errorf("wrong format.\nexpected=%q\ngot=     %q", expected, out)
+	}
+	testIdempotent(t, out)
+}
+
+func TestSourceKeepsMeaning(t *testing.T) {
+	tests := []string{
+		`let x = 1; "${"$"}{x}"`,
+		`let x = 1; "a$${x}{${"}"}$"`,
+		`let x = 1; "${"$" + "{"}x}"`,
+		`let x = 1; "${x}${"{"}${"}"}"`,
+	}
+
+	for _, input := range tests {
+		out, err := Source(input)
+		if err != nil {
+			t.Errorf("unexpected error formatting %q: %s", input, err)
+			continue
+		}
+		expected, err := interpreter.New().Eval(input)
+		if err != nil {
+			t.Errorf("unexpected error evaluating %q: %s", input, err)
+			continue
+		}
+		got, err := interpreter.New().Eval(out)
+		if err != nil {
+			t.Errorf("unexpected error evaluating formatted %q: %s", out, err)
+			continue
+		}
+		if got.Inspect() != expected.Inspect() {
+			t.Errorf("format changed the result of %q.\nexpected=%q\ngot=     %q", input, expected.Inspect(), got.Inspect())
+		}
+		testIdempotent(t, out)
+	}
+}
+
+func TestSourceErrors(t *testing.T) {
+	if _, err := Source("let = 1;"); err == nil {
+		t.Errorf("expected error for invalid program")
+	}
+}
+
+func testIdempotent(t *testing.T, formatted string) {
+	t.Helper()
+	again, err := Source(formatted)
+	if err != nil {
+		t.Errorf("unexpected error formatting %q again: %s", formatted, err)
+		return
+	}
+	if again != formatted {
+		t.Errorf("format is not idempotent.\nfirst= %q\nsecond=%q", formatted, again)
+	}
+}
@@ -0,0 +1,45 @@
+// Package framing reads and writes messages framed with a Content-Length header, the base
+// protocol shared by the Language Server and Debug Adapter protocols.
+package framing
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/textproto"
+	"strconv"
+)
+
+// MaxSize is the largest message body accepted from the client.
+const MaxSize = 64 << 20
+
+// Read reads the body of the next message from r. a message with a missing, negative or too big
+// Content-Length fails, as the rest of the stream can't be read.
+func Read(r *bufio.Reader) ([]byte, error) {
+	headers, err := textproto.NewReader(r).ReadMIMEHeader()
+	if err != nil {
+		return nil, err
+	}
+	length, err := strconv.Atoi(headers.Get("Content-Length"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid Content-Length: %w", err)
+	}
+	if length < 0 || length > MaxSize {
+		return nil, fmt.Errorf("invalid Content-Length: %d is not between 0 and %d", length, MaxSize)
+	}
+
+	body := make([]byte, length)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return nil, err
+	}
+	return body, nil
+}
+
+// Write writes body to w as a single message.
+func Write(w io.Writer, body []byte) error {
+	if _, err := fmt.Fprintf(w, "Content-Length: %d\r\n\r\n", len(body)); err != nil {
+		return err
+	}
+	_, err := w.Write(body)
+	return err
+}
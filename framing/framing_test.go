@@ -0,0 +1,42 @@
+package framing
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestRoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	for _, body := range []string{`{"a":1}`, ``, `[1,2,3]`} {
+		if err := Write(&buf, []byte(body)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	r := bufio.NewReader(&buf)
+	for _, expected := range []string{`{"a":1}`, ``, `[1,2,3]`} {
+		body, err := Read(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(body) != expected {
+			t.Errorf("expected %q, got %q", expected, body)
+		}
+	}
+	if _, err := Read(r); !errors.Is(err, io.EOF) {
+		t.Errorf("expected EOF, got %v", err)
+	}
+}
+
+func TestInvalidContentLength(t *testing.T) {
+	for _, header := range []string{"Content-Length: -1", "Content-Length: 1099511627776", "Content-Length: x", "Content-Type: json"} {
+		_, err := Read(bufio.NewReader(strings.NewReader(header + "\r\n\r\n{}")))
+		if err == nil || !strings.Contains(err.Error(), "invalid Content-Length") {
+			t.Errorf("%s: expected framing error, got %v", header, err)
+		}
+	}
+}
@@ -26,6 +26,12 @@ func Graph(src string, dst string) {
 		}
 	}
 
+	GraphProgram(prog, dst)
+}
+
+// GraphProgram writes a DOT file of an already parsed (and possibly optimized) program
+// to dst and renders it as SVG.
+func GraphProgram(prog *ast.Program, dst string) {
 	g := doGraph(prog)
 
 	file, _ := os.Create(dst)
@@ -0,0 +1,246 @@
+// Package interpreter embeds Monkey in Go programs.
+//
+// An Interpreter keeps the global bindings between calls, so code can be evaluated piece by
+// piece, and has its own builtins and output:
+//
+//	interp := interpreter.New(interpreter.WithStdout(&buf))
+//	interp.Eval(`let double = fn(x) { x * 2 };`)
+//	res, err := interp.Call("double", &object.Integer{Value: 21})
+//
+// An Interpreter is not safe for concurrent use, but interpreters don't share any state so each
+// goroutine can use its own. Programs returned by Parse are never modified while evaluated, so they
+// can be parsed once and run by many interpreters at the same time with EvalProgram.
+package interpreter
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/manuelpepe/interpreter/ast"
+	"github.com/manuelpepe/interpreter/eval"
+	"github.com/manuelpepe/interpreter/lexer"
+	"github.com/manuelpepe/interpreter/object"
+	"github.com/manuelpepe/interpreter/parser"
+	"github.com/manuelpepe/interpreter/resolver"
+)
+
+type Interpreter struct {
+	env *object.Environment
+
+	stdout io.Writer
+	stderr io.Writer
+	stdin  io.Reader
+
+	builtins []object.BuiltinFunction
+	loader   ModuleLoader
+	modules  map[string]*module
+}
+
+type Option func(*Interpreter)
+
+// WithStdout sets where the `inspect` builtin prints. defaults to os.Stdout.
+func WithStdout(w io.Writer) Option {
+	return func(i *Interpreter) { i.stdout = w }
+}
+
+// WithStderr sets the error output, available to builtins through Interpreter.Stderr.
+// defaults to os.Stderr.
+func WithStderr(w io.Writer) Option {
+	return func(i *Interpreter) { i.stderr = w }
+}
+
+// WithStdin sets where the `input` builtin reads from. defaults to os.Stdin.
+func WithStdin(r io.Reader) Option {
+	return func(i *Interpreter) { i.stdin = r }
+}
+
+// WithBuiltins adds builtin functions without a spec, replacing the default ones with the same names.
+// New panics if a name is not valid, see object.Registry. builtins with a spec can be registered
+// through Interpreter.Builtins.
+func WithBuiltins(fns ...object.BuiltinFunction) Option {
+	return func(i *Interpreter) { i.builtins = append(i.builtins, fns...) }
+}
+
+// WithMaxDepth limits the depth of nested function calls, failing with an error when exceeded.
+func WithMaxDepth(n int) Option {
+	return func(i *Interpreter) { i.env.Runtime().MaxDepth = n }
+}
+
+// WithMaxSteps limits the number of statements evaluated on each call to Eval, Run or Call,
+// failing with an error when exceeded.
+func WithMaxSteps(n int) Option {
+	return func(i *Interpreter) { i.env.Runtime().MaxSteps = n }
+}
+
+// WithModuleLoader enables the `import` builtin, loading the source of modules with l.
+func WithModuleLoader(l ModuleLoader) Option {
+	return func(i *Interpreter) { i.loader = l }
+}
+
+// New creates an interpreter with an empty global environment.
+func New(opts ...Option) *Interpreter {
+	i := &Interpreter{
+		env:     object.NewEnvironment(),
+		stdout:  os.Stdout,
+		stderr:  os.Stderr,
+		stdin:   os.Stdin,
+		modules: make(map[string]*module),
+	}
+	for _, opt := range opts {
+		opt(i)
+	}
+
+	builtins := eval.Builtins()
+	inspect, _ := builtins.Get("inspect")
+	builtins.MustRegister(&eval.InspectBuiltin{Out: i.stdout}, inspect.Spec)
+	input, _ := builtins.Get("input")
+	builtins.MustRegister(&eval.InputBuiltin{In: bufio.NewReader(i.stdin), Out: i.stdout}, input.Spec)
+	builtins.MustRegister(&importBuiltin{interp: i}, &object.BuiltinSpec{
+		Params: []object.BuiltinParam{{Name: "name", Types: []object.ObjectType{object.STRING_OBJ}}},
+		Doc:    "hash with the global bindings of a module",
+	})
+	for _, fn := range i.builtins {
+		builtins.MustRegister(fn, nil)
+	}
+	i.env.Runtime().Builtins = builtins
+	return i
+}
+
+// Builtins returns the registry of builtin functions of the interpreter, which can be modified
+// between evaluations.
+func (i *Interpreter) Builtins() *object.Registry {
+	return i.env.Runtime().Builtins
+}
+
+func (i *Interpreter) Stdout() io.Writer { return i.stdout }
+func (i *Interpreter) Stderr() io.Writer { return i.stderr }
+func (i *Interpreter) Stdin() io.Reader  { return i.stdin }
+
+// SyntaxError is returned when the source can't be parsed.
+type SyntaxError struct {
+	File   string // empty when evaluating a string
+	Errors []parser.Error
+}
+
+func (e *SyntaxError) Error() string {
+	msgs := make([]string, len(e.Errors))
+	for ix, err := range e.Errors {
+		msgs[ix] = err.Error()
+		if e.File != "" {
+			msgs[ix] = e.File + ":" + msgs[ix]
+		}
+	}
+	return strings.Join(msgs, "\n")
+}
+
+// RuntimeError is returned when the evaluation results in an error.
+type RuntimeError struct {
+	Message string
+}
+
+func (e *RuntimeError) Error() string {
+	return e.Message
+}
+
+// Eval runs src in the global environment of the interpreter, returning its result.
+// the result is nil if the last statement doesn't produce a value, eg. a let statement.
+func (i *Interpreter) Eval(src string) (object.Object, error) {
+	return i.EvalContext(context.Background(), src)
+}
+
+// EvalContext is like Eval, but stops when ctx is done returning ctx.Err().
+func (i *Interpreter) EvalContext(ctx context.Context, src string) (object.Object, error) {
+	return i.eval(ctx, src, "")
+}
+
+// Run reads a file and runs it like Eval.
+func (i *Interpreter) Run(file string) (object.Object, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	return i.eval(context.Background(), string(data), file)
+}
+
+func (i *Interpreter) eval(ctx context.Context, src string, file string) (object.Object, error) {
+	prog, err := parse(src, file)
+	if err != nil {
+		return nil, err
+	}
+	return i.EvalProgram(ctx, prog)
+}
+
+// Parse parses and resolves src, returning a program that can be shared by interpreters running
+// in different goroutines.
+func Parse(src string) (*ast.Program, error) {
+	return parse(src, "")
+}
+
+func parse(src string, file string) (*ast.Program, error) {
+	p := parser.New(lexer.NewLexer(src))
+	prog := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		return nil, &SyntaxError{File: file, Errors: p.SyntaxErrors()}
+	}
+	resolver.Resolve(prog)
+	return prog, nil
+}
+
+// EvalProgram runs a program returned by Parse like EvalContext.
+func (i *Interpreter) EvalProgram(ctx context.Context, prog *ast.Program) (object.Object, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	rt := i.env.Runtime()
+	prev := rt.Context
+	rt.Steps = 0
+	rt.Context = ctx
+	defer func() { rt.Context = prev }()
+
+	res := eval.Eval(prog, i.env)
+	if err, ok := res.(*object.Error); ok {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, &RuntimeError{Message: err.Message}
+	}
+	return res, nil
+}
+
+// Get returns the value of a global binding.
+func (i *Interpreter) Get(name string) (object.Object, bool) {
+	return i.env.GetGlobal(name)
+}
+
+// Set creates or replaces a global binding.
+func (i *Interpreter) Set(name string, val object.Object) {
+	i.env.Set(name, val)
+}
+
+// Call calls the function or builtin bound to name with the given arguments, see eval.Call.
+func (i *Interpreter) Call(fnName string, args ...object.Object) (object.Object, error) {
+	return i.CallContext(context.Background(), fnName, args...)
+}
+
+// CallContext is like Call, but stops when ctx is done returning ctx.Err().
+func (i *Interpreter) CallContext(ctx context.Context, fnName string, args ...object.Object) (object.Object, error) {
+	fn, ok := i.Get(fnName)
+	if !ok {
+		builtin, isBuiltin := i.Builtins().Get(fnName)
+		if !isBuiltin {
+			return nil, fmt.Errorf("identifier not found: %s", fnName)
+		}
+		fn = builtin
+	}
+	i.env.Runtime().Steps = 0
+	res, err := eval.CallContext(ctx, fn, args...)
+	if objErr, ok := err.(*object.Error); ok {
+		return nil, &RuntimeError{Message: objErr.Message}
+	}
+	return res, err
+}
@@ -0,0 +1,363 @@
+package interpreter
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/manuelpepe/interpreter/eval"
+	"github.com/manuelpepe/interpreter/object"
+)
+
+func TestEvalKeepsBindings(t *testing.T) {
+	interp := New()
+	if _, err := interp.Eval(`let double = fn(x) { x * 2 };`); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	res, err := interp.Eval(`double(21)`)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if res.Inspect() != "42" {
+		t.Errorf("wrong result. expected=42, got=%s", res.Inspect())
+	}
+}
+
+func TestErrors(t *testing.T) {
+	interp := New()
+
+	_, err := interp.Eval(`let x = ;`)
+	var syntaxErr *SyntaxError
+	if !errors.As(err, &syntaxErr) {
+		t.Fatalf("expected a *SyntaxError, got %T (%v)", err, err)
+	}
+	if len(syntaxErr.Errors) == 0 || syntaxErr.Errors[0].Token.Line != 1 {
+		t.Errorf("wrong syntax errors: %v", syntaxErr.Errors)
+	}
+
+	_, err = interp.Eval(`1 + "a"`)
+	var runtimeErr *RuntimeError
+	if !errors.As(err, &runtimeErr) {
+		t.Fatalf("expected a *RuntimeError, got %T (%v)", err, err)
+	}
+	if runtimeErr.Message != "type mismatch: INTEGER + STRING" {
+		t.Errorf("wrong message: %q", runtimeErr.Message)
+	}
+}
+
+func TestGetSetCall(t *testing.T) {
+	interp := New()
+	interp.Set("base", &object.Integer{Value: 10})
+	if _, err := interp.Eval(`let add = fn(x) { base + x };`); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	res, err := interp.Call("add", &object.Integer{Value: 5})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if res.Inspect() != "15" {
+		t.Errorf("wrong result. expected=15, got=%s", res.Inspect())
+	}
+
+	res, err = interp.Call("len", &object.String{Value: "abc"})
+	if err != nil || res.Inspect() != "3" {
+		t.Errorf("wrong builtin call: %v, %v", res, err)
+	}
+
+	if _, err := interp.Call("missing"); err == nil || err.Error() != "identifier not found: missing" {
+		t.Errorf("wrong error for missing function: %v", err)
+	}
+
+	if val, ok := interp.Get("add"); !ok || val.Type() != object.FUNCTION_OBJ {
+		t.Errorf("wrong value for add: %v", val)
+	}
+	if _, ok := interp.Get("x"); ok {
+		t.Errorf("expected local x to not be a global")
+	}
+}
+
+func TestIO(t *testing.T) {
+	var out bytes.Buffer
+	interp := New(WithStdout(&out), WithStdin(strings.NewReader("monkey\n")))
+	if _, err := interp.Eval(`inspect("hello " + input("name: "))`); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if out.String() != "name: hello monkey\n" {
+		t.Errorf("wrong output: %q", out.String())
+	}
+
+	// interpreters don't share their output
+	var other bytes.Buffer
+	New(WithStdout(&other)).Eval(`inspect(1)`)
+	if out.String() != "name: hello monkey\n" || other.String() != "1\n" {
+		t.Errorf("wrong outputs: %q, %q", out.String(), other.String())
+	}
+}
+
+type shout struct{}
+
+func (shout) Name() string { return "shout" }
+
+func (shout) Do(args ...object.Object) object.Object {
+	return &object.String{Value: strings.ToUpper(args[0].(*object.String).Value) + "!"}
+}
+
+func TestWithBuiltins(t *testing.T) {
+	res, err := New(WithBuiltins(shout{})).Eval(`shout("abc")`)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if res.Inspect() != "ABC!" {
+		t.Errorf("wrong result: %s", res.Inspect())
+	}
+
+	if _, err := New().Eval(`shout("abc")`); err == nil {
+		t.Errorf("expected builtin to be missing from other interpreters")
+	}
+}
+
+type fetch struct{}
+
+func (fetch) Name() string { return "http.get" }
+
+func (fetch) Do(args ...object.Object) object.Object {
+	return &object.String{Value: "GET " + args[0].(*object.String).Value}
+}
+
+func TestRegistry(t *testing.T) {
+	interp := New()
+	err := interp.Builtins().Register(fetch{}, &object.BuiltinSpec{
+		Params: []object.BuiltinParam{{Name: "url", Types: []object.ObjectType{object.STRING_OBJ}}},
+		Doc:    "fetches url",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	res, err := interp.Eval(`let http_get = http["get"]; http_get("/")`)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if res.Inspect() != "GET /" {
+		t.Errorf("wrong result: %s", res.Inspect())
+	}
+
+	if _, err := interp.Eval(`http["get"](1)`); err == nil || err.Error() != "argument 1 to `http.get` must be STRING, got INTEGER" {
+		t.Errorf("wrong error: %v", err)
+	}
+	if res, err := interp.Call("http.get", &object.String{Value: "/a"}); err != nil || res.Inspect() != "GET /a" {
+		t.Errorf("wrong call result: %v, %v", res, err)
+	}
+
+	if _, err := New().Eval(`http`); err == nil {
+		t.Errorf("expected namespace to be missing from other interpreters")
+	}
+}
+
+type cancelBuiltin struct{ cancel context.CancelFunc }
+
+func (cb cancelBuiltin) Name() string { return "cancel" }
+
+func (cb cancelBuiltin) Do(args ...object.Object) object.Object {
+	cb.cancel()
+	return &object.Null{}
+}
+
+func TestContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	interp := New(WithBuiltins(cancelBuiltin{cancel}))
+
+	if _, err := interp.EvalContext(ctx, `let f = fn() { cancel(); 1 }; f(); 2`); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+	if _, err := interp.CallContext(ctx, "f"); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+	if res, err := interp.Call("f"); err != nil || res.Inspect() != "1" {
+		t.Errorf("wrong result without context: %v, %v", res, err)
+	}
+}
+
+func TestLimits(t *testing.T) {
+	tests := []struct {
+		opt      Option
+		src      string
+		expected string
+	}{
+		{WithMaxDepth(50), `let f = fn(n) { f(n + 1) }; f(0)`, "maximum call depth exceeded: 50"},
+		{WithMaxSteps(100), `let f = fn(n) { if (n == 0) { 0 } else { f(n - 1) } }; f(1000)`, "step limit exceeded: 100"},
+	}
+	for _, tt := range tests {
+		interp := New(tt.opt)
+		_, err := interp.Eval(tt.src)
+		if err == nil || err.Error() != tt.expected {
+			t.Errorf("wrong error. expected=%q, got=%v", tt.expected, err)
+		}
+
+		// limits apply to each evaluation, not to the lifetime of the interpreter.
+		res, err := interp.Eval(`let g = fn(n) { if (n == 0) { 0 } else { g(n - 1) } }; g(10)`)
+		if err != nil || res.Inspect() != "0" {
+			t.Errorf("wrong result after limit: %v, %v", res, err)
+		}
+	}
+}
+
+func TestImport(t *testing.T) {
+	loads := 0
+	modules := map[string]string{
+		"math":  `let square = fn(x) { x * x }; let pi = 3;`,
+		"a":     `let b = import("b");`,
+		"b":     `let a = import("a");`,
+		"wrong": `let x = ;`,
+	}
+	loader := func(name string) (string, error) {
+		loads++
+		src, ok := modules[name]
+		if !ok {
+			return "", fmt.Errorf("module not found")
+		}
+		return src, nil
+	}
+	interp := New(WithModuleLoader(loader))
+
+	res, err := interp.Eval(`let math = import("math"); math["square"](math["pi"])`)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if res.Inspect() != "9" {
+		t.Errorf("wrong result: %s", res.Inspect())
+	}
+	interp.Eval(`import("math")`)
+	if loads != 1 {
+		t.Errorf("expected module to be loaded once, got %d", loads)
+	}
+
+	errs := map[string]string{
+		`import("a")`:       "import cycle: a",
+		`import("missing")`: "can't import missing: module not found",
+		`import("wrong")`:   "can't import wrong: no prefix parse function for ; found",
+		`import(1)`:         "argument 1 to `import` must be STRING, got INTEGER",
+	}
+	for src, expected := range errs {
+		if _, err := interp.Eval(src); err == nil || err.Error() != expected {
+			t.Errorf("wrong error for %s. expected=%q, got=%v", src, expected, err)
+		}
+	}
+
+	if _, err := New().Eval(`import("math")`); err == nil || err.Error() != "can't import math: no module loader" {
+		t.Errorf("wrong error without loader: %v", err)
+	}
+}
+
+func TestRunAndDirLoader(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "lib.monkey"), []byte(`let greet = fn(name) { "hi " + name };`), 0o644)
+	os.WriteFile(filepath.Join(dir, "main.monkey"), []byte(`let lib = import("lib"); lib["greet"]("there")`), 0o644)
+
+	res, err := New(WithModuleLoader(DirLoader(dir))).Run(filepath.Join(dir, "main.monkey"))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if res.Inspect() != "hi there" {
+		t.Errorf("wrong result: %s", res.Inspect())
+	}
+
+	if _, err := New().Run(filepath.Join(dir, "nope.monkey")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected a not exist error, got %v", err)
+	}
+}
+
+// TestConcurrent runs many interpreters at the same time sharing a parsed program, run it with
+// -race to check that they don't share state.
+func TestConcurrent(t *testing.T) {
+	prog, err := Parse(`
+let lib = import("lib");
+let fib = fn(x) { if (x < 2) { x } else { fib(x - 1) + fib(x - 2) } };
+let words = map(range(n), fn(i) { format("w%d", i) });
+inspect(join(words, ","));
+inspect(input());
+lib["twice"](fib(n)) + sum(filter(range(n), fn(x) { x == x })) + len(sort(words)) * 0
+`)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	loader := func(name string) (string, error) {
+		return `let twice = fn(x) { x * 2 };`, nil
+	}
+	fib := []int64{0, 1, 1, 2, 3, 5, 8, 13, 21, 34, 55, 89, 144}
+
+	// the goroutines wait to start evaluating at the same time, and don't do anything else while
+	// evaluating, as other work like using fmt would order their memory accesses hiding races from
+	// the detector. the results are checked afterwards.
+	type run struct {
+		interp *Interpreter
+		n      int64
+		out    bytes.Buffer
+		res    object.Object
+		err    error
+	}
+	runs := make([]run, 300)
+	start := make(chan struct{})
+	var wg sync.WaitGroup
+	for ix := range runs {
+		r := &runs[ix]
+		r.n = int64(ix % len(fib))
+		r.interp = New(
+			WithStdout(&r.out),
+			WithStdin(strings.NewReader("line "+strconv.Itoa(ix)+"\n")),
+			WithModuleLoader(loader),
+			WithMaxSteps(10_000),
+		)
+		r.interp.Set("n", &object.Integer{Value: r.n})
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			r.res, r.err = r.interp.EvalProgram(context.Background(), prog)
+		}()
+	}
+	close(start)
+	wg.Wait()
+
+	for ix, r := range runs {
+		if r.err != nil {
+			t.Errorf("%d: unexpected error: %s", ix, r.err)
+			continue
+		}
+		if expected := fib[r.n]*2 + r.n*(r.n-1)/2; r.res.(*object.Integer).Value != expected {
+			t.Errorf("%d: wrong result. expected=%d, got=%s", ix, expected, r.res.Inspect())
+		}
+		words := make([]string, r.n)
+		for jx := range words {
+			words[jx] = fmt.Sprintf("w%d", jx)
+		}
+		if expected := fmt.Sprintf("%s\nline %d\n", strings.Join(words, ","), ix); r.out.String() != expected {
+			t.Errorf("%d: wrong output. expected=%q, got=%q", ix, expected, r.out.String())
+		}
+	}
+
+	// callbacks and evaluation errors are also local to each interpreter
+	for ix := range runs {
+		r := &runs[ix]
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			double, _ := r.interp.Eval(`fn(x) { x * 2 }`)
+			if res, err := eval.Call(double, &object.Integer{Value: r.n}); err != nil || res.(*object.Integer).Value != r.n*2 {
+				t.Errorf("%d: wrong callback result: %v, %v", ix, res, err)
+			}
+			if _, err := r.interp.Eval(`fib(30)`); err == nil || err.Error() != "step limit exceeded: 10000" {
+				t.Errorf("%d: wrong error: %v", ix, err)
+			}
+		}()
+	}
+	wg.Wait()
+}
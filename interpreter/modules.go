@@ -0,0 +1,84 @@
+package interpreter
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/manuelpepe/interpreter/eval"
+	"github.com/manuelpepe/interpreter/lexer"
+	"github.com/manuelpepe/interpreter/object"
+	"github.com/manuelpepe/interpreter/parser"
+)
+
+// ModuleLoader returns the source of a module given the name used to import it.
+type ModuleLoader func(name string) (string, error)
+
+// DirLoader loads modules from files in dir, with the .monkey extension being optional.
+func DirLoader(dir string) ModuleLoader {
+	return func(name string) (string, error) {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if filepath.Ext(path) == "" {
+			path += ".monkey"
+		}
+		data, err := os.ReadFile(path)
+		return string(data), err
+	}
+}
+
+type module struct {
+	exports *object.Hash // nil while the module is being loaded
+}
+
+// importBuiltin runs a module once and returns a hash with its global bindings.
+type importBuiltin struct {
+	interp *Interpreter
+}
+
+func (ib *importBuiltin) Name() string {
+	return "import"
+}
+
+func (ib *importBuiltin) Do(args ...object.Object) object.Object {
+	name := args[0].(*object.String) // checked by its spec
+
+	i := ib.interp
+	if mod, ok := i.modules[name.Value]; ok {
+		if mod.exports == nil {
+			return newError("import cycle: %s", name.Value)
+		}
+		return mod.exports
+	}
+	if i.loader == nil {
+		return newError("can't import %s: no module loader", name.Value)
+	}
+	src, err := i.loader(name.Value)
+	if err != nil {
+		return newError("can't import %s: %v", name.Value, err)
+	}
+
+	p := parser.New(lexer.NewLexer(src))
+	prog := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		return newError("can't import %s: %s", name.Value, strings.Join(p.Errors(), ", "))
+	}
+
+	mod := &module{}
+	i.modules[name.Value] = mod
+	env := object.NewEnvironmentWithRuntime(i.env.Runtime())
+	if res := eval.Eval(prog, env); res != nil && res.Type() == object.ERROR_OBJ {
+		delete(i.modules, name.Value)
+		return res
+	}
+
+	mod.exports = object.NewHash()
+	for _, b := range env.Bindings() {
+		mod.exports.Set(&object.String{Value: b.Name}, b.Value)
+	}
+	return mod.exports
+}
+
+func newError(format string, a ...interface{}) *object.Error {
+	return &object.Error{Message: fmt.Sprintf(format, a...)}
+}
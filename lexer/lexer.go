@@ -1,38 +1,64 @@
 package lexer
 
 import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
 	"github.com/manuelpepe/interpreter/token"
 )
 
 type Lexer struct {
 	input   string
-	readPos int  // next position to read
-	pos     int  // current position read (points to ch)
-	ch      byte // last byte read from input
+	readPos int  // next position to read (byte offset)
+	pos     int  // current position read (byte offset, points to ch)
+	ch      rune // last rune decoded from input
+
+	line int // line of ch, starting from 1
+	col  int // column of ch in runes, starting from 1
+
+	// templates holds, for each interpolated string currently open, the number of unclosed
+	// braces inside its current `${ }` expression.
+	templates []int
+
+	comments []token.Token
 }
 
+// readChar decodes the next UTF-8 rune from the input. invalid sequences are decoded as utf8.RuneError.
 func (l *Lexer) readChar() {
+	if l.ch == '\n' {
+		l.line += 1
+		l.col = 1
+	} else {
+		l.col += 1
+	}
 	if l.readPos >= len(l.input) {
 		l.ch = 0
-	} else {
-		l.ch = l.input[l.readPos]
+		l.pos = l.readPos
+		l.readPos += 1
+		return
 	}
+	r, width := utf8.DecodeRuneInString(l.input[l.readPos:])
+	l.ch = r
 	l.pos = l.readPos
-	l.readPos += 1
+	l.readPos += width
 }
 
-func (l *Lexer) peekChar() byte {
+func (l *Lexer) peekChar() rune {
 	if l.readPos >= len(l.input) {
 		return 0
-	} else {
-		return l.input[l.readPos]
 	}
+	r, _ := utf8.DecodeRuneInString(l.input[l.readPos:])
+	return r
 }
 
-func (l *Lexer) NextToken() token.Token {
-	var tok token.Token
+func (l *Lexer) NextToken() (tok token.Token) {
 
 	l.skipWhitespace()
+	line, col := l.line, l.col
+	defer func() {
+		tok.Line, tok.Column = line, col
+	}()
 
 	switch l.ch {
 	case '=':
@@ -58,9 +84,26 @@ func (l *Lexer) NextToken() token.Token {
 	case '-':
 		tok = newToken(token.MINUS, l.ch)
 	case '{':
+		if n := len(l.templates); n > 0 {
+			l.templates[n-1] += 1
+		}
 		tok = newToken(token.LBRACE, l.ch)
 	case '}':
-		tok = newToken(token.RBRACE, l.ch)
+		if n := len(l.templates); n > 0 && l.templates[n-1] == 0 {
+			lit, interpolated := l.readString()
+			tok.Literal = lit
+			if interpolated {
+				tok.Type = token.TEMPLATE_MIDDLE
+			} else {
+				tok.Type = token.TEMPLATE_END
+				l.templates = l.templates[:n-1]
+			}
+		} else {
+			if n > 0 {
+				l.templates[n-1] -= 1
+			}
+			tok = newToken(token.RBRACE, l.ch)
+		}
 	case '[':
 		tok = newToken(token.LBRACKET, l.ch)
 	case ']':
@@ -82,8 +125,14 @@ func (l *Lexer) NextToken() token.Token {
 	case '>':
 		tok = newToken(token.GT, l.ch)
 	case '"':
-		tok.Literal = l.readString()
-		tok.Type = token.STRING
+		lit, interpolated := l.readString()
+		tok.Literal = lit
+		if interpolated {
+			tok.Type = token.TEMPLATE_START
+			l.templates = append(l.templates, 0)
+		} else {
+			tok.Type = token.STRING
+		}
 	case 0:
 		tok.Literal = ""
 		tok.Type = token.EOF
@@ -106,15 +155,22 @@ func (l *Lexer) NextToken() token.Token {
 	return tok
 }
 
-// readString reads an string starting from the current position (which must be the starting quote)
-// advancing it until it encounters the closing quote. it leaves the lexer in the position following the ending quote.
-func (l *Lexer) readString() string {
+// readString reads an string starting from the current position (which must be the starting quote, or the
+// closing brace of an interpolated expression) advancing it until it encounters the closing quote or the start
+// of an interpolated expression `${`, in which case it also reports true.
+// it leaves the lexer on the ending quote or on the opening brace of the interpolation.
+func (l *Lexer) readString() (string, bool) {
 	l.readChar()
 	pos := l.pos
 	for l.ch != '"' && l.ch != 0 {
+		if l.ch == '$' && l.peekChar() == '{' {
+			lit := l.input[pos:l.pos]
+			l.readChar()
+			return lit, true
+		}
 		l.readChar()
 	}
-	return l.input[pos:l.pos]
+	return l.input[pos:l.pos], false
 }
 
 // readIdent reads an identifier starting from the current position, advancing it until it encounters a non-letter character.
@@ -135,30 +191,49 @@ func (l *Lexer) readNumber() string {
 	return l.input[pos:l.pos]
 }
 
+// skipWhitespace advances the lexer over whitespace and comments, keeping the comments found.
 func (l *Lexer) skipWhitespace() {
-	for isWhitespace(l.ch) {
-		l.readChar()
+	for {
+		for isWhitespace(l.ch) {
+			l.readChar()
+		}
+		if l.ch != '/' || l.peekChar() != '/' {
+			return
+		}
+		tok := token.Token{Type: token.COMMENT, Line: l.line, Column: l.col}
+		pos := l.pos
+		for l.ch != '\n' && l.ch != 0 {
+			l.readChar()
+		}
+		tok.Literal = strings.TrimRight(l.input[pos:l.pos], " \t\r")
+		l.comments = append(l.comments, tok)
 	}
 }
 
-func isNumber(ch byte) bool {
+// Comments returns the comments skipped so far, in source order.
+func (l *Lexer) Comments() []token.Token {
+	return l.comments
+}
+
+func isNumber(ch rune) bool {
 	return '0' <= ch && '9' >= ch
 }
 
-func isLetter(ch byte) bool {
-	return 'a' <= ch && ch <= 'z' || 'A' <= ch && ch <= 'Z' || ch == '_'
+// isLetter reports whether ch can be part of an identifier. any unicode letter is accepted.
+func isLetter(ch rune) bool {
+	return 'a' <= ch && ch <= 'z' || 'A' <= ch && ch <= 'Z' || ch == '_' || ch >= utf8.RuneSelf && unicode.IsLetter(ch)
 }
 
-func isWhitespace(ch byte) bool {
+func isWhitespace(ch rune) bool {
 	return ch == ' ' || ch == '\t' || ch == '\n' || ch == '\r'
 }
 
-func newToken(tokenType token.TokenType, ch byte) token.Token {
+func newToken(tokenType token.TokenType, ch rune) token.Token {
 	return token.Token{Type: tokenType, Literal: string(ch)}
 }
 
 func NewLexer(inp string) *Lexer {
-	l := &Lexer{input: inp}
+	l := &Lexer{input: inp, line: 1}
 	l.readChar()
 	return l
 }
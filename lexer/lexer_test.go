@@ -137,10 +137,10 @@ lst[1]
 	for i, tt := range tests {
 		tok := l.NextToken()
 		exp := token.Token{Type: tt.expType, Literal: tt.expLit}
-		if tok != exp {
+		if tok.Type != exp.Type || tok.Literal != exp.Literal {
 			t.Fatalf(
-				"tests[%d] - tokentype wrong. expected=%q, got=%q",
-				i, exp, tok,
+				"tests[%d] - tokentype wrong. expected=%q %q, got=%q %q",
+				i, exp.Type, exp.Literal, tok.Type, tok.Literal,
 			)
 		}
 	}
@@ -161,11 +161,177 @@ func TestUnclosedString(t *testing.T) {
 	for i, tt := range tests {
 		tok := l.NextToken()
 		exp := token.Token{Type: tt.expType, Literal: tt.expLit}
-		if tok != exp {
+		if tok.Type != exp.Type || tok.Literal != exp.Literal {
 			t.Fatalf(
-				"tests[%d] - tokentype wrong. expected=%q, got=%q",
-				i, exp, tok,
+				"tests[%d] - tokentype wrong. expected=%q %q, got=%q %q",
+				i, exp.Type, exp.Literal, tok.Type, tok.Literal,
 			)
 		}
 	}
 }
+
+func TestUnicodeInput(t *testing.T) {
+	input := `let año = "héllo 世界";
+let π = 3;
+€`
+	tests := []struct {
+		expType token.TokenType
+		expLit  string
+	}{
+		{token.LET, "let"},
+		{token.IDENT, "año"},
+		{token.ASSIGN, "="},
+		{token.STRING, "héllo 世界"},
+		{token.SEMICOLON, ";"},
+		{token.LET, "let"},
+		{token.IDENT, "π"},
+		{token.ASSIGN, "="},
+		{token.INT, "3"},
+		{token.SEMICOLON, ";"},
+		{token.ILLEGAL, "€"},
+		{token.EOF, ""},
+	}
+
+	l := NewLexer(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+		exp := token.Token{Type: tt.expType, Literal: tt.expLit}
+		if tok.Type != exp.Type || tok.Literal != exp.Literal {
+			t.Fatalf(
+				"tests[%d] - tokentype wrong. expected=%q %q, got=%q %q",
+				i, exp.Type, exp.Literal, tok.Type, tok.Literal,
+			)
+		}
+	}
+}
+
+func TestInterpolatedStrings(t *testing.T) {
+	input := `"Hello ${name}, you are ${age + 1}"
+"${ {"a": 1}["a"] }"
+"a ${ "b ${c}" } d"`
+	tests := []struct {
+		expType token.TokenType
+		expLit  string
+	}{
+		{token.TEMPLATE_START, "Hello "},
+		{token.IDENT, "name"},
+		{token.TEMPLATE_MIDDLE, ", you are "},
+		{token.IDENT, "age"},
+		{token.PLUS, "+"},
+		{token.INT, "1"},
+		{token.TEMPLATE_END, ""},
+		{token.TEMPLATE_START, ""},
+		{token.LBRACE, "{"},
+		{token.STRING, "a"},
+		{token.COLON, ":"},
+		{token.INT, "1"},
+		{token.RBRACE, "}"},
+		{token.LBRACKET, "["},
+		{token.STRING, "a"},
+		{token.RBRACKET, "]"},
+		{token.TEMPLATE_END, ""},
+		{token.TEMPLATE_START, "a "},
+		{token.TEMPLATE_START, "b "},
+		{token.IDENT, "c"},
+		{token.TEMPLATE_END, ""},
+		{token.TEMPLATE_END, " d"},
+		{token.EOF, ""},
+	}
+
+	l := NewLexer(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+		exp := token.Token{Type: tt.expType, Literal: tt.expLit}
+		if tok.Type != exp.Type || tok.Literal != exp.Literal {
+			t.Fatalf(
+				"tests[%d] - tokentype wrong. expected=%q %q, got=%q %q",
+				i, exp.Type, exp.Literal, tok.Type, tok.Literal,
+			)
+		}
+	}
+}
+
+func TestTokenPositions(t *testing.T) {
+	input := `let x = 5;
+  if (x) {
+	"héllo ${x}" }`
+
+	tests := []struct {
+		expType token.TokenType
+		line    int
+		column  int
+	}{
+		{token.LET, 1, 1},
+		{token.IDENT, 1, 5},
+		{token.ASSIGN, 1, 7},
+		{token.INT, 1, 9},
+		{token.SEMICOLON, 1, 10},
+		{token.IF, 2, 3},
+		{token.LPAREN, 2, 6},
+		{token.IDENT, 2, 7},
+		{token.RPAREN, 2, 8},
+		{token.LBRACE, 2, 10},
+		{token.TEMPLATE_START, 3, 2},
+		{token.IDENT, 3, 11},
+		{token.TEMPLATE_END, 3, 12},
+		{token.RBRACE, 3, 15},
+		{token.EOF, 3, 16},
+	}
+
+	l := NewLexer(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+		if tok.Type != tt.expType || tok.Line != tt.line || tok.Column != tt.column {
+			t.Fatalf("tests[%d] - wrong token. expected=%s at %d:%d, got=%s at %d:%d",
+				i, tt.expType, tt.line, tt.column, tok.Type, tok.Line, tok.Column)
+		}
+	}
+}
+
+func TestComments(t *testing.T) {
+	input := `// leading
+let x = 10 / 2; // trailing
+// last`
+
+	tests := []struct {
+		expType token.TokenType
+		expLit  string
+	}{
+		{token.LET, "let"},
+		{token.IDENT, "x"},
+		{token.ASSIGN, "="},
+		{token.INT, "10"},
+		{token.SLASH, "/"},
+		{token.INT, "2"},
+		{token.SEMICOLON, ";"},
+		{token.EOF, ""},
+	}
+
+	l := NewLexer(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+		if tok.Type != tt.expType || tok.Literal != tt.expLit {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q %q, got=%q %q",
+				i, tt.expType, tt.expLit, tok.Type, tok.Literal)
+		}
+	}
+
+	expected := []token.Token{
+		{Type: token.COMMENT, Literal: "// leading", Line: 1, Column: 1},
+		{Type: token.COMMENT, Literal: "// trailing", Line: 2, Column: 17},
+		{Type: token.COMMENT, Literal: "// last", Line: 3, Column: 1},
+	}
+	comments := l.Comments()
+	if len(comments) != len(expected) {
+		t.Fatalf("wrong number of comments. expected=%d, got=%d", len(expected), len(comments))
+	}
+	for ix, c := range comments {
+		if c != expected[ix] {
+			t.Errorf("comments[%d] wrong. expected=%+v, got=%+v", ix, expected[ix], c)
+		}
+	}
+}
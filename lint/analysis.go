@@ -0,0 +1,112 @@
+package lint
+
+import (
+	"github.com/manuelpepe/interpreter/ast"
+	"github.com/manuelpepe/interpreter/resolver"
+)
+
+type declaration struct {
+	ident *ast.Identifier // first declaration of the binding
+	param bool
+	lets  int
+	refs  int
+	fn    *ast.FunctionLiteral // value of the let, if it's a function literal
+}
+
+type reference struct {
+	ident   *ast.Identifier
+	binding resolver.Binding
+}
+
+type callSite struct {
+	call    *ast.CallExpression
+	binding *resolver.Binding // nil if the function called is not an identifier
+}
+
+// analysis holds the information about a program shared by all rules.
+type analysis struct {
+	decls map[resolver.Binding]*declaration
+	order []resolver.Binding // in declaration order
+
+	refs      []reference
+	undefined []*ast.Identifier // references to globals that are never declared
+	calls     []callSite
+	ifs       []*ast.IfExpression
+	blocks    [][]ast.Statement // every list of statements, including the program
+
+	scopes []*ast.FunctionLiteral // enclosing functions while walking, innermost last
+}
+
+func analyze(prog *ast.Program) *analysis {
+	a := &analysis{decls: make(map[resolver.Binding]*declaration)}
+	a.blocks = append(a.blocks, prog.Statements)
+	for _, stmt := range prog.Statements {
+		a.walk(stmt)
+	}
+	// lets are hoisted, so references are only matched with their declarations after the whole
+	// program has been walked.
+	for _, ref := range a.refs {
+		if decl, ok := a.decls[ref.binding]; ok {
+			decl.refs += 1
+		} else if ref.binding.Scope == nil {
+			a.undefined = append(a.undefined, ref.ident)
+		}
+	}
+	return a
+}
+
+func (a *analysis) bindingOf(ident *ast.Identifier) resolver.Binding {
+	b, _ := resolver.BindingOf(a.scopes, ident)
+	return b
+}
+
+func (a *analysis) declare(ident *ast.Identifier, param bool) *declaration {
+	b := a.bindingOf(ident)
+	decl, ok := a.decls[b]
+	if !ok {
+		decl = &declaration{ident: ident, param: param}
+		a.decls[b] = decl
+		a.order = append(a.order, b)
+	}
+	return decl
+}
+
+func (a *analysis) walk(node ast.Node) {
+	switch node := node.(type) {
+	case nil:
+		return
+	case *ast.Identifier:
+		a.refs = append(a.refs, reference{ident: node, binding: a.bindingOf(node)})
+		return
+	case *ast.LetStatement:
+		a.walk(node.Value)
+		decl := a.declare(node.Name, false)
+		decl.lets += 1
+		if fl, ok := node.Value.(*ast.FunctionLiteral); ok {
+			decl.fn = fl
+		}
+		return
+	case *ast.FunctionLiteral:
+		a.scopes = append(a.scopes, node)
+		for _, param := range node.Parameters {
+			a.declare(param, true)
+		}
+		a.walk(node.Body)
+		a.scopes = a.scopes[:len(a.scopes)-1]
+		return
+	case *ast.BlockStatement:
+		a.blocks = append(a.blocks, node.Statements)
+	case *ast.IfExpression:
+		a.ifs = append(a.ifs, node)
+	case *ast.CallExpression:
+		site := callSite{call: node}
+		if ident, ok := node.Function.(*ast.Identifier); ok {
+			b := a.bindingOf(ident)
+			site.binding = &b
+		}
+		a.calls = append(a.calls, site)
+	}
+	for _, child := range node.ChildNodes() {
+		a.walk(child)
+	}
+}
@@ -0,0 +1,83 @@
+// Package lint reports common mistakes in Monkey programs that are not errors for the parser.
+//
+// Each check is a Rule that can be enabled or disabled by name. Bindings are told apart using the
+// lexical addresses computed by the resolver, so shadowed names are not confused with each other.
+package lint
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/manuelpepe/interpreter/ast"
+	"github.com/manuelpepe/interpreter/resolver"
+	"github.com/manuelpepe/interpreter/token"
+)
+
+// Diagnostic is a single problem found by a rule.
+type Diagnostic struct {
+	Rule    string `json:"rule"`
+	Line    int    `json:"line"`
+	Column  int    `json:"column"`
+	Message string `json:"message"`
+}
+
+func (d Diagnostic) String() string {
+	return fmt.Sprintf("%d:%d: %s (%s)", d.Line, d.Column, d.Message, d.Rule)
+}
+
+type reportFunc = func(tok token.Token, format string, args ...any)
+
+// Rule is a check that can be toggled individually.
+type Rule struct {
+	Name        string
+	Description string
+
+	check func(a *analysis, report reportFunc)
+}
+
+// Rules holds all available rules, all of them are enabled by default.
+var Rules = []*Rule{
+	unusedRule,
+	shadowedBuiltinRule,
+	undefinedRule,
+	unreachableRule,
+	arityRule,
+	constantConditionRule,
+}
+
+// LookupRule returns the rule with the given name.
+func LookupRule(name string) (*Rule, bool) {
+	for _, r := range Rules {
+		if r.Name == name {
+			return r, true
+		}
+	}
+	return nil, false
+}
+
+// Lint runs the given rules over prog, returning their diagnostics sorted by position.
+// the program is resolved if it wasn't already.
+func Lint(prog *ast.Program, rules []*Rule) []Diagnostic {
+	resolver.Resolve(prog)
+	a := analyze(prog)
+
+	diags := make([]Diagnostic, 0)
+	for _, rule := range rules {
+		rule.check(a, func(tok token.Token, format string, args ...any) {
+			diags = append(diags, Diagnostic{
+				Rule:    rule.Name,
+				Line:    tok.Line,
+				Column:  tok.Column,
+				Message: fmt.Sprintf(format, args...),
+			})
+		})
+	}
+
+	sort.SliceStable(diags, func(i, j int) bool {
+		if diags[i].Line != diags[j].Line {
+			return diags[i].Line < diags[j].Line
+		}
+		return diags[i].Column < diags[j].Column
+	})
+	return diags
+}
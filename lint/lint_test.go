@@ -0,0 +1,105 @@
+package lint
+
+import (
+	"testing"
+
+	"github.com/manuelpepe/interpreter/ast"
+	"github.com/manuelpepe/interpreter/lexer"
+	"github.com/manuelpepe/interpreter/parser"
+)
+
+func TestRules(t *testing.T) {
+	tests := []struct {
+		rule     string
+		input    string
+		expected []string
+	}{
+		{"unused", `let x = 1; let y = 2; y`, []string{"1:5: unused variable x (unused)"}},
+		{"unused", `let f = fn(a, b) { a }; f(1, 2)`, []string{"1:15: unused parameter b (unused)"}},
+		{"unused", `let f = fn(a, _b) { a }; f(1, 2)`, []string{}},
+		{"unused", `let f = fn() { g() }; let g = fn() { 1 }; f()`, []string{}},
+		{"unused", `let f = fn() { let x = 1; let inner = fn() { x }; inner() }; f()`, []string{}},
+		{"unused", `let x = 1; let f = fn(x) { x }; f(2)`, []string{"1:5: unused variable x (unused)"}},
+		{"shadowed-builtin", `let len = 1; let f = fn(first) { first }; f(len)`, []string{
+			"1:5: len shadows the builtin function len (shadowed-builtin)",
+			"1:25: first shadows the builtin function first (shadowed-builtin)",
+		}},
+		{"undefined", `let f = fn() { x + y }; let x = 1; len(z)`, []string{
+			"1:20: identifier not found: y (undefined)",
+			"1:40: identifier not found: z (undefined)",
+		}},
+		{"undefined", `let f = fn(a) { let b = 1; a + b + len(a) }`, []string{}},
+		{"unreachable", `let f = fn() { return 1; let x = 2; x }; return 2; 3`, []string{
+			"1:26: unreachable code after return (unreachable)",
+			"1:52: unreachable code after return (unreachable)",
+		}},
+		{"unreachable", `if (x) { return 1; }; 2`, []string{}},
+		{"arity", `let f = fn(a, b) { a }; f(1); f(1, 2); f(1, 2, 3)`, []string{
+			"1:26: wrong number of arguments to f. got=1, want=2 (arity)",
+			"1:41: wrong number of arguments to f. got=3, want=2 (arity)",
+		}},
+		{"arity", `fn(a) { a }()`, []string{"1:12: wrong number of arguments to fn(a) { a; }. got=0, want=1 (arity)"}},
+		{"arity", `let f = fn(a) { a }; let f = fn() { 1 }; f()`, []string{}},
+		{"arity", `let call = fn(f) { f(1, 2) }; call(fn(a) { a })`, []string{}},
+		{"arity", `len("x", "y"); len("x")`, []string{"1:4: wrong number of arguments to len. got=2, want=1 (arity)"}},
+		{"arity", `pad_left("x"); pad_left("x", 2, " ", 1)`, []string{
+			"1:9: wrong number of arguments to pad_left. got=1, want=2..3 (arity)",
+			"1:24: wrong number of arguments to pad_left. got=4, want=2..3 (arity)",
+		}},
+		{"arity", `let f = fn(len) { len(1, 2) }; f(1)`, []string{}},
+		{"constant-condition", `if (true) { 1 }; if (1 < 2) { 1 }; if (x) { 1 }; if (!x) { 1 }`, []string{
+			"1:1: constant if condition true (constant-condition)",
+			"1:18: constant if condition (1 < 2) (constant-condition)",
+		}},
+	}
+
+	for _, tt := range tests {
+		rule, ok := LookupRule(tt.rule)
+		if !ok {
+			t.Fatalf("rule %s not found", tt.rule)
+		}
+		diags := Lint(parse(t, tt.input), []*Rule{rule})
+		if len(diags) != len(tt.expected) {
+			t.Errorf("wrong number of diagnostics for %q. expected=%v, got=%v", tt.input, tt.expected, diags)
+			continue
+		}
+		for ix, diag := range diags {
+			if diag.String() != tt.expected[ix] {
+				t.Errorf("wrong diagnostic for %q. expected=%q, got=%q", tt.input, tt.expected[ix], diag.String())
+			}
+		}
+	}
+}
+
+func TestLintSortsDiagnostics(t *testing.T) {
+	input := `let len = fn(a) { return a; 1 };
+len(1, 2);
+if (true) { y }`
+
+	diags := Lint(parse(t, input), Rules)
+	expected := []string{
+		"1:5: len shadows the builtin function len (shadowed-builtin)",
+		"1:29: unreachable code after return (unreachable)",
+		"2:4: wrong number of arguments to len. got=2, want=1 (arity)",
+		"3:1: constant if condition true (constant-condition)",
+		"3:13: identifier not found: y (undefined)",
+	}
+	if len(diags) != len(expected) {
+		t.Fatalf("wrong diagnostics. expected=%v, got=%v", expected, diags)
+	}
+	for ix, diag := range diags {
+		if diag.String() != expected[ix] {
+			t.Errorf("wrong diagnostic %d. expected=%q, got=%q", ix, expected[ix], diag.String())
+		}
+	}
+}
+
+func parse(t *testing.T, input string) *ast.Program {
+	t.Helper()
+	p := parser.New(lexer.NewLexer(input))
+	prog := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("parser errors: %v", p.Errors())
+	}
+	return prog
+}
@@ -0,0 +1,148 @@
+package lint
+
+import (
+	"strings"
+
+	"github.com/manuelpepe/interpreter/ast"
+	"github.com/manuelpepe/interpreter/eval"
+	"github.com/manuelpepe/interpreter/object"
+)
+
+var unusedRule = &Rule{
+	Name:        "unused",
+	Description: "let bindings and parameters that are never used (names starting with _ are ignored)",
+	check: func(a *analysis, report reportFunc) {
+		for _, b := range a.order {
+			decl := a.decls[b]
+			if decl.refs > 0 || strings.HasPrefix(b.Name, "_") {
+				continue
+			}
+			if decl.param {
+				report(decl.ident.Token, "unused parameter %s", b.Name)
+			} else {
+				report(decl.ident.Token, "unused variable %s", b.Name)
+			}
+		}
+	},
+}
+
+var shadowedBuiltinRule = &Rule{
+	Name:        "shadowed-builtin",
+	Description: "let bindings and parameters named like a builtin function",
+	check: func(a *analysis, report reportFunc) {
+		for _, b := range a.order {
+			if eval.IsBuiltin(b.Name) {
+				report(a.decls[b].ident.Token, "%s shadows the builtin function %s", b.Name, b.Name)
+			}
+		}
+	},
+}
+
+var undefinedRule = &Rule{
+	Name:        "undefined",
+	Description: "identifiers that are never bound",
+	check: func(a *analysis, report reportFunc) {
+		for _, ident := range a.undefined {
+			if !eval.IsBuiltin(ident.Value) {
+				report(ident.Token, "identifier not found: %s", ident.Value)
+			}
+		}
+	},
+}
+
+var unreachableRule = &Rule{
+	Name:        "unreachable",
+	Description: "statements after a return",
+	check: func(a *analysis, report reportFunc) {
+		for _, stmts := range a.blocks {
+			for ix, stmt := range stmts[:max(len(stmts)-1, 0)] {
+				if _, ok := stmt.(*ast.ReturnStatement); ok {
+					report(ast.StatementToken(stmts[ix+1]), "unreachable code after return")
+					break
+				}
+			}
+		}
+	},
+}
+
+var arityRule = &Rule{
+	Name:        "arity",
+	Description: "calls with the wrong number of arguments to builtins and functions defined in the program",
+	check: func(a *analysis, report reportFunc) {
+		for _, site := range a.calls {
+			got := len(site.call.Arguments)
+			if fn, ok := a.calledFunction(site); ok && len(fn.Parameters) != got {
+				report(site.call.Token, "wrong number of arguments to %s. got=%d, want=%d",
+					site.call.Function, got, len(fn.Parameters))
+				continue
+			}
+			spec, ok := a.calledBuiltin(site)
+			if !ok {
+				continue
+			}
+			switch lo, hi := spec.Arity(); {
+			case hi == -1 && got < lo:
+				report(site.call.Token, "wrong number of arguments to %s. got=%d, want=%d+", site.call.Function, got, lo)
+			case hi != -1 && (got < lo || got > hi) && lo == hi:
+				report(site.call.Token, "wrong number of arguments to %s. got=%d, want=%d", site.call.Function, got, lo)
+			case hi != -1 && (got < lo || got > hi):
+				report(site.call.Token, "wrong number of arguments to %s. got=%d, want=%d..%d", site.call.Function, got, lo, hi)
+			}
+		}
+	},
+}
+
+var constantConditionRule = &Rule{
+	Name:        "constant-condition",
+	Description: "if conditions that don't depend on any variable",
+	check: func(a *analysis, report reportFunc) {
+		for _, ie := range a.ifs {
+			if isConstant(ie.Condition) {
+				report(ie.Token, "constant if condition %s", ie.Condition)
+			}
+		}
+	},
+}
+
+// calledFunction returns the function literal called at site, if it can be known statically.
+func (a *analysis) calledFunction(site callSite) (*ast.FunctionLiteral, bool) {
+	if site.binding == nil {
+		fn, ok := site.call.Function.(*ast.FunctionLiteral)
+		return fn, ok
+	}
+	decl, ok := a.decls[*site.binding]
+	if !ok || decl.param || decl.lets != 1 || decl.fn == nil {
+		return nil, false
+	}
+	return decl.fn, true
+}
+
+// calledBuiltin returns the spec of the builtin called at site, if it's not shadowed by a binding.
+func (a *analysis) calledBuiltin(site callSite) (*object.BuiltinSpec, bool) {
+	if site.binding == nil || site.binding.Scope != nil {
+		return nil, false
+	}
+	if _, ok := a.decls[*site.binding]; ok {
+		return nil, false
+	}
+	return eval.LookupSpec(site.binding.Name)
+}
+
+func isConstant(expr ast.Expression) bool {
+	switch expr := expr.(type) {
+	case *ast.IntegerLiteral, *ast.StringLiteral, *ast.TemplateText, *ast.Boolean:
+		return true
+	case *ast.PrefixExpression:
+		return isConstant(expr.Right)
+	case *ast.InfixExpression:
+		return isConstant(expr.Left) && isConstant(expr.Right)
+	case *ast.InterpolatedString:
+		for _, part := range expr.Parts {
+			if !isConstant(part) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
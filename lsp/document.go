@@ -0,0 +1,175 @@
+package lsp
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"github.com/manuelpepe/interpreter/ast"
+	"github.com/manuelpepe/interpreter/lexer"
+	"github.com/manuelpepe/interpreter/parser"
+	"github.com/manuelpepe/interpreter/resolver"
+	"github.com/manuelpepe/interpreter/token"
+)
+
+type declaration struct {
+	ident *ast.Identifier
+	let   *ast.LetStatement    // nil for parameters
+	fn    *ast.FunctionLiteral // function declaring the parameter, nil for lets
+}
+
+// occurrence is an identifier declaring or referring to a binding.
+type occurrence struct {
+	ident   *ast.Identifier
+	binding resolver.Binding
+	decl    bool
+}
+
+type document struct {
+	uri   string
+	text  string
+	lines []string
+
+	errors []parser.Error
+
+	// index of the last version of the document without syntax errors
+	prog        *ast.Program
+	decls       map[resolver.Binding]*declaration
+	occurrences []occurrence
+}
+
+func newDocument(uri string, text string) *document {
+	d := &document{uri: uri}
+	d.update(text)
+	return d
+}
+
+// update parses the new text of the document. the index is only rebuilt if the text has no
+// syntax errors, so navigation keeps working while the document is being edited.
+func (d *document) update(text string) {
+	d.text = text
+	d.lines = strings.Split(text, "\n")
+
+	p := parser.New(lexer.NewLexer(text))
+	prog := p.ParseProgram()
+	d.errors = p.SyntaxErrors()
+	if len(d.errors) != 0 {
+		return
+	}
+
+	resolver.Resolve(prog)
+	d.prog = prog
+	d.decls = make(map[resolver.Binding]*declaration)
+	d.occurrences = nil
+	ix := &indexer{doc: d}
+	for _, stmt := range prog.Statements {
+		ix.walk(stmt)
+	}
+}
+
+type indexer struct {
+	doc    *document
+	scopes []*ast.FunctionLiteral // innermost last
+}
+
+func (ix *indexer) bindingOf(ident *ast.Identifier) resolver.Binding {
+	b, _ := resolver.BindingOf(ix.scopes, ident)
+	return b
+}
+
+func (ix *indexer) declare(ident *ast.Identifier, decl *declaration) {
+	b := ix.bindingOf(ident)
+	if _, ok := ix.doc.decls[b]; !ok {
+		ix.doc.decls[b] = decl
+	}
+	ix.doc.occurrences = append(ix.doc.occurrences, occurrence{ident: ident, binding: b, decl: true})
+}
+
+func (ix *indexer) walk(node ast.Node) {
+	switch node := node.(type) {
+	case nil:
+		return
+	case *ast.Identifier:
+		ix.doc.occurrences = append(ix.doc.occurrences, occurrence{ident: node, binding: ix.bindingOf(node)})
+		return
+	case *ast.LetStatement:
+		ix.walk(node.Value)
+		ix.declare(node.Name, &declaration{ident: node.Name, let: node})
+		return
+	case *ast.FunctionLiteral:
+		ix.scopes = append(ix.scopes, node)
+		for _, param := range node.Parameters {
+			ix.declare(param, &declaration{ident: param, fn: node})
+		}
+		ix.walk(node.Body)
+		ix.scopes = ix.scopes[:len(ix.scopes)-1]
+		return
+	}
+	for _, child := range node.ChildNodes() {
+		ix.walk(child)
+	}
+}
+
+// occurrenceAt returns the identifier under pos, which may also be right after its last character.
+func (d *document) occurrenceAt(pos Position) (occurrence, bool) {
+	for _, occ := range d.occurrences {
+		r := d.identRange(occ.ident)
+		if r.Start.Line == pos.Line && r.Start.Character <= pos.Character && pos.Character <= r.End.Character {
+			return occ, true
+		}
+	}
+	return occurrence{}, false
+}
+
+// position converts a token position (one based, in runes) into an LSP position.
+func (d *document) position(line int, column int) Position {
+	if line < 1 || line > len(d.lines) {
+		return Position{Line: max(line-1, 0)}
+	}
+	char := 0
+	for ix, r := range []rune(d.lines[line-1]) {
+		if ix >= column-1 {
+			break
+		}
+		char += utf16Len(r)
+	}
+	return Position{Line: line - 1, Character: char}
+}
+
+func (d *document) tokenRange(tok token.Token, text string) Range {
+	start := d.position(tok.Line, tok.Column)
+	end := start
+	for _, r := range text {
+		end.Character += utf16Len(r)
+	}
+	return Range{Start: start, End: end}
+}
+
+func (d *document) identRange(ident *ast.Identifier) Range {
+	return d.tokenRange(ident.Token, ident.Value)
+}
+
+// contains reports if pos is between the tokens from and to (inclusive).
+func (d *document) contains(from token.Token, to token.Token, pos Position) bool {
+	start := d.position(from.Line, from.Column)
+	end := d.position(to.Line, to.Column)
+	afterStart := pos.Line > start.Line || pos.Line == start.Line && pos.Character >= start.Character
+	beforeEnd := pos.Line < end.Line || pos.Line == end.Line && pos.Character <= end.Character
+	return afterStart && beforeEnd
+}
+
+// end returns the position after the last character of the document.
+func (d *document) end() Position {
+	last := d.lines[len(d.lines)-1]
+	char := 0
+	for _, r := range last {
+		char += utf16Len(r)
+	}
+	return Position{Line: len(d.lines) - 1, Character: char}
+}
+
+func utf16Len(r rune) int {
+	if r >= 0x10000 && r <= utf8.MaxRune {
+		return 2
+	}
+	return 1
+}
@@ -0,0 +1,248 @@
+package lsp
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/manuelpepe/interpreter/ast"
+	"github.com/manuelpepe/interpreter/eval"
+	"github.com/manuelpepe/interpreter/format"
+	"github.com/manuelpepe/interpreter/token"
+)
+
+// at decodes the document and position of a request, returning the occurrence under it if any.
+func (s *server) at(params json.RawMessage, p any) (*document, occurrence, bool, error) {
+	if err := json.Unmarshal(params, p); err != nil {
+		return nil, occurrence{}, false, err
+	}
+	var pos TextDocumentPositionParams
+	switch p := p.(type) {
+	case *TextDocumentPositionParams:
+		pos = *p
+	case *ReferenceParams:
+		pos = p.TextDocumentPositionParams
+	}
+	doc, err := s.document(pos.TextDocument.URI)
+	if err != nil {
+		return nil, occurrence{}, false, err
+	}
+	occ, ok := doc.occurrenceAt(pos.Position)
+	return doc, occ, ok, nil
+}
+
+func (s *server) definition(params json.RawMessage) (any, error) {
+	doc, occ, ok, err := s.at(params, &TextDocumentPositionParams{})
+	if err != nil || !ok {
+		return nil, err
+	}
+	decl, ok := doc.decls[occ.binding]
+	if !ok {
+		return nil, nil
+	}
+	return Location{URI: doc.uri, Range: doc.identRange(decl.ident)}, nil
+}
+
+func (s *server) references(params json.RawMessage) (any, error) {
+	var p ReferenceParams
+	doc, occ, ok, err := s.at(params, &p)
+	if err != nil || !ok {
+		return nil, err
+	}
+	locations := []Location{}
+	for _, other := range doc.occurrences {
+		if other.binding != occ.binding || other.decl && !p.Context.IncludeDeclaration {
+			continue
+		}
+		locations = append(locations, Location{URI: doc.uri, Range: doc.identRange(other.ident)})
+	}
+	sort.Slice(locations, func(i, j int) bool {
+		a, b := locations[i].Range.Start, locations[j].Range.Start
+		return a.Line < b.Line || a.Line == b.Line && a.Character < b.Character
+	})
+	return locations, nil
+}
+
+func (s *server) hover(params json.RawMessage) (any, error) {
+	doc, occ, ok, err := s.at(params, &TextDocumentPositionParams{})
+	if err != nil || !ok {
+		return nil, err
+	}
+
+	var text, docs string
+	decl, declared := doc.decls[occ.binding]
+	switch {
+	case declared && decl.fn != nil:
+		text = "(parameter) " + describe(decl.ident, nil)
+	case declared:
+		text = "let " + describe(decl.ident, decl.let.Value)
+	case occ.binding.Scope == nil && eval.IsBuiltin(occ.binding.Name):
+		help, _ := eval.BuiltinHelp(occ.binding.Name)
+		signature, doc, _ := strings.Cut(help, "\n")
+		text, docs = "builtin function "+signature, strings.TrimSpace(doc)
+	default:
+		return nil, nil
+	}
+	return Hover{
+		Contents: MarkupContent{Kind: "markdown", Value: strings.TrimSpace("```monkey\n" + text + "\n```\n\n" + docs)},
+		Range:    doc.identRange(occ.ident),
+	}, nil
+}
+
+// describe returns the name of a binding followed by its annotated type, or the kind of value
+// bound to it if it isn't annotated.
+func describe(ident *ast.Identifier, value ast.Expression) string {
+	if ident.Type != nil {
+		return ident.Value + ": " + ident.Type.String()
+	}
+	if kind := kindOf(value); kind != "" {
+		return ident.Value + ": " + kind
+	}
+	return ident.Value
+}
+
+func kindOf(value ast.Expression) string {
+	switch value := value.(type) {
+	case *ast.IntegerLiteral:
+		return "int"
+	case *ast.StringLiteral, *ast.InterpolatedString:
+		return "string"
+	case *ast.Boolean:
+		return "bool"
+	case *ast.ArrayLiteral:
+		return "array"
+	case *ast.HashLiteral:
+		return "hash"
+	case *ast.FunctionLiteral:
+		return signature(value)
+	}
+	return ""
+}
+
+func signature(fn *ast.FunctionLiteral) string {
+	params := make([]string, len(fn.Parameters))
+	for ix, param := range fn.Parameters {
+		params[ix] = describe(param, nil)
+	}
+	sig := "fn(" + strings.Join(params, ", ") + ")"
+	if fn.ReturnType != nil {
+		sig += ": " + fn.ReturnType.String()
+	}
+	return sig
+}
+
+func (s *server) completion(params json.RawMessage) (any, error) {
+	var p TextDocumentPositionParams
+	if err := json.Unmarshal(params, &p); err != nil {
+		return nil, err
+	}
+	doc, err := s.document(p.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool)
+	items := []CompletionItem{}
+	add := func(item CompletionItem) {
+		if !seen[item.Label] {
+			seen[item.Label] = true
+			items = append(items, item)
+		}
+	}
+
+	// bindings of the functions enclosing the position shadow outer ones, so they go first
+	var locals, globals []CompletionItem
+	for b, decl := range doc.decls {
+		item := CompletionItem{Label: b.Name, Kind: completionVariable}
+		if decl.let != nil {
+			if fn, ok := decl.let.Value.(*ast.FunctionLiteral); ok {
+				item.Kind = completionFunction
+				item.Detail = signature(fn)
+			}
+		}
+		if b.Scope == nil {
+			globals = append(globals, item)
+		} else if doc.contains(b.Scope.Token, b.Scope.Body.End, p.Position) {
+			locals = append(locals, item)
+		}
+	}
+	byLabel := func(items []CompletionItem) []CompletionItem {
+		sort.Slice(items, func(i, j int) bool { return items[i].Label < items[j].Label })
+		return items
+	}
+	for _, item := range byLabel(locals) {
+		add(item)
+	}
+	for _, item := range byLabel(globals) {
+		add(item)
+	}
+	for _, name := range eval.BuiltinNames() {
+		add(CompletionItem{Label: name, Kind: completionFunction, Detail: "builtin"})
+	}
+	for _, keyword := range token.Keywords() {
+		add(CompletionItem{Label: keyword, Kind: completionKeyword})
+	}
+	return items, nil
+}
+
+func (s *server) documentSymbol(params json.RawMessage) (any, error) {
+	var p DocumentParams
+	if err := json.Unmarshal(params, &p); err != nil {
+		return nil, err
+	}
+	doc, err := s.document(p.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+	if doc.prog == nil {
+		return []DocumentSymbol{}, nil
+	}
+	return doc.symbols(doc.prog.Statements), nil
+}
+
+// symbols returns a symbol for each let statement, with the lets of function bodies as children.
+func (d *document) symbols(stmts []ast.Statement) []DocumentSymbol {
+	symbols := []DocumentSymbol{}
+	for _, stmt := range stmts {
+		let, ok := stmt.(*ast.LetStatement)
+		if !ok {
+			continue
+		}
+		symbol := DocumentSymbol{
+			Name:           let.Name.Value,
+			Kind:           symbolVariable,
+			Range:          Range{Start: d.position(let.Token.Line, let.Token.Column), End: d.identRange(let.Name).End},
+			SelectionRange: d.identRange(let.Name),
+		}
+		if fn, ok := let.Value.(*ast.FunctionLiteral); ok {
+			symbol.Kind = symbolFunction
+			symbol.Detail = signature(fn)
+			symbol.Range.End = d.tokenRange(fn.Body.End, fn.Body.End.Literal).End
+			if children := d.symbols(fn.Body.Statements); len(children) != 0 {
+				symbol.Children = children
+			}
+		}
+		symbols = append(symbols, symbol)
+	}
+	return symbols
+}
+
+func (s *server) formatting(params json.RawMessage) (any, error) {
+	var p DocumentParams
+	if err := json.Unmarshal(params, &p); err != nil {
+		return nil, err
+	}
+	doc, err := s.document(p.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+	out, err := format.Source(doc.text)
+	if err != nil {
+		return nil, &responseError{Code: codeRequestFailed, Message: fmt.Sprintf("can't format document: %v", err)}
+	}
+	if out == doc.text {
+		return []TextEdit{}, nil
+	}
+	return []TextEdit{{Range: Range{End: doc.end()}, NewText: out}}, nil
+}
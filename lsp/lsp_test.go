@@ -0,0 +1,297 @@
+package lsp
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+const uri = "file:///test.monkey"
+
+const src = `let add = fn(a: int, b) {
+    let total = a + b;
+    total
+};
+let x = add(1, 2);
+inspect(x);
+`
+
+// session runs the server over the given requests, returning the messages it wrote.
+func session(t *testing.T, requests ...map[string]any) []map[string]any {
+	t.Helper()
+	msgs, err := serve(t, requests...)
+	if err != nil {
+		t.Fatalf("Serve failed: %v", err)
+	}
+	return msgs
+}
+
+// serve is like session, also returning the error returned by Serve.
+func serve(t *testing.T, requests ...map[string]any) ([]map[string]any, error) {
+	t.Helper()
+	var in bytes.Buffer
+	for _, req := range requests {
+		body, err := json.Marshal(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fmt.Fprintf(&in, "Content-Length: %d\r\n\r\n%s", len(body), body)
+	}
+
+	var out bytes.Buffer
+	serveErr := Serve(&in, &out)
+
+	var msgs []map[string]any
+	r := bufio.NewReader(&out)
+	for {
+		msg, err := readMessage(r)
+		if err != nil {
+			break
+		}
+		raw, _ := json.Marshal(msg)
+		var decoded map[string]any
+		json.Unmarshal(raw, &decoded)
+		msgs = append(msgs, decoded)
+	}
+	return msgs, serveErr
+}
+
+func open(text string) map[string]any {
+	return map[string]any{
+		"jsonrpc": "2.0",
+		"method":  "textDocument/didOpen",
+		"params":  map[string]any{"textDocument": map[string]any{"uri": uri, "version": 1, "text": text}},
+	}
+}
+
+func request(id int, method string, params map[string]any) map[string]any {
+	params["textDocument"] = map[string]any{"uri": uri}
+	return map[string]any{"jsonrpc": "2.0", "id": id, "method": method, "params": params}
+}
+
+func at(line, char int) map[string]any {
+	return map[string]any{"position": map[string]any{"line": line, "character": char}}
+}
+
+// response returns the result of the response with the given id, re-encoded as JSON.
+func response(t *testing.T, msgs []map[string]any, id int) string {
+	t.Helper()
+	for _, msg := range msgs {
+		if msg["id"] == float64(id) {
+			if msg["error"] != nil {
+				t.Fatalf("request %d failed: %v", id, msg["error"])
+			}
+			raw, _ := json.Marshal(msg["result"])
+			return string(raw)
+		}
+	}
+	t.Fatalf("no response for request %d", id)
+	return ""
+}
+
+func TestInitialize(t *testing.T) {
+	msgs := session(t, map[string]any{"jsonrpc": "2.0", "id": 1, "method": "initialize", "params": map[string]any{}})
+	result := response(t, msgs, 1)
+	for _, capability := range []string{"definitionProvider", "referencesProvider", "hoverProvider", "completionProvider", "documentSymbolProvider", "documentFormattingProvider"} {
+		if !strings.Contains(result, capability) {
+			t.Errorf("missing capability %s in %s", capability, result)
+		}
+	}
+}
+
+func TestDiagnostics(t *testing.T) {
+	msgs := session(t, open("let x = 5;\nlet = 3;"))
+	if len(msgs) != 1 || msgs[0]["method"] != "textDocument/publishDiagnostics" {
+		t.Fatalf("expected a diagnostics notification, got %v", msgs)
+	}
+	raw, _ := json.Marshal(msgs[0]["params"])
+	var params PublishDiagnosticsParams
+	json.Unmarshal(raw, &params)
+	if len(params.Diagnostics) == 0 {
+		t.Fatalf("expected diagnostics, got none")
+	}
+	diag := params.Diagnostics[0]
+	if diag.Range.Start != (Position{Line: 1, Character: 4}) {
+		t.Errorf("wrong diagnostic position: %+v", diag.Range.Start)
+	}
+	if !strings.Contains(diag.Message, "expected next token to be IDENT") {
+		t.Errorf("wrong diagnostic message: %q", diag.Message)
+	}
+}
+
+func TestNavigation(t *testing.T) {
+	msgs := session(t,
+		open(src),
+		request(1, "textDocument/definition", at(2, 4)),  // total
+		request(2, "textDocument/definition", at(1, 16)), // a
+		request(3, "textDocument/references", map[string]any{"position": map[string]any{"line": 0, "character": 5}, "context": map[string]any{"includeDeclaration": true}}),
+		request(4, "textDocument/references", map[string]any{"position": map[string]any{"line": 4, "character": 4}, "context": map[string]any{"includeDeclaration": false}}),
+		request(5, "textDocument/definition", at(5, 1)), // builtin
+	)
+
+	tests := []struct {
+		id       int
+		expected string
+	}{
+		{1, `{"range":{"end":{"character":13,"line":1},"start":{"character":8,"line":1}},"uri":"file:///test.monkey"}`},
+		{2, `{"range":{"end":{"character":14,"line":0},"start":{"character":13,"line":0}},"uri":"file:///test.monkey"}`},
+		{3, `[{"range":{"end":{"character":7,"line":0},"start":{"character":4,"line":0}},"uri":"file:///test.monkey"},{"range":{"end":{"character":11,"line":4},"start":{"character":8,"line":4}},"uri":"file:///test.monkey"}]`},
+		{4, `[{"range":{"end":{"character":9,"line":5},"start":{"character":8,"line":5}},"uri":"file:///test.monkey"}]`},
+		{5, `null`},
+	}
+	for _, tt := range tests {
+		if got := response(t, msgs, tt.id); got != tt.expected {
+			t.Errorf("request %d: expected %s, got %s", tt.id, tt.expected, got)
+		}
+	}
+}
+
+func TestHover(t *testing.T) {
+	msgs := session(t,
+		open(src),
+		request(1, "textDocument/hover", at(4, 9)),  // add
+		request(2, "textDocument/hover", at(1, 16)), // a
+		request(3, "textDocument/hover", at(5, 2)),  // inspect
+		request(4, "textDocument/hover", at(4, 5)),  // x
+		request(5, "textDocument/hover", at(4, 12)), // not an identifier
+	)
+
+	tests := []struct {
+		id       int
+		expected string
+	}{
+		{1, "let add: fn(a: int, b)"},
+		{2, "(parameter) a: int"},
+		{3, "builtin function inspect(...values)\\n```\\n\\nprints each value in a line"},
+		{4, "let x"},
+		{5, ""},
+	}
+	for _, tt := range tests {
+		got := response(t, msgs, tt.id)
+		if tt.expected == "" {
+			if got != "null" {
+				t.Errorf("request %d: expected no hover, got %s", tt.id, got)
+			}
+			continue
+		}
+		if !strings.Contains(got, tt.expected) {
+			t.Errorf("request %d: expected hover with %q, got %s", tt.id, tt.expected, got)
+		}
+	}
+}
+
+func TestCompletion(t *testing.T) {
+	msgs := session(t,
+		open(src),
+		request(1, "textDocument/completion", at(2, 4)), // inside add
+		request(2, "textDocument/completion", at(5, 0)), // global scope
+	)
+
+	labels := func(id int) []string {
+		var items []CompletionItem
+		json.Unmarshal([]byte(response(t, msgs, id)), &items)
+		out := make([]string, len(items))
+		for ix, item := range items {
+			out[ix] = item.Label
+		}
+		return out
+	}
+
+	inside := labels(1)
+	if strings.Join(inside[:5], " ") != "a b total add x" {
+		t.Errorf("expected locals and then globals first, got %v", inside)
+	}
+	global := strings.Join(labels(2), " ")
+	if strings.Contains(global, "total") {
+		t.Errorf("expected locals of add not to be completed outside of it, got %s", global)
+	}
+	for _, name := range []string{"add", "x", "len", "inspect", "let"} {
+		if !strings.Contains(" "+global+" ", " "+name+" ") {
+			t.Errorf("expected %s to be completed, got %s", name, global)
+		}
+	}
+}
+
+func TestDocumentSymbols(t *testing.T) {
+	msgs := session(t, open(src), request(1, "textDocument/documentSymbol", map[string]any{}))
+
+	var symbols []DocumentSymbol
+	json.Unmarshal([]byte(response(t, msgs, 1)), &symbols)
+	if len(symbols) != 2 {
+		t.Fatalf("expected 2 symbols, got %+v", symbols)
+	}
+	add := symbols[0]
+	if add.Name != "add" || add.Kind != symbolFunction || add.Range.End != (Position{Line: 3, Character: 1}) {
+		t.Errorf("wrong symbol for add: %+v", add)
+	}
+	if len(add.Children) != 1 || add.Children[0].Name != "total" {
+		t.Errorf("expected total as child of add, got %+v", add.Children)
+	}
+	if symbols[1].Name != "x" || symbols[1].Kind != symbolVariable {
+		t.Errorf("wrong symbol for x: %+v", symbols[1])
+	}
+}
+
+func TestFormatting(t *testing.T) {
+	msgs := session(t, open("let x=1;\nlet y  =  x+1;"), request(1, "textDocument/formatting", map[string]any{}))
+
+	var edits []TextEdit
+	json.Unmarshal([]byte(response(t, msgs, 1)), &edits)
+	if len(edits) != 1 {
+		t.Fatalf("expected a single edit, got %+v", edits)
+	}
+	if edits[0].NewText != "let x = 1;\nlet y = x + 1;\n" {
+		t.Errorf("wrong formatted text: %q", edits[0].NewText)
+	}
+	if edits[0].Range.End != (Position{Line: 1, Character: 14}) {
+		t.Errorf("edit doesn't cover the document: %+v", edits[0].Range)
+	}
+}
+
+func TestUnknownMethod(t *testing.T) {
+	msgs := session(t, map[string]any{"jsonrpc": "2.0", "id": 1, "method": "workspace/symbol", "params": map[string]any{}})
+	if len(msgs) != 1 {
+		t.Fatalf("expected a single response, got %v", msgs)
+	}
+	err, ok := msgs[0]["error"].(map[string]any)
+	if !ok || err["code"] != float64(codeMethodNotFound) {
+		t.Errorf("expected method not found error, got %v", msgs[0])
+	}
+}
+
+func TestShutdown(t *testing.T) {
+	msgs, err := serve(t,
+		map[string]any{"jsonrpc": "2.0", "id": 1, "method": "shutdown"},
+		open(src),
+		request(2, "textDocument/hover", at(0, 5)),
+		map[string]any{"jsonrpc": "2.0", "method": "exit"},
+		request(3, "textDocument/hover", at(0, 5)),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error exiting after shutdown: %v", err)
+	}
+	if len(msgs) != 2 {
+		t.Fatalf("expected two responses, got %v", msgs)
+	}
+	response(t, msgs, 1)
+	if err, ok := msgs[1]["error"].(map[string]any); !ok || msgs[1]["id"] != float64(2) || err["code"] != float64(codeInvalidRequest) {
+		t.Errorf("expected invalid request error after shutdown, got %v", msgs[1])
+	}
+
+	if _, err := serve(t, map[string]any{"jsonrpc": "2.0", "method": "exit"}); err != ErrNoShutdown {
+		t.Errorf("expected ErrNoShutdown exiting without shutdown, got %v", err)
+	}
+}
+
+func TestInvalidContentLength(t *testing.T) {
+	for _, length := range []string{"-1", "1099511627776", "x"} {
+		in := strings.NewReader("Content-Length: " + length + "\r\n\r\n{}")
+		err := Serve(in, &bytes.Buffer{})
+		if err == nil || !strings.Contains(err.Error(), "invalid Content-Length") {
+			t.Errorf("Content-Length %s: expected framing error, got %v", length, err)
+		}
+	}
+}
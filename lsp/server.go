@@ -0,0 +1,207 @@
+// Package lsp implements a Language Server Protocol server for Monkey over stdio.
+//
+// Documents are synchronized in full on every change. The server publishes syntax errors as
+// diagnostics and provides go-to-definition, references, hover, completion, document symbols and
+// formatting. Navigation uses the last version of each document that parsed without errors.
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io"
+)
+
+type server struct {
+	in  *bufio.Reader
+	out io.Writer
+
+	docs     map[string]*document
+	shutdown bool
+}
+
+// ErrNoShutdown is returned by Serve when the client sends `exit` without a `shutdown` request
+// before it, in which case the server should exit with status 1.
+var ErrNoShutdown = errors.New("exit without shutdown")
+
+// Serve handles messages read from r, writing responses to w, until the client sends `exit`
+// or r is closed.
+func Serve(r io.Reader, w io.Writer) error {
+	s := &server{in: bufio.NewReader(r), out: w, docs: make(map[string]*document)}
+	return s.run()
+}
+
+func (s *server) run() error {
+	for {
+		msg, err := readMessage(s.in)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		var rpcErr *responseError
+		if errors.As(err, &rpcErr) {
+			if err := writeMessage(s.out, &message{ID: rawNull(), Error: rpcErr}); err != nil {
+				return err
+			}
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		if msg.Method == "exit" {
+			if !s.shutdown {
+				return ErrNoShutdown
+			}
+			return nil
+		}
+		if s.shutdown {
+			// only exit is expected after shutdown, requests fail and notifications are dropped
+			if msg.ID != nil {
+				rpcErr := &responseError{Code: codeInvalidRequest, Message: "server is shutting down"}
+				if err := writeMessage(s.out, &message{ID: msg.ID, Error: rpcErr}); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		result, rpcErr := s.handle(msg)
+		if msg.ID == nil {
+			continue // notifications have no response
+		}
+		resp := &message{ID: msg.ID, Result: result, Error: rpcErr}
+		if rpcErr == nil && result == nil {
+			resp.Result = json.RawMessage("null")
+		}
+		if err := writeMessage(s.out, resp); err != nil {
+			return err
+		}
+	}
+}
+
+type handler func(s *server, params json.RawMessage) (any, error)
+
+var handlers = map[string]handler{
+	"initialize":                  (*server).initialize,
+	"initialized":                 nothing,
+	"shutdown":                    (*server).shutdownRequest,
+	"textDocument/didOpen":        (*server).didOpen,
+	"textDocument/didChange":      (*server).didChange,
+	"textDocument/didClose":       (*server).didClose,
+	"textDocument/didSave":        nothing,
+	"textDocument/definition":     (*server).definition,
+	"textDocument/references":     (*server).references,
+	"textDocument/hover":          (*server).hover,
+	"textDocument/completion":     (*server).completion,
+	"textDocument/documentSymbol": (*server).documentSymbol,
+	"textDocument/formatting":     (*server).formatting,
+}
+
+func (s *server) handle(msg *message) (any, *responseError) {
+	h, ok := handlers[msg.Method]
+	if !ok {
+		return nil, &responseError{Code: codeMethodNotFound, Message: "method not supported: " + msg.Method}
+	}
+	result, err := h(s, msg.Params)
+	if err != nil {
+		var rpcErr *responseError
+		if errors.As(err, &rpcErr) {
+			return nil, rpcErr
+		}
+		return nil, &responseError{Code: codeInvalidParams, Message: err.Error()}
+	}
+	return result, nil
+}
+
+func nothing(s *server, params json.RawMessage) (any, error) {
+	return nil, nil
+}
+
+func (s *server) initialize(params json.RawMessage) (any, error) {
+	return map[string]any{
+		"capabilities": map[string]any{
+			"textDocumentSync":           1, // full
+			"definitionProvider":         true,
+			"referencesProvider":         true,
+			"hoverProvider":              true,
+			"completionProvider":         map[string]any{},
+			"documentSymbolProvider":     true,
+			"documentFormattingProvider": true,
+		},
+		"serverInfo": map[string]any{"name": "monkey"},
+	}, nil
+}
+
+func (s *server) shutdownRequest(params json.RawMessage) (any, error) {
+	s.shutdown = true
+	return nil, nil
+}
+
+func (s *server) didOpen(params json.RawMessage) (any, error) {
+	var p DidOpenTextDocumentParams
+	if err := json.Unmarshal(params, &p); err != nil {
+		return nil, err
+	}
+	doc := newDocument(p.TextDocument.URI, p.TextDocument.Text)
+	s.docs[doc.uri] = doc
+	return nil, s.publishDiagnostics(doc)
+}
+
+func (s *server) didChange(params json.RawMessage) (any, error) {
+	var p DidChangeTextDocumentParams
+	if err := json.Unmarshal(params, &p); err != nil {
+		return nil, err
+	}
+	doc, ok := s.docs[p.TextDocument.URI]
+	if !ok || len(p.ContentChanges) == 0 {
+		return nil, nil
+	}
+	doc.update(p.ContentChanges[len(p.ContentChanges)-1].Text)
+	return nil, s.publishDiagnostics(doc)
+}
+
+func (s *server) didClose(params json.RawMessage) (any, error) {
+	var p DidCloseTextDocumentParams
+	if err := json.Unmarshal(params, &p); err != nil {
+		return nil, err
+	}
+	delete(s.docs, p.TextDocument.URI)
+	return nil, s.notify("textDocument/publishDiagnostics", PublishDiagnosticsParams{
+		URI:         p.TextDocument.URI,
+		Diagnostics: []Diagnostic{},
+	})
+}
+
+func (s *server) publishDiagnostics(doc *document) error {
+	diags := make([]Diagnostic, len(doc.errors))
+	for ix, err := range doc.errors {
+		diags[ix] = Diagnostic{
+			Range:    doc.tokenRange(err.Token, err.Token.Literal),
+			Severity: severityError,
+			Source:   "monkey",
+			Message:  err.Message,
+		}
+	}
+	return s.notify("textDocument/publishDiagnostics", PublishDiagnosticsParams{URI: doc.uri, Diagnostics: diags})
+}
+
+func (s *server) notify(method string, params any) error {
+	raw, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+	return writeMessage(s.out, &message{Method: method, Params: raw})
+}
+
+// document returns the open document referred by params.
+func (s *server) document(uri string) (*document, error) {
+	doc, ok := s.docs[uri]
+	if !ok {
+		return nil, &responseError{Code: codeInvalidParams, Message: "document not open: " + uri}
+	}
+	return doc, nil
+}
+
+func rawNull() *json.RawMessage {
+	raw := json.RawMessage("null")
+	return &raw
+}
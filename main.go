@@ -1,41 +1,71 @@
 package main
 
 import (
+	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
 	"os"
 	"os/user"
+	"strings"
 
+	"github.com/manuelpepe/interpreter/dap"
+	"github.com/manuelpepe/interpreter/debug"
 	"github.com/manuelpepe/interpreter/eval"
+	"github.com/manuelpepe/interpreter/format"
 	"github.com/manuelpepe/interpreter/graph"
 	"github.com/manuelpepe/interpreter/lexer"
+	"github.com/manuelpepe/interpreter/lint"
+	"github.com/manuelpepe/interpreter/lsp"
 	"github.com/manuelpepe/interpreter/object"
+	"github.com/manuelpepe/interpreter/optimize"
 	"github.com/manuelpepe/interpreter/parser"
 	"github.com/manuelpepe/interpreter/repl"
+	"github.com/manuelpepe/interpreter/typecheck"
 )
 
 const option = 3
 
+// commands are run as `monkey <command> [flags] [args]`, anything else is handled by the flags in main.
+var commands = map[string]func(args []string) int{
+	"check": doCheck,
+	"lint":  doLint,
+	"fmt":   doFmt,
+	"lsp":   doLSP,
+	"debug": doDebug,
+	"dap":   doDAP,
+}
+
 func main() {
+	if len(os.Args) > 1 {
+		if cmd, ok := commands[os.Args[1]]; ok {
+			os.Exit(cmd(os.Args[2:]))
+		}
+	}
+
 	flags := struct {
 		graph *string
 		out   *string
 
 		run *string
+
+		optimize *bool
 	}{
 		graph: flag.String("graph", "", "produce graph"),
 		out:   flag.String("out", "./ast.gv", "output file for graph"),
 
 		run: flag.String("run", "", "execute file"),
+
+		optimize: flag.Bool("optimize", false, "optimize the program before graphing or executing it"),
 	}
 
 	flag.Parse()
 
 	if flags.graph != nil && *flags.graph != "" {
-		doGraph(*flags.graph, *flags.out)
+		doGraph(*flags.graph, *flags.out, *flags.optimize)
 	} else if flags.run != nil && *flags.run != "" {
-		doRunFile(*flags.run)
+		doRunFile(*flags.run, *flags.optimize)
 	} else {
 		doREPL()
 	}
@@ -50,7 +80,7 @@ func doREPL() {
 	repl.Start(os.Stdin, os.Stdout)
 }
 
-func doRunFile(srcFile string) {
+func doRunFile(srcFile string, optimized bool) {
 	data, err := os.ReadFile(srcFile)
 	if err != nil {
 		fmt.Fprintf(os.Stdout, "Error opening file: %v\n", err)
@@ -64,6 +94,9 @@ func doRunFile(srcFile string) {
 		repl.PrintParserErrors(os.Stdout, p.Errors())
 		return
 	}
+	if optimized {
+		prog = optimize.Optimize(prog)
+	}
 
 	env := object.NewEnvironment()
 	res := eval.Eval(prog, env)
@@ -73,15 +106,312 @@ func doRunFile(srcFile string) {
 	}
 }
 
-func doGraph(src string, dst string) {
+func doGraph(src string, dst string, optimized bool) {
 	data, err := os.ReadFile(src)
 	if err != nil {
 		fmt.Fprintf(os.Stdout, "Error opening file: %v\n", err)
 		return
 	}
 
-	graph.Graph(
-		string(data),
-		dst,
-	)
+	if !optimized {
+		graph.Graph(
+			string(data),
+			dst,
+		)
+		return
+	}
+
+	l := lexer.NewLexer(string(data))
+	p := parser.New(l)
+	prog := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		repl.PrintParserErrors(os.Stdout, p.Errors())
+		return
+	}
+	graph.GraphProgram(optimize.Optimize(prog), dst)
+}
+
+// doCheck reports the type errors of the given files without executing them,
+// unless -run is given and no errors are found.
+func doCheck(args []string) int {
+	fs := flag.NewFlagSet("check", flag.ExitOnError)
+	run := fs.Bool("run", false, "execute the file if it has no type errors")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: %s check [-run] file...\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return 2
+	}
+
+	status := 0
+	for _, src := range fs.Args() {
+		data, err := os.ReadFile(src)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error opening file: %v\n", err)
+			status = 1
+			continue
+		}
+
+		l := lexer.NewLexer(string(data))
+		p := parser.New(l)
+		prog := p.ParseProgram()
+		if len(p.Errors()) != 0 {
+			for _, err := range p.SyntaxErrors() {
+				fmt.Fprintf(os.Stderr, "%s:%s\n", src, err)
+			}
+			status = 1
+			continue
+		}
+
+		errs := typecheck.Check(prog)
+		for _, err := range errs {
+			fmt.Fprintf(os.Stderr, "%s:%s\n", src, err)
+		}
+		if len(errs) != 0 {
+			status = 1
+			continue
+		}
+
+		if *run {
+			res := eval.Eval(prog, object.NewEnvironment())
+			if res != nil {
+				io.WriteString(os.Stdout, res.Inspect())
+				io.WriteString(os.Stdout, "\n")
+			}
+		}
+	}
+	return status
+}
+
+// doLint reports the diagnostics of the enabled lint rules for the given files,
+// either as text or as one JSON object per line.
+func doLint(args []string) int {
+	fs := flag.NewFlagSet("lint", flag.ExitOnError)
+	asJSON := fs.Bool("json", false, "print diagnostics as JSON objects, one per line")
+	enable := fs.String("enable", "", "comma separated list of the only rules to run")
+	disable := fs.String("disable", "", "comma separated list of rules to skip")
+	list := fs.Bool("rules", false, "list the available rules")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: %s lint [-json] [-enable rules] [-disable rules] file...\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if *list {
+		for _, rule := range lint.Rules {
+			fmt.Printf("%-20s %s\n", rule.Name, rule.Description)
+		}
+		return 0
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return 2
+	}
+
+	rules := lint.Rules
+	if *enable != "" {
+		rules = nil
+		for _, name := range strings.Split(*enable, ",") {
+			rule, ok := lint.LookupRule(strings.TrimSpace(name))
+			if !ok {
+				fmt.Fprintf(os.Stderr, "unknown rule: %s\n", name)
+				return 2
+			}
+			rules = append(rules, rule)
+		}
+	}
+	if *disable != "" {
+		skip := make(map[string]bool)
+		for _, name := range strings.Split(*disable, ",") {
+			name = strings.TrimSpace(name)
+			if _, ok := lint.LookupRule(name); !ok {
+				fmt.Fprintf(os.Stderr, "unknown rule: %s\n", name)
+				return 2
+			}
+			skip[name] = true
+		}
+		enabled := make([]*lint.Rule, 0, len(rules))
+		for _, rule := range rules {
+			if !skip[rule.Name] {
+				enabled = append(enabled, rule)
+			}
+		}
+		rules = enabled
+	}
+
+	status := 0
+	enc := json.NewEncoder(os.Stdout)
+	for _, src := range fs.Args() {
+		data, err := os.ReadFile(src)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error opening file: %v\n", err)
+			status = 1
+			continue
+		}
+
+		l := lexer.NewLexer(string(data))
+		p := parser.New(l)
+		prog := p.ParseProgram()
+		if len(p.Errors()) != 0 {
+			for _, err := range p.SyntaxErrors() {
+				fmt.Fprintf(os.Stderr, "%s:%s\n", src, err)
+			}
+			status = 1
+			continue
+		}
+
+		for _, diag := range lint.Lint(prog, rules) {
+			status = 1
+			if *asJSON {
+				enc.Encode(struct {
+					File string `json:"file"`
+					lint.Diagnostic
+				}{src, diag})
+			} else {
+				fmt.Printf("%s:%s\n", src, diag)
+			}
+		}
+	}
+	return status
+}
+
+// doFmt formats the given files, printing the result unless -w or -check are given.
+// without files it formats the standard input.
+func doFmt(args []string) int {
+	fs := flag.NewFlagSet("fmt", flag.ExitOnError)
+	write := fs.Bool("w", false, "write the result to the source files instead of printing it")
+	check := fs.Bool("check", false, "list files that are not formatted and fail if there are any")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: %s fmt [-w | -check] [file...]\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if fs.NArg() == 0 {
+		data, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading input: %v\n", err)
+			return 1
+		}
+		out, err := format.Source(string(data))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "<stdin>: %v\n", err)
+			return 1
+		}
+		if *check {
+			if out != string(data) {
+				fmt.Println("<stdin>")
+				return 1
+			}
+			return 0
+		}
+		io.WriteString(os.Stdout, out)
+		return 0
+	}
+
+	status := 0
+	for _, src := range fs.Args() {
+		data, err := os.ReadFile(src)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error opening file: %v\n", err)
+			status = 1
+			continue
+		}
+		out, err := format.Source(string(data))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", src, err)
+			status = 1
+			continue
+		}
+
+		switch {
+		case *check:
+			if out != string(data) {
+				fmt.Println(src)
+				status = 1
+			}
+		case *write:
+			if out != string(data) {
+				if err := os.WriteFile(src, []byte(out), 0o644); err != nil {
+					fmt.Fprintf(os.Stderr, "Error writing file: %v\n", err)
+					status = 1
+				}
+			}
+		default:
+			io.WriteString(os.Stdout, out)
+		}
+	}
+	return status
+}
+
+// doLSP runs a language server over the standard input and output.
+func doLSP(args []string) int {
+	fs := flag.NewFlagSet("lsp", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: %s lsp\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	err := lsp.Serve(os.Stdin, os.Stdout)
+	if errors.Is(err, lsp.ErrNoShutdown) {
+		return 1
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "lsp: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// doDebug runs a file under the interactive debugger.
+func doDebug(args []string) int {
+	fs := flag.NewFlagSet("debug", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: %s debug file\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return 2
+	}
+
+	src := fs.Arg(0)
+	data, err := os.ReadFile(src)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error opening file: %v\n", err)
+		return 1
+	}
+	l := lexer.NewLexer(string(data))
+	p := parser.New(l)
+	prog := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		for _, err := range p.SyntaxErrors() {
+			fmt.Fprintf(os.Stderr, "%s:%s\n", src, err)
+		}
+		return 1
+	}
+
+	debug.Start(src, string(data), prog, os.Stdin, os.Stdout)
+	return 0
+}
+
+// doDAP runs a debug adapter over the standard input and output.
+func doDAP(args []string) int {
+	fs := flag.NewFlagSet("dap", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: %s dap\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if err := dap.Serve(os.Stdin, os.Stdout); err != nil {
+		fmt.Fprintf(os.Stderr, "dap: %v\n", err)
+		return 1
+	}
+	return 0
 }
@@ -1,16 +1,74 @@
 package object
 
+import (
+	"context"
+	"sort"
+
+	"github.com/manuelpepe/interpreter/ast"
+)
+
+// NewEnvironment creates a global environment with a new Runtime.
 func NewEnvironment() *Environment {
+	return NewEnvironmentWithRuntime(&Runtime{})
+}
+
+// NewEnvironmentWithRuntime creates a global environment sharing an existing Runtime.
+func NewEnvironmentWithRuntime(rt *Runtime) *Environment {
 	s := make(map[string]Object)
-	return &Environment{store: s}
+	return &Environment{store: s, runtime: rt}
 }
 
+// Environment holds the bindings of a scope. The global environment stores bindings by name, while
+// function scopes store them in slots whose positions are computed by the resolver.
 type Environment struct {
 	store map[string]Object
+	names []string // name of each slot
+	slots []Object
 	outer *Environment
+
+	runtime *Runtime // shared with enclosed scopes
+}
+
+// Runtime is the state shared by a global environment and all the scopes enclosed by it, which
+// is set up by the host running the code. The zero value uses the default builtins and has no
+// limits.
+type Runtime struct {
+	Builtins *Registry // builtin functions available, the evaluator's defaults if nil
+	Debugger Debugger  // notified while code runs, if set
+
+	MaxDepth int             // maximum depth of nested function calls, 0 for no limit
+	MaxSteps int             // maximum number of statements evaluated, 0 for no limit
+	Context  context.Context // stops the evaluation when done, if set
+
+	// updated by the evaluator
+	Depth int
+	Steps int
+}
+
+// Debugger is notified by the evaluator while it runs code in an environment with a debugger set,
+// see Environment.SetDebugger.
+type Debugger interface {
+	// Statement is called before each statement is evaluated.
+	Statement(stmt ast.Statement, env *Environment)
+	// Enter is called when a function is called, with the environment of the new call.
+	Enter(fn *Function, env *Environment)
+	// Leave is called when a function returns.
+	Leave(fn *Function, result Object)
+}
+
+// Binding is a name bound in a scope, see Environment.Bindings.
+type Binding struct {
+	Name  string
+	Value Object
 }
 
+// Get looks up a binding by name through all enclosing scopes.
 func (e *Environment) Get(name string) (Object, bool) {
+	for ix := len(e.names) - 1; ix >= 0; ix-- {
+		if e.names[ix] == name && e.slots[ix] != nil {
+			return e.slots[ix], true
+		}
+	}
 	obj, ok := e.store[name]
 	if !ok && e.outer != nil {
 		obj, ok = e.outer.Get(name)
@@ -18,13 +76,95 @@ func (e *Environment) Get(name string) (Object, bool) {
 	return obj, ok
 }
 
+// GetSlot looks up a binding by its lexical address. unset slots are reported as missing.
+func (e *Environment) GetSlot(depth int, slot int) (Object, bool) {
+	env := e
+	for ; depth > 0; depth-- {
+		env = env.outer
+	}
+	obj := env.slots[slot]
+	return obj, obj != nil
+}
+
+// GetGlobal looks up a binding by name only in the outermost environment.
+func (e *Environment) GetGlobal(name string) (Object, bool) {
+	env := e
+	for env.outer != nil {
+		env = env.outer
+	}
+	obj, ok := env.store[name]
+	return obj, ok
+}
+
 func (e *Environment) Set(name string, val Object) Object {
+	if e.store == nil {
+		// function scopes only have slots, bindings from code the resolver didn't see are added
+		// as new slots. names is shared with the function literal so it must be copied.
+		for ix := range e.names {
+			if e.names[ix] == name {
+				e.slots[ix] = val
+				return val
+			}
+		}
+		e.names = append(e.names[:len(e.names):len(e.names)], name)
+		e.slots = append(e.slots, val)
+		return val
+	}
 	e.store[name] = val
 	return val
 }
 
-func (e *Environment) Enclose() *Environment {
-	env := NewEnvironment()
-	env.outer = e
-	return env
+func (e *Environment) SetSlot(slot int, val Object) Object {
+	e.slots[slot] = val
+	return val
+}
+
+// Enclose creates a function scope with a slot for each of the given names.
+func (e *Environment) Enclose(names []string) *Environment {
+	return &Environment{
+		names: names,
+		slots: make([]Object, len(names)),
+		outer: e,
+
+		runtime: e.runtime,
+	}
+}
+
+// Outer returns the enclosing scope, or nil for the global environment.
+func (e *Environment) Outer() *Environment {
+	return e.outer
+}
+
+// Bindings returns the bindings of this scope without the enclosing ones: the set slots of a
+// function scope in order, or the globals sorted by name.
+func (e *Environment) Bindings() []Binding {
+	var out []Binding
+	for ix, name := range e.names {
+		if e.slots[ix] != nil {
+			out = append(out, Binding{Name: name, Value: e.slots[ix]})
+		}
+	}
+	names := make([]string, 0, len(e.store))
+	for name := range e.store {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		out = append(out, Binding{Name: name, Value: e.store[name]})
+	}
+	return out
+}
+
+// Runtime returns the state shared with the global environment.
+func (e *Environment) Runtime() *Runtime {
+	return e.runtime
+}
+
+// SetDebugger attaches a debugger to the runtime of the environment. A nil debugger detaches it.
+func (e *Environment) SetDebugger(d Debugger) {
+	e.runtime.Debugger = d
+}
+
+func (e *Environment) Debugger() Debugger {
+	return e.runtime.Debugger
 }
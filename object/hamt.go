@@ -0,0 +1,194 @@
+package object
+
+import "math/bits"
+
+const (
+	hamtBits = 5
+	hamtMask = 1<<hamtBits - 1
+)
+
+// hamt is a persistent hash array mapped trie from hashable objects to values of type T.
+// each level of the trie consumes 5 bits of the key's HashKey, and keys whose HashKey collide
+// completely are kept together in a leaf and told apart with Equal.
+// Lookups and updates are O(log32 n) and updates return a new trie sharing structure with the original.
+type hamt[T any] struct {
+	root *hamtNode[T]
+	size int
+}
+
+// hamtNode is an internal node, only the children for the set bits of bitmap are stored.
+type hamtNode[T any] struct {
+	bitmap   uint32
+	children []hamtChild[T]
+}
+
+// hamtChild holds either a nested node or a leaf.
+type hamtChild[T any] struct {
+	node *hamtNode[T]
+	leaf *hamtLeaf[T]
+}
+
+// hamtLeaf holds all entries whose keys share the same hash.
+type hamtLeaf[T any] struct {
+	hash    uint64
+	entries []hamtEntry[T]
+}
+
+type hamtEntry[T any] struct {
+	key   Object
+	value T
+}
+
+func (h hamt[T]) Len() int {
+	return h.size
+}
+
+func (h hamt[T]) Get(key Object, hash uint64) (T, bool) {
+	node := h.root
+	for shift := uint(0); node != nil; shift += hamtBits {
+		bit := uint32(1) << ((hash >> shift) & hamtMask)
+		if node.bitmap&bit == 0 {
+			break
+		}
+		child := node.children[node.index(bit)]
+		if child.leaf != nil {
+			if child.leaf.hash == hash {
+				for _, entry := range child.leaf.entries {
+					if Equal(entry.key, key) {
+						return entry.value, true
+					}
+				}
+			}
+			break
+		}
+		node = child.node
+	}
+	var zero T
+	return zero, false
+}
+
+// Set returns a new trie where key maps to value.
+func (h hamt[T]) Set(key Object, hash uint64, value T) hamt[T] {
+	root := h.root
+	if root == nil {
+		root = &hamtNode[T]{}
+	}
+	newRoot, added := root.set(0, &hamtLeaf[T]{hash: hash, entries: []hamtEntry[T]{{key: key, value: value}}})
+	size := h.size
+	if added {
+		size += 1
+	}
+	return hamt[T]{root: newRoot, size: size}
+}
+
+// Delete returns a new trie without key.
+func (h hamt[T]) Delete(key Object, hash uint64) hamt[T] {
+	if h.root == nil {
+		return h
+	}
+	newRoot, removed := h.root.delete(0, key, hash)
+	if !removed {
+		return h
+	}
+	return hamt[T]{root: newRoot, size: h.size - 1}
+}
+
+func (n *hamtNode[T]) index(bit uint32) int {
+	return bits.OnesCount32(n.bitmap & (bit - 1))
+}
+
+func (n *hamtNode[T]) with(ix int, child hamtChild[T]) *hamtNode[T] {
+	children := make([]hamtChild[T], len(n.children))
+	copy(children, n.children)
+	children[ix] = child
+	return &hamtNode[T]{bitmap: n.bitmap, children: children}
+}
+
+// set inserts the single entry of leaf into the trie rooted at n, reporting if the key is new.
+func (n *hamtNode[T]) set(shift uint, leaf *hamtLeaf[T]) (*hamtNode[T], bool) {
+	bit := uint32(1) << ((leaf.hash >> shift) & hamtMask)
+	ix := n.index(bit)
+
+	if n.bitmap&bit == 0 {
+		children := make([]hamtChild[T], len(n.children)+1)
+		copy(children, n.children[:ix])
+		children[ix] = hamtChild[T]{leaf: leaf}
+		copy(children[ix+1:], n.children[ix:])
+		return &hamtNode[T]{bitmap: n.bitmap | bit, children: children}, true
+	}
+
+	child := n.children[ix]
+	if child.node != nil {
+		node, added := child.node.set(shift+hamtBits, leaf)
+		return n.with(ix, hamtChild[T]{node: node}), added
+	}
+
+	if child.leaf.hash == leaf.hash {
+		entry := leaf.entries[0]
+		entries := make([]hamtEntry[T], len(child.leaf.entries), len(child.leaf.entries)+1)
+		copy(entries, child.leaf.entries)
+		for jx := range entries {
+			if Equal(entries[jx].key, entry.key) {
+				entries[jx].value = entry.value
+				return n.with(ix, hamtChild[T]{leaf: &hamtLeaf[T]{hash: leaf.hash, entries: entries}}), false
+			}
+		}
+		entries = append(entries, entry)
+		return n.with(ix, hamtChild[T]{leaf: &hamtLeaf[T]{hash: leaf.hash, entries: entries}}), true
+	}
+
+	// different hashes share this slot, push both one level down
+	node := &hamtNode[T]{}
+	node, _ = node.set(shift+hamtBits, child.leaf)
+	node, _ = node.set(shift+hamtBits, leaf)
+	return n.with(ix, hamtChild[T]{node: node}), true
+}
+
+// delete removes key from the trie rooted at n, reporting if it was found. nodes left with a
+// single leaf are collapsed into their parent.
+func (n *hamtNode[T]) delete(shift uint, key Object, hash uint64) (*hamtNode[T], bool) {
+	bit := uint32(1) << ((hash >> shift) & hamtMask)
+	if n.bitmap&bit == 0 {
+		return n, false
+	}
+	ix := n.index(bit)
+	child := n.children[ix]
+
+	var replacement hamtChild[T]
+	if child.node != nil {
+		node, removed := child.node.delete(shift+hamtBits, key, hash)
+		if !removed {
+			return n, false
+		}
+		if len(node.children) == 1 && node.children[0].leaf != nil {
+			replacement = node.children[0]
+		} else if len(node.children) > 0 {
+			replacement = hamtChild[T]{node: node}
+		}
+	} else {
+		if child.leaf.hash != hash {
+			return n, false
+		}
+		entries := make([]hamtEntry[T], 0, len(child.leaf.entries))
+		for _, entry := range child.leaf.entries {
+			if !Equal(entry.key, key) {
+				entries = append(entries, entry)
+			}
+		}
+		if len(entries) == len(child.leaf.entries) {
+			return n, false
+		}
+		if len(entries) > 0 {
+			replacement = hamtChild[T]{leaf: &hamtLeaf[T]{hash: hash, entries: entries}}
+		}
+	}
+
+	if replacement.node != nil || replacement.leaf != nil {
+		return n.with(ix, replacement), true
+	}
+
+	children := make([]hamtChild[T], len(n.children)-1)
+	copy(children, n.children[:ix])
+	copy(children[ix:], n.children[ix+1:])
+	return &hamtNode[T]{bitmap: n.bitmap &^ bit, children: children}, true
+}
@@ -0,0 +1,210 @@
+package object
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"hash/fnv"
+	"strings"
+)
+
+// HashKey is a digest of a hashable object. different objects may share the same HashKey, so it
+// must only be used to find candidate pairs whose keys are then compared with Equal.
+type HashKey struct {
+	Type  ObjectType
+	Value uint64
+}
+
+type Hashable interface {
+	HashKey() HashKey
+}
+
+func (b *Boolean) HashKey() HashKey {
+	var value uint64
+	if b.Value {
+		value = 1
+	} else {
+		value = 0
+	}
+	return HashKey{Type: b.Type(), Value: value}
+}
+
+func (i *Integer) HashKey() HashKey {
+	return HashKey{Type: i.Type(), Value: uint64(i.Value)}
+}
+
+func (s *String) HashKey() HashKey {
+	h := fnv.New64a()
+	h.Write([]byte(s.Value))
+	return HashKey{Type: s.Type(), Value: h.Sum64()}
+}
+
+// HashKey combines the keys of all the items of the array. it is only meaningful if all items are
+// hashable, which HashKeyOf checks.
+func (a *Array) HashKey() HashKey {
+	h := fnv.New64a()
+	buf := make([]byte, 8)
+	for _, item := range a.Items() {
+		if hashable, ok := item.(Hashable); ok {
+			key := hashable.HashKey()
+			h.Write([]byte(key.Type))
+			binary.LittleEndian.PutUint64(buf, key.Value)
+			h.Write(buf)
+		}
+	}
+	return HashKey{Type: a.Type(), Value: h.Sum64()}
+}
+
+// HashKeyOf returns the HashKey of obj, reporting false if obj can't be used as a hash key.
+// arrays are only hashable if all of their items are (recursively) hashable.
+func HashKeyOf(obj Object) (HashKey, bool) {
+	if arr, ok := obj.(*Array); ok {
+		for _, item := range arr.Items() {
+			if _, ok := HashKeyOf(item); !ok {
+				return HashKey{}, false
+			}
+		}
+	}
+	hashable, ok := obj.(Hashable)
+	if !ok {
+		return HashKey{}, false
+	}
+	return hashable.HashKey(), true
+}
+
+// Equal reports whether two objects hold the same value. scalars and arrays are compared by value,
+// any other object is only equal to itself.
+func Equal(a Object, b Object) bool {
+	switch a := a.(type) {
+	case *Integer:
+		b, ok := b.(*Integer)
+		return ok && a.Value == b.Value
+	case *String:
+		b, ok := b.(*String)
+		return ok && a.Value == b.Value
+	case *Boolean:
+		b, ok := b.(*Boolean)
+		return ok && a.Value == b.Value
+	case *Null:
+		_, ok := b.(*Null)
+		return ok
+	case *Array:
+		b, ok := b.(*Array)
+		if !ok || a.Len() != b.Len() {
+			return false
+		}
+		for ix := 0; ix < a.Len(); ix++ {
+			if !Equal(a.Get(ix), b.Get(ix)) {
+				return false
+			}
+		}
+		return true
+	default:
+		return a == b
+	}
+}
+
+type HashPair struct {
+	Key   Object
+	Value Object
+}
+
+// Hash maps hashable keys to values, remembering the order in which keys were first inserted.
+// It is backed by persistent data structures, so Copy is O(1) and modifying the copy never affects
+// the original. Hashes must be created with NewHash.
+type Hash struct {
+	index hamt[int]        // key to its position in pairs
+	pairs vector[HashPair] // in insertion order, deleted pairs are left with a nil Key
+}
+
+func NewHash() *Hash {
+	return &Hash{pairs: newVector[HashPair]()}
+}
+
+func (h *Hash) Type() ObjectType { return HASH_OBJ }
+func (h *Hash) Inspect() string {
+	var out bytes.Buffer
+
+	pairs := []string{}
+	for _, pair := range h.Items() {
+		pairs = append(pairs, fmt.Sprintf("%s: %s",
+			pair.Key.Inspect(), pair.Value.Inspect()))
+	}
+
+	out.WriteString("{")
+	out.WriteString(strings.Join(pairs, ", "))
+	out.WriteString("}")
+
+	return out.String()
+}
+
+// Set adds or replaces the value for key, reporting false if key is not hashable.
+// replacing a value keeps the original position of the key.
+func (h *Hash) Set(key Object, value Object) bool {
+	hk, ok := HashKeyOf(key)
+	if !ok {
+		return false
+	}
+	if ix, found := h.index.Get(key, hk.Value); found {
+		h.pairs = h.pairs.Set(ix, HashPair{Key: key, Value: value})
+		return true
+	}
+	h.index = h.index.Set(key, hk.Value, h.pairs.Len())
+	h.pairs = h.pairs.Push(HashPair{Key: key, Value: value})
+	return true
+}
+
+// Get looks up the value for key, reporting false if it is missing or key is not hashable.
+func (h *Hash) Get(key Object) (Object, bool) {
+	hk, ok := HashKeyOf(key)
+	if !ok {
+		return nil, false
+	}
+	ix, found := h.index.Get(key, hk.Value)
+	if !found {
+		return nil, false
+	}
+	return h.pairs.Get(ix).Value, true
+}
+
+func (h *Hash) Delete(key Object) {
+	hk, ok := HashKeyOf(key)
+	if !ok {
+		return
+	}
+	ix, found := h.index.Get(key, hk.Value)
+	if !found {
+		return
+	}
+	h.index = h.index.Delete(key, hk.Value)
+	h.pairs = h.pairs.Set(ix, HashPair{})
+
+	// once most of the pairs are deleted rebuild the hash so they don't pile up
+	if h.pairs.Len() > vectorWidth && h.index.Len()*2 < h.pairs.Len() {
+		compacted := NewHash()
+		for _, pair := range h.Items() {
+			compacted.Set(pair.Key, pair.Value)
+		}
+		*h = *compacted
+	}
+}
+
+func (h *Hash) Len() int {
+	return h.index.Len()
+}
+
+// Items returns all the pairs in insertion order.
+func (h *Hash) Items() []HashPair {
+	items := make([]HashPair, 0, h.index.Len())
+	for ix := 0; ix < h.pairs.Len(); ix++ {
+		if pair := h.pairs.Get(ix); pair.Key != nil {
+			items = append(items, pair)
+		}
+	}
+	return items
+}
+
+// Copy returns a copy of the hash that can be modified without affecting the original.
+func (h *Hash) Copy() *Hash {
+	return &Hash{index: h.index, pairs: h.pairs}
+}
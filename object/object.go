@@ -3,7 +3,6 @@ package object
 import (
 	"bytes"
 	"fmt"
-	"hash/fnv"
 	"strings"
 
 	"github.com/manuelpepe/interpreter/ast"
@@ -34,15 +33,27 @@ type Object interface {
 	Inspect() string
 }
 
+// Array is an immutable sequence of objects backed by a persistent vector, so Push, Set and Slice
+// are near O(1) and return new arrays that share most of their structure with the original.
 type Array struct {
-	Items []Object
+	items vector[Object]
+	start int // the array is the view [start, end) of items
+	end   int
+}
+
+func NewArray(items []Object) *Array {
+	vec := newVector[Object]()
+	for _, item := range items {
+		vec = vec.Push(item)
+	}
+	return &Array{items: vec, start: 0, end: vec.Len()}
 }
 
 func (a *Array) Type() ObjectType { return ARRAY_OBJ }
 func (a *Array) Inspect() string {
 	var out bytes.Buffer
 	items := []string{}
-	for _, e := range a.Items {
+	for _, e := range a.Items() {
 		items = append(items, e.Inspect())
 	}
 	out.WriteString("[")
@@ -52,13 +63,72 @@ func (a *Array) Inspect() string {
 
 }
 
+func (a *Array) Len() int {
+	return a.end - a.start
+}
+
+// Get returns the item at position i, which must be within bounds.
+func (a *Array) Get(i int) Object {
+	return a.items.Get(a.start + i)
+}
+
+// Items returns a new slice with all the items of the array.
+func (a *Array) Items() []Object {
+	out := make([]Object, a.Len())
+	for ix := range out {
+		out[ix] = a.Get(ix)
+	}
+	return out
+}
+
+// Push returns a new array with x added at the end.
+func (a *Array) Push(x Object) *Array {
+	var items vector[Object]
+	if a.end == a.items.Len() {
+		items = a.items.Push(x)
+	} else {
+		// a is a slice of a longer array, reuse the slot after its end
+		items = a.items.Set(a.end, x)
+	}
+	return &Array{items: items, start: a.start, end: a.end + 1}
+}
+
+// Set returns a new array with the item at position i (which must be within bounds) replaced by x.
+func (a *Array) Set(i int, x Object) *Array {
+	return &Array{items: a.items.Set(a.start+i, x), start: a.start, end: a.end}
+}
+
+// Slice returns the items in [start, end), which must be within bounds. the items are not copied.
+func (a *Array) Slice(start int, end int) *Array {
+	return &Array{items: a.items, start: a.start + start, end: a.start + end}
+}
+
 type Builtin struct {
-	Fn BuiltinFunction
+	Fn   BuiltinFunction
+	Spec *BuiltinSpec // checked before calling Fn, if set
 }
 
 func (b *Builtin) Type() ObjectType { return BUILTIN_OBJ }
 func (b *Builtin) Inspect() string  { return fmt.Sprintf("<builtin function '%s'>", b.Fn.Name()) }
 
+// Call checks the arguments against the spec of the builtin and calls it.
+func (b *Builtin) Call(args ...Object) Object {
+	if b.Spec != nil {
+		if err := b.Spec.Check(b.Fn.Name(), args); err != nil {
+			return err
+		}
+	}
+	return b.Fn.Do(args...)
+}
+
+// Signature returns how the builtin is called, with its parameters if it has a spec.
+func (b *Builtin) Signature() string {
+	if b.Spec == nil {
+		return b.Fn.Name() + "(...)"
+	}
+	return b.Spec.Signature(b.Fn.Name())
+}
+
 type Integer struct {
 	Value int64
 }
@@ -99,13 +169,27 @@ type Error struct {
 func (e *Error) Type() ObjectType { return ERROR_OBJ }
 func (e *Error) Inspect() string  { return "ERROR: " + e.Message }
 
+// Error makes errors usable as Go errors, see eval.Call.
+func (e *Error) Error() string { return e.Message }
+
 type Function struct {
+	Name       string // name of the let statement that defined the function, if any
 	Parameters []*ast.Identifier
 	Body       *ast.BlockStatement
+	Locals     []string // names of the slots of the function's scope, see Environment.Enclose
 	Env        *Environment
 }
 
 func (f *Function) Type() ObjectType { return FUNCTION_OBJ }
+
+// Signature is the function without its body, eg. `fn(a, b)`.
+func (f *Function) Signature() string {
+	params := make([]string, len(f.Parameters))
+	for ix, p := range f.Parameters {
+		params[ix] = p.Value
+	}
+	return "fn(" + strings.Join(params, ", ") + ")"
+}
 func (f *Function) Inspect() string {
 	var out bytes.Buffer
 
@@ -123,58 +207,3 @@ func (f *Function) Inspect() string {
 
 	return out.String()
 }
-
-type HashKey struct {
-	Type  ObjectType
-	Value uint64
-}
-
-func (b *Boolean) HashKey() HashKey {
-	var value uint64
-	if b.Value {
-		value = 1
-	} else {
-		value = 0
-	}
-	return HashKey{Type: b.Type(), Value: value}
-}
-
-func (i *Integer) HashKey() HashKey {
-	return HashKey{Type: i.Type(), Value: uint64(i.Value)}
-}
-
-func (s *String) HashKey() HashKey {
-	h := fnv.New64a()
-	h.Write([]byte(s.Value))
-	return HashKey{Type: s.Type(), Value: h.Sum64()}
-}
-
-type HashPair struct {
-	Key   Object
-	Value Object
-}
-
-type Hash struct {
-	Pairs map[HashKey]HashPair
-}
-
-func (h *Hash) Type() ObjectType { return HASH_OBJ }
-func (h *Hash) Inspect() string {
-	var out bytes.Buffer
-
-	pairs := []string{}
-	for _, pair := range h.Pairs {
-		pairs = append(pairs, fmt.Sprintf("%s: %s",
-			pair.Key.Inspect(), pair.Value.Inspect()))
-	}
-
-	out.WriteString("{")
-	out.WriteString(strings.Join(pairs, ", "))
-	out.WriteString("}")
-
-	return out.String()
-}
-
-type Hashable interface {
-	HashKey() HashKey
-}
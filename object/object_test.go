@@ -1,6 +1,9 @@
 package object
 
-import "testing"
+import (
+	"fmt"
+	"testing"
+)
 
 func TestStringHashKey(t *testing.T) {
 	hello1 := &String{Value: "Hello World"}
@@ -17,3 +20,286 @@ func TestStringHashKey(t *testing.T) {
 		t.Errorf("strings with different content have same hash keys")
 	}
 }
+
+func TestHashKeepsInsertionOrder(t *testing.T) {
+	keys := []*String{{Value: "c"}, {Value: "a"}, {Value: "b"}, {Value: "d"}}
+
+	hash := NewHash()
+	for ix, key := range keys {
+		hash.Set(key, &Integer{Value: int64(ix)})
+	}
+	hash.Set(&String{Value: "c"}, &Integer{Value: 10})
+	hash.Delete(&String{Value: "b"})
+
+	cp := hash.Copy()
+	cp.Delete(&String{Value: "a"})
+
+	if hash.Inspect() != "{c: 10, a: 1, d: 3}" {
+		t.Errorf("hash.Inspect() wrong. got=%q", hash.Inspect())
+	}
+	if cp.Inspect() != "{c: 10, d: 3}" {
+		t.Errorf("cp.Inspect() wrong. got=%q", cp.Inspect())
+	}
+	if hash.Len() != 3 || cp.Len() != 2 {
+		t.Errorf("wrong lengths. got=%d and %d", hash.Len(), cp.Len())
+	}
+}
+
+func TestArrayHashKey(t *testing.T) {
+	pair1 := NewArray([]Object{&Integer{Value: 1}, &String{Value: "a"}})
+	pair2 := NewArray([]Object{&Integer{Value: 1}, &String{Value: "a"}})
+	swapped := NewArray([]Object{&String{Value: "a"}, &Integer{Value: 1}})
+	if pair1.HashKey() != pair2.HashKey() {
+		t.Errorf("arrays with same content have different hash keys")
+	}
+	if pair1.HashKey() == swapped.HashKey() {
+		t.Errorf("arrays with different content have same hash keys")
+	}
+	if _, ok := HashKeyOf(NewArray([]Object{NewHash()})); ok {
+		t.Errorf("array containing a hash should not be hashable")
+	}
+}
+
+// collidingKey always produces the same HashKey, to simulate collisions.
+type collidingKey struct{ name string }
+
+func (c *collidingKey) Type() ObjectType { return "COLLIDING" }
+func (c *collidingKey) Inspect() string  { return c.name }
+func (c *collidingKey) HashKey() HashKey { return HashKey{Type: c.Type(), Value: 42} }
+
+func TestHashCollisions(t *testing.T) {
+	a := &collidingKey{name: "a"}
+	b := &collidingKey{name: "b"}
+
+	hash := NewHash()
+	hash.Set(a, &Integer{Value: 1})
+	hash.Set(b, &Integer{Value: 2})
+
+	if val, ok := hash.Get(a); !ok || val.Inspect() != "1" {
+		t.Errorf("wrong value for a. got=%v", val)
+	}
+	if val, ok := hash.Get(b); !ok || val.Inspect() != "2" {
+		t.Errorf("wrong value for b. got=%v", val)
+	}
+	if _, ok := hash.Get(&collidingKey{name: "c"}); ok {
+		t.Errorf("found value for a missing key with colliding hash")
+	}
+
+	hash.Delete(a)
+	if _, ok := hash.Get(a); ok {
+		t.Errorf("found value for deleted key")
+	}
+	if val, ok := hash.Get(b); !ok || val.Inspect() != "2" {
+		t.Errorf("wrong value for b after deleting a. got=%v", val)
+	}
+}
+
+func TestArrayPersistence(t *testing.T) {
+	const n = 5000 // enough items for a trie with three levels
+
+	arrays := []*Array{NewArray(nil)}
+	for i := 0; i < n; i++ {
+		arrays = append(arrays, arrays[i].Push(&Integer{Value: int64(i)}))
+	}
+
+	for size, arr := range arrays {
+		if arr.Len() != size {
+			t.Fatalf("wrong length. got=%d, want=%d", arr.Len(), size)
+		}
+	}
+
+	full := arrays[n]
+	for i := 0; i < n; i++ {
+		if full.Get(i).(*Integer).Value != int64(i) {
+			t.Fatalf("wrong item at %d. got=%s", i, full.Get(i).Inspect())
+		}
+	}
+
+	updated := full.Set(10, &String{Value: "a"}).Set(n-1, &String{Value: "b"})
+	if updated.Get(10).Inspect() != "a" || updated.Get(n-1).Inspect() != "b" {
+		t.Errorf("Set didn't update items")
+	}
+	if full.Get(10).Inspect() != "10" || full.Get(n-1).Inspect() != fmt.Sprint(n-1) {
+		t.Errorf("Set modified the original array")
+	}
+
+	// pushing to a slice must not affect the array it was sliced from
+	slice := full.Slice(1, 3)
+	pushed := slice.Push(&String{Value: "c"})
+	if pushed.Inspect() != "[1, 2, c]" {
+		t.Errorf("wrong pushed slice. got=%s", pushed.Inspect())
+	}
+	if full.Get(3).Inspect() != "3" {
+		t.Errorf("pushing to a slice modified the original array. got=%s", full.Get(3).Inspect())
+	}
+	if slice.Slice(1, 1).Push(&Integer{Value: 9}).Inspect() != "[9]" {
+		t.Errorf("wrong push to empty slice")
+	}
+}
+
+func TestHashPersistence(t *testing.T) {
+	const n = 3000
+
+	hash := NewHash()
+	for i := 0; i < n; i++ {
+		hash.Set(&Integer{Value: int64(i)}, &Integer{Value: int64(i * 2)})
+	}
+	snapshot := hash.Copy()
+
+	for i := 0; i < n; i += 2 {
+		hash.Delete(&Integer{Value: int64(i)})
+	}
+	hash.Set(&Integer{Value: 1}, &String{Value: "one"})
+
+	if hash.Len() != n/2 || snapshot.Len() != n {
+		t.Fatalf("wrong lengths. got=%d and %d", hash.Len(), snapshot.Len())
+	}
+	for i := 0; i < n; i++ {
+		val, ok := snapshot.Get(&Integer{Value: int64(i)})
+		if !ok || val.(*Integer).Value != int64(i*2) {
+			t.Fatalf("snapshot modified at %d. got=%v", i, val)
+		}
+		_, ok = hash.Get(&Integer{Value: int64(i)})
+		if ok != (i%2 == 1) {
+			t.Fatalf("wrong presence for %d. got=%t", i, ok)
+		}
+	}
+	if val, _ := hash.Get(&Integer{Value: 1}); val.Inspect() != "one" {
+		t.Errorf("wrong value for 1. got=%s", val.Inspect())
+	}
+
+	items := hash.Items()
+	for ix, pair := range items {
+		if pair.Key.(*Integer).Value != int64(ix*2+1) {
+			t.Fatalf("wrong order at %d. got=%s", ix, pair.Key.Inspect())
+		}
+	}
+}
+
+func TestRepr(t *testing.T) {
+	hash := NewHash()
+	hash.Set(&String{Value: "name"}, &String{Value: "monkey"})
+	hash.Set(&Integer{Value: 1}, NewArray([]Object{&String{Value: "a\nb"}, &Boolean{Value: true}, &Null{}}))
+
+	tests := []struct {
+		obj     Object
+		repr    string
+		inspect string
+	}{
+		{&String{Value: "hi"}, `"hi"`, `hi`},
+		{&Integer{Value: 5}, `5`, `5`},
+		{NewArray([]Object{&String{Value: "a"}, &Integer{Value: 1}}), `["a", 1]`, `[a, 1]`},
+		{hash, `{"name": "monkey", 1: ["a\nb", true, null]}`, "{name: monkey, 1: [a\nb, true, null]}"},
+	}
+
+	for _, tt := range tests {
+		if got := Repr(tt.obj); got != tt.repr {
+			t.Errorf("wrong repr. expected=%q, got=%q", tt.repr, got)
+		}
+		if got := tt.obj.Inspect(); got != tt.inspect {
+			t.Errorf("wrong inspect. expected=%q, got=%q", tt.inspect, got)
+		}
+	}
+}
+
+type testBuiltin string
+
+func (tb testBuiltin) Name() string             { return string(tb) }
+func (tb testBuiltin) Do(args ...Object) Object { return &String{Value: string(tb)} }
+
+func TestRegistry(t *testing.T) {
+	r := NewRegistry()
+	get := &BuiltinSpec{
+		Params:   []BuiltinParam{{Name: "url", Types: []ObjectType{STRING_OBJ}}, {Name: "headers", Types: []ObjectType{HASH_OBJ}}},
+		Optional: 1,
+		Doc:      "fetches url",
+	}
+	for _, name := range []string{"http.get", "http.post", "http.cookies.clear", "len"} {
+		spec := get
+		if name != "http.get" {
+			spec = nil
+		}
+		if err := r.Register(testBuiltin(name), spec); err != nil {
+			t.Fatalf("unexpected error registering %s: %s", name, err)
+		}
+	}
+
+	errs := map[string]string{
+		"http":          "builtin http conflicts with http.cookies.clear",
+		"len.x":         "builtin len.x conflicts with len",
+		"a..b":          `invalid builtin name: "a..b"`,
+		"fn":            `invalid builtin name: "fn"`,
+		"http.get-list": `invalid builtin name: "http.get-list"`,
+	}
+	for name, expected := range errs {
+		if err := r.Register(testBuiltin(name), nil); err == nil || err.Error() != expected {
+			t.Errorf("wrong error registering %s. expected=%q, got=%v", name, expected, err)
+		}
+	}
+
+	if expected := []string{"http.cookies.clear", "http.get", "http.post", "len"}; fmt.Sprint(r.Names()) != fmt.Sprint(expected) {
+		t.Errorf("wrong names. expected=%v, got=%v", expected, r.Names())
+	}
+
+	ns, ok := r.Lookup("http")
+	if !ok || Repr(ns) != "{\"cookies\": {\"clear\": <builtin function 'http.cookies.clear'>}, "+
+		"\"get\": <builtin function 'http.get'>, \"post\": <builtin function 'http.post'>}" {
+		t.Errorf("wrong namespace: %v", Repr(ns))
+	}
+	if _, ok := r.Lookup("cookies"); ok {
+		t.Errorf("expected nested namespace to not be found by its own name")
+	}
+
+	help, _ := r.Help("http.get")
+	if help != "http.get(url: STRING, headers?: HASHMAP)\n    fetches url" {
+		t.Errorf("wrong help: %q", help)
+	}
+	help, _ = r.Help("http")
+	if help != "http.cookies.clear(...)\nhttp.get(url: STRING, headers?: HASHMAP)\nhttp.post(...)" {
+		t.Errorf("wrong namespace help: %q", help)
+	}
+
+	if again, _ := r.Lookup("http"); again != ns {
+		t.Errorf("expected namespace to be reused between lookups")
+	}
+
+	clone := r.Clone()
+	clone.Register(testBuiltin("extra"), nil)
+	clone.Register(testBuiltin("http.delete"), nil)
+	if _, ok := r.Get("extra"); ok {
+		t.Errorf("expected clone to be independent")
+	}
+	if again, _ := r.Lookup("http"); again != ns || ns.(*Hash).Len() != 3 {
+		t.Errorf("expected namespace of the original registry to be unchanged: %s", Repr(again))
+	}
+	cloned, _ := clone.Lookup("http")
+	if _, ok := cloned.(*Hash).Get(&String{Value: "delete"}); !ok {
+		t.Errorf("expected new member in namespace of the clone: %s", Repr(cloned))
+	}
+}
+
+func TestBuiltinSpecCheck(t *testing.T) {
+	str, num := &String{Value: "a"}, &Integer{Value: 1}
+	tests := []struct {
+		spec     BuiltinSpec
+		args     []Object
+		expected string
+	}{
+		{BuiltinSpec{Params: []BuiltinParam{{Name: "a"}}}, []Object{}, "wrong number of arguments. got=0, want=1"},
+		{BuiltinSpec{Params: []BuiltinParam{{Name: "a"}, {Name: "b"}}, Optional: 1}, []Object{str, str, str}, "wrong number of arguments. got=3, want=1..2"},
+		{BuiltinSpec{Params: []BuiltinParam{{Name: "a"}}, Variadic: true}, []Object{}, "wrong number of arguments. got=0, want=1+"},
+		{BuiltinSpec{Params: []BuiltinParam{{Name: "a"}}, Optional: 1, Variadic: true}, []Object{}, ""},
+		{BuiltinSpec{Params: []BuiltinParam{{Name: "a", Types: []ObjectType{STRING_OBJ}}, {Name: "b", Types: []ObjectType{INTEGER_OBJ, STRING_OBJ}}}, Variadic: true},
+			[]Object{str, num, str, num}, ""},
+		{BuiltinSpec{Params: []BuiltinParam{{Name: "a", Types: []ObjectType{STRING_OBJ}}, {Name: "b", Types: []ObjectType{INTEGER_OBJ, ARRAY_OBJ}}}, Variadic: true},
+			[]Object{str, num, str}, "argument 3 to `f` must be INTEGER or ARRAY, got STRING"},
+	}
+	for _, tt := range tests {
+		err := tt.spec.Check("f", tt.args)
+		if tt.expected == "" && err != nil {
+			t.Errorf("unexpected error: %s", err.Message)
+		} else if tt.expected != "" && (err == nil || err.Message != tt.expected) {
+			t.Errorf("wrong error. expected=%q, got=%v", tt.expected, err)
+		}
+	}
+}
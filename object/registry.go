@@ -0,0 +1,238 @@
+package object
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/manuelpepe/interpreter/token"
+)
+
+// BuiltinSpec describes the arguments of a builtin function. when a builtin has a spec its arguments
+// are checked before calling it, and the spec is used to document it.
+type BuiltinSpec struct {
+	Params []BuiltinParam
+	// Optional is the number of trailing parameters that can be omitted.
+	Optional int
+	// Variadic makes the last parameter accept any number of arguments.
+	Variadic bool
+	// Result is the type of the value returned, any type (or an error) if empty.
+	Result ObjectType
+	Doc    string
+}
+
+type BuiltinParam struct {
+	Name  string
+	Types []ObjectType // accepted types, any type if empty
+}
+
+// Arity returns the minimum and maximum number of arguments, max being -1 if there's no limit.
+func (s *BuiltinSpec) Arity() (int, int) {
+	required := len(s.Params) - s.Optional
+	if s.Variadic {
+		return required, -1
+	}
+	return required, len(s.Params)
+}
+
+// Check validates the number and types of the arguments to the builtin called name.
+func (s *BuiltinSpec) Check(name string, args []Object) *Error {
+	lo, hi := s.Arity()
+	switch {
+	case hi == -1 && len(args) < lo:
+		return &Error{Message: fmt.Sprintf("wrong number of arguments. got=%d, want=%d+", len(args), lo)}
+	case hi != -1 && (len(args) < lo || len(args) > hi) && lo == hi:
+		return &Error{Message: fmt.Sprintf("wrong number of arguments. got=%d, want=%d", len(args), lo)}
+	case hi != -1 && (len(args) < lo || len(args) > hi):
+		return &Error{Message: fmt.Sprintf("wrong number of arguments. got=%d, want=%d..%d", len(args), lo, hi)}
+	}
+
+	for ix, arg := range args {
+		param := s.Params[min(ix, len(s.Params)-1)]
+		if len(param.Types) == 0 {
+			continue
+		}
+		found := false
+		for _, typ := range param.Types {
+			found = found || arg.Type() == typ
+		}
+		if !found {
+			types := make([]string, len(param.Types))
+			for ix, typ := range param.Types {
+				types[ix] = string(typ)
+			}
+			return &Error{Message: fmt.Sprintf("argument %d to `%s` must be %s, got %s",
+				ix+1, name, strings.Join(types, " or "), arg.Type())}
+		}
+	}
+	return nil
+}
+
+// Signature returns the spec as it would be called, eg. `pad_left(s: STRING, width: INTEGER, pad?: STRING): STRING`.
+func (s *BuiltinSpec) Signature(name string) string {
+	required, _ := s.Arity()
+	params := make([]string, len(s.Params))
+	for ix, p := range s.Params {
+		param := p.Name
+		if s.Variadic && ix == len(s.Params)-1 {
+			param = "..." + param
+		} else if ix >= required {
+			param += "?"
+		}
+		if len(p.Types) != 0 {
+			types := make([]string, len(p.Types))
+			for ix, typ := range p.Types {
+				types[ix] = string(typ)
+			}
+			param += ": " + strings.Join(types, " | ")
+		}
+		params[ix] = param
+	}
+	sig := name + "(" + strings.Join(params, ", ") + ")"
+	if s.Result != "" {
+		sig += ": " + string(s.Result)
+	}
+	return sig
+}
+
+// Registry holds the builtin functions available to programs by name. names can be qualified with
+// namespaces separated by dots, eg. `http.get`, in which case programs see the namespace `http` as a
+// hash with the builtin under the key "get".
+//
+// a registry is not safe to modify while programs using it are running.
+type Registry struct {
+	builtins map[string]*Builtin
+
+	// members holds the names of the members of each namespace, sorted, eg. "http" to ["get"].
+	// namespaces holds the hash programs see for each of them, rebuilt when a member is added.
+	// both are replaced instead of modified, as they are shared with clones.
+	members    map[string][]string
+	namespaces map[string]*Hash
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		builtins:   make(map[string]*Builtin),
+		members:    make(map[string][]string),
+		namespaces: make(map[string]*Hash),
+	}
+}
+
+// Register adds fn under fn.Name(), replacing any builtin with the same name. spec may be nil, in
+// which case the arguments are not checked before calling fn.
+func (r *Registry) Register(fn BuiltinFunction, spec *BuiltinSpec) error {
+	name := fn.Name()
+	parts := strings.Split(name, ".")
+	for _, part := range parts {
+		if !isIdentifier(part) || token.LookupIdent(part) != token.IDENT {
+			return fmt.Errorf("invalid builtin name: %q", name)
+		}
+	}
+	if spec != nil && (spec.Optional > len(spec.Params) || spec.Variadic && len(spec.Params) == 0) {
+		return fmt.Errorf("invalid spec for builtin %s", name)
+	}
+	if _, ok := r.members[name]; ok {
+		other := name
+		for members, ok := r.members[other]; ok; members, ok = r.members[other] {
+			other += "." + members[0]
+		}
+		return fmt.Errorf("builtin %s conflicts with %s", name, other)
+	}
+	for ix := 1; ix < len(parts); ix++ {
+		if ns := strings.Join(parts[:ix], "."); r.builtins[ns] != nil {
+			return fmt.Errorf("builtin %s conflicts with %s", name, ns)
+		}
+	}
+
+	_, replaced := r.builtins[name]
+	r.builtins[name] = &Builtin{Fn: fn, Spec: spec}
+	// namespaces hold their members, so all the enclosing ones change, innermost first
+	for ix := len(parts) - 1; ix > 0; ix-- {
+		ns := strings.Join(parts[:ix], ".")
+		if !replaced && !slices.Contains(r.members[ns], parts[ix]) {
+			members := append(slices.Clone(r.members[ns]), parts[ix])
+			slices.Sort(members)
+			r.members[ns] = members
+		}
+		hash := NewHash()
+		for _, member := range r.members[ns] {
+			val, _ := r.Lookup(ns + "." + member)
+			hash.Set(&String{Value: member}, val)
+		}
+		r.namespaces[ns] = hash
+	}
+	return nil
+}
+
+// MustRegister is like Register but panics if fn can't be registered.
+func (r *Registry) MustRegister(fn BuiltinFunction, spec *BuiltinSpec) {
+	if err := r.Register(fn, spec); err != nil {
+		panic(err)
+	}
+}
+
+// Get returns the builtin registered with a qualified name.
+func (r *Registry) Get(name string) (*Builtin, bool) {
+	b, ok := r.builtins[name]
+	return b, ok
+}
+
+// Lookup returns what a program sees for an identifier: either a builtin or a hash with the
+// members of a namespace.
+func (r *Registry) Lookup(name string) (Object, bool) {
+	if b, ok := r.builtins[name]; ok {
+		return b, true
+	}
+	if ns, ok := r.namespaces[name]; ok {
+		return ns, true
+	}
+	return nil, false
+}
+
+// Names returns the qualified names of all builtins, sorted.
+func (r *Registry) Names() []string {
+	names := make([]string, 0, len(r.builtins))
+	for name := range r.builtins {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Clone returns a copy of the registry that can be modified independently.
+func (r *Registry) Clone() *Registry {
+	return &Registry{
+		builtins:   maps.Clone(r.builtins),
+		members:    maps.Clone(r.members),
+		namespaces: maps.Clone(r.namespaces),
+	}
+}
+
+// Help returns the signature and documentation of a builtin, or the builtins of a namespace.
+func (r *Registry) Help(name string) (string, bool) {
+	if b, ok := r.builtins[name]; ok {
+		if b.Spec == nil || b.Spec.Doc == "" {
+			return b.Signature(), true
+		}
+		return b.Signature() + "\n    " + b.Spec.Doc, true
+	}
+	var members []string
+	for _, qualified := range r.Names() {
+		if strings.HasPrefix(qualified, name+".") {
+			members = append(members, r.builtins[qualified].Signature())
+		}
+	}
+	return strings.Join(members, "\n"), len(members) != 0
+}
+
+func isIdentifier(s string) bool {
+	for _, ch := range s {
+		if ch != '_' && !unicode.IsLetter(ch) {
+			return false
+		}
+	}
+	return s != ""
+}
@@ -0,0 +1,33 @@
+package object
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Repr returns a representation of an object for display, which unlike Inspect quotes strings,
+// including those inside arrays and hashes. Strings are quoted and escaped Go-style (eg. "a\n"),
+// which Monkey has no syntax for, so the result is not always valid code: a string containing `${`
+// would also be read as an interpolation. Objects that have no literal form are shown as their
+// Inspect.
+func Repr(obj Object) string {
+	switch obj := obj.(type) {
+	case *String:
+		return strconv.Quote(obj.Value)
+	case *Array:
+		items := make([]string, obj.Len())
+		for ix, item := range obj.Items() {
+			items[ix] = Repr(item)
+		}
+		return "[" + strings.Join(items, ", ") + "]"
+	case *Hash:
+		pairs := make([]string, 0, obj.Len())
+		for _, pair := range obj.Items() {
+			pairs = append(pairs, Repr(pair.Key)+": "+Repr(pair.Value))
+		}
+		return "{" + strings.Join(pairs, ", ") + "}"
+	case *ReturnValue:
+		return Repr(obj.Value)
+	}
+	return obj.Inspect()
+}
@@ -0,0 +1,128 @@
+package object
+
+const (
+	vectorBits  = 5
+	vectorWidth = 1 << vectorBits
+	vectorMask  = vectorWidth - 1
+)
+
+// vector is a persistent (immutable) sequence implemented as a 32-way trie with a tail buffer,
+// in the style of Clojure's PersistentVector. Get, Set and Push are O(log32 n) and every update
+// returns a new vector sharing most of its structure with the original.
+type vector[T any] struct {
+	count int
+	shift uint
+	root  *vectorNode[T]
+	tail  []T
+}
+
+// vectorNode is either an internal node (children) or a leaf (values).
+type vectorNode[T any] struct {
+	children []*vectorNode[T]
+	values   []T
+}
+
+func newVector[T any]() vector[T] {
+	return vector[T]{shift: vectorBits, root: &vectorNode[T]{}}
+}
+
+func (v vector[T]) Len() int {
+	return v.count
+}
+
+// tailOffset is the index of the first item stored in the tail.
+func (v vector[T]) tailOffset() int {
+	if v.count < vectorWidth {
+		return 0
+	}
+	return ((v.count - 1) >> vectorBits) << vectorBits
+}
+
+// Get returns the item at position i, which must be within bounds.
+func (v vector[T]) Get(i int) T {
+	if i >= v.tailOffset() {
+		return v.tail[i-v.tailOffset()]
+	}
+	node := v.root
+	for level := v.shift; level > 0; level -= vectorBits {
+		node = node.children[(i>>level)&vectorMask]
+	}
+	return node.values[i&vectorMask]
+}
+
+// Push returns a new vector with x added at the end.
+func (v vector[T]) Push(x T) vector[T] {
+	if v.count-v.tailOffset() < vectorWidth {
+		tail := make([]T, len(v.tail)+1)
+		copy(tail, v.tail)
+		tail[len(v.tail)] = x
+		return vector[T]{count: v.count + 1, shift: v.shift, root: v.root, tail: tail}
+	}
+
+	// tail is full, move it into the trie
+	tailNode := &vectorNode[T]{values: v.tail}
+	shift := v.shift
+	var root *vectorNode[T]
+	if (v.count >> vectorBits) > (1 << v.shift) {
+		// root overflow, grow the trie one level
+		root = &vectorNode[T]{children: []*vectorNode[T]{v.root, newVectorPath(v.shift, tailNode)}}
+		shift += vectorBits
+	} else {
+		root = v.pushTail(v.shift, v.root, tailNode)
+	}
+	return vector[T]{count: v.count + 1, shift: shift, root: root, tail: []T{x}}
+}
+
+func (v vector[T]) pushTail(level uint, parent *vectorNode[T], tailNode *vectorNode[T]) *vectorNode[T] {
+	sub := ((v.count - 1) >> level) & vectorMask
+	node := &vectorNode[T]{children: make([]*vectorNode[T], len(parent.children), sub+1)}
+	copy(node.children, parent.children)
+
+	var child *vectorNode[T]
+	if level == vectorBits {
+		child = tailNode
+	} else if sub < len(parent.children) {
+		child = v.pushTail(level-vectorBits, parent.children[sub], tailNode)
+	} else {
+		child = newVectorPath(level-vectorBits, tailNode)
+	}
+
+	if sub < len(node.children) {
+		node.children[sub] = child
+	} else {
+		node.children = append(node.children, child)
+	}
+	return node
+}
+
+func newVectorPath[T any](level uint, node *vectorNode[T]) *vectorNode[T] {
+	if level == 0 {
+		return node
+	}
+	return &vectorNode[T]{children: []*vectorNode[T]{newVectorPath(level-vectorBits, node)}}
+}
+
+// Set returns a new vector with the item at position i (which must be within bounds) replaced by x.
+func (v vector[T]) Set(i int, x T) vector[T] {
+	if i >= v.tailOffset() {
+		tail := make([]T, len(v.tail))
+		copy(tail, v.tail)
+		tail[i-v.tailOffset()] = x
+		return vector[T]{count: v.count, shift: v.shift, root: v.root, tail: tail}
+	}
+	return vector[T]{count: v.count, shift: v.shift, root: v.set(v.shift, v.root, i, x), tail: v.tail}
+}
+
+func (v vector[T]) set(level uint, node *vectorNode[T], i int, x T) *vectorNode[T] {
+	if level == 0 {
+		values := make([]T, len(node.values))
+		copy(values, node.values)
+		values[i&vectorMask] = x
+		return &vectorNode[T]{values: values}
+	}
+	children := make([]*vectorNode[T], len(node.children))
+	copy(children, node.children)
+	sub := (i >> level) & vectorMask
+	children[sub] = v.set(level-vectorBits, node.children[sub], i, x)
+	return &vectorNode[T]{children: children}
+}
@@ -0,0 +1,123 @@
+package optimize
+
+import (
+	"strconv"
+
+	"github.com/manuelpepe/interpreter/ast"
+	"github.com/manuelpepe/interpreter/token"
+)
+
+// foldPrefix replaces a prefix operation on a literal with its result.
+func foldPrefix(pe *ast.PrefixExpression) ast.Expression {
+	switch right := pe.Right.(type) {
+	case *ast.IntegerLiteral:
+		switch pe.Operator {
+		case "-":
+			return newInteger(-right.Value)
+		case "!":
+			return newBoolean(false)
+		}
+	case *ast.StringLiteral:
+		if pe.Operator == "!" {
+			return newBoolean(false)
+		}
+	case *ast.Boolean:
+		if pe.Operator == "!" {
+			return newBoolean(!right.Value)
+		}
+	}
+	return pe
+}
+
+// foldInfix replaces an operation between two literals of the same type with its result.
+// operations that would fail at runtime are left untouched so they still report their errors.
+func foldInfix(ie *ast.InfixExpression) ast.Expression {
+	switch left := ie.Left.(type) {
+	case *ast.IntegerLiteral:
+		right, ok := ie.Right.(*ast.IntegerLiteral)
+		if !ok {
+			return ie
+		}
+		switch ie.Operator {
+		case "+":
+			return newInteger(left.Value + right.Value)
+		case "-":
+			return newInteger(left.Value - right.Value)
+		case "*":
+			return newInteger(left.Value * right.Value)
+		case "/":
+			if right.Value != 0 {
+				return newInteger(left.Value / right.Value)
+			}
+		case "<":
+			return newBoolean(left.Value < right.Value)
+		case ">":
+			return newBoolean(left.Value > right.Value)
+		case "==":
+			return newBoolean(left.Value == right.Value)
+		case "!=":
+			return newBoolean(left.Value != right.Value)
+		}
+	case *ast.StringLiteral:
+		right, ok := ie.Right.(*ast.StringLiteral)
+		if !ok {
+			return ie
+		}
+		switch ie.Operator {
+		case "+":
+			return newString(left.Value + right.Value)
+		case "==":
+			return newBoolean(left.Value == right.Value)
+		case "!=":
+			return newBoolean(left.Value != right.Value)
+		}
+	case *ast.Boolean:
+		right, ok := ie.Right.(*ast.Boolean)
+		if !ok {
+			return ie
+		}
+		switch ie.Operator {
+		case "==":
+			return newBoolean(left.Value == right.Value)
+		case "!=":
+			return newBoolean(left.Value != right.Value)
+		}
+	}
+	return ie
+}
+
+// foldInterpolation joins an interpolated string made only of literals into a single string.
+func foldInterpolation(is *ast.InterpolatedString) ast.Expression {
+	var out string
+	for _, part := range is.Parts {
+		switch part := part.(type) {
+		case *ast.TemplateText:
+			out += part.Value
+		case *ast.StringLiteral:
+			out += part.Value
+		case *ast.IntegerLiteral:
+			out += strconv.FormatInt(part.Value, 10)
+		case *ast.Boolean:
+			out += strconv.FormatBool(part.Value)
+		default:
+			return is
+		}
+	}
+	return newString(out)
+}
+
+func newInteger(value int64) *ast.IntegerLiteral {
+	lit := strconv.FormatInt(value, 10)
+	return &ast.IntegerLiteral{Token: token.Token{Type: token.INT, Literal: lit}, Value: value}
+}
+
+func newString(value string) *ast.StringLiteral {
+	return &ast.StringLiteral{Token: token.Token{Type: token.STRING, Literal: value}, Value: value}
+}
+
+func newBoolean(value bool) *ast.Boolean {
+	if value {
+		return &ast.Boolean{Token: token.Token{Type: token.TRUE, Literal: "true"}, Value: true}
+	}
+	return &ast.Boolean{Token: token.Token{Type: token.FALSE, Literal: "false"}, Value: false}
+}
@@ -0,0 +1,216 @@
+package optimize
+
+import (
+	"slices"
+
+	"github.com/manuelpepe/interpreter/ast"
+	"github.com/manuelpepe/interpreter/resolver"
+)
+
+// inlineable reports if fl, bound to b, can be inlined in its callers. only functions with a single
+// expression as body are inlined, as long as they don't define other functions or variables,
+// don't return early and only refer to their parameters and to globals other than themselves.
+func (o *optimizer) inlineable(fl *ast.FunctionLiteral, b resolver.Binding) bool {
+	body, ok := functionBody(fl)
+	if !ok {
+		return false
+	}
+	inlineable := true
+	var walk func(ast.Node)
+	walk = func(node ast.Node) {
+		switch node := node.(type) {
+		case nil:
+			return
+		case *ast.FunctionLiteral, *ast.LetStatement, *ast.ReturnStatement:
+			inlineable = false
+			return
+		case *ast.Identifier:
+			switch {
+			case !node.Resolved:
+				inlineable = false
+			case node.Depth == 0:
+			case node.Depth == ast.GlobalDepth:
+				if b.Scope == nil && node.Value == b.Name {
+					inlineable = false // recursive
+				}
+			default:
+				inlineable = false // closes over a local of another function
+			}
+		}
+		for _, child := range node.ChildNodes() {
+			walk(child)
+		}
+	}
+	walk(body)
+	return inlineable
+}
+
+// functionBody returns the only expression in the body of fl.
+func functionBody(fl *ast.FunctionLiteral) (ast.Expression, bool) {
+	if len(fl.Body.Statements) != 1 {
+		return nil, false
+	}
+	switch stmt := fl.Body.Statements[0].(type) {
+	case *ast.ExpressionStatement:
+		return stmt.Expression, stmt.Expression != nil
+	case *ast.ReturnStatement:
+		return stmt.ReturnValue, stmt.ReturnValue != nil
+	}
+	return nil, false
+}
+
+// inline replaces a call to an inlineable function with the body of the function, where parameters
+// are replaced by the arguments of the call. arguments must be literals or identifiers so they can be
+// duplicated or dropped without changing the behaviour of the program.
+func (o *optimizer) inline(call *ast.CallExpression) (ast.Expression, bool) {
+	ident, ok := call.Function.(*ast.Identifier)
+	if !ok {
+		return nil, false
+	}
+	b, ok := resolver.BindingOf(o.scopes, ident)
+	if !ok {
+		return nil, false
+	}
+	fl, ok := o.funcs[b]
+	if !ok || o.inlining[fl] || len(fl.Parameters) != len(call.Arguments) {
+		return nil, false
+	}
+	for _, arg := range call.Arguments {
+		switch arg.(type) {
+		case *ast.IntegerLiteral, *ast.StringLiteral, *ast.Boolean, *ast.Identifier:
+		default:
+			return nil, false
+		}
+	}
+	body, _ := functionBody(fl)
+	if o.shadowsGlobals(body) {
+		return nil, false
+	}
+
+	params := make(map[int]ast.Expression, len(fl.Parameters))
+	for ix, param := range fl.Parameters {
+		params[param.Slot] = call.Arguments[ix]
+	}
+	o.inlining[fl] = true
+	defer delete(o.inlining, fl)
+	return o.expression(cloneExpression(body, params)), true
+}
+
+// shadowsGlobals reports if any global referenced in expr is shadowed by a local at the current scope.
+func (o *optimizer) shadowsGlobals(expr ast.Node) bool {
+	if ident, ok := expr.(*ast.Identifier); ok && ident.Depth == ast.GlobalDepth {
+		for _, scope := range o.scopes {
+			if slices.Contains(scope.Locals, ident.Value) {
+				return true
+			}
+		}
+	}
+	for _, child := range expr.ChildNodes() {
+		if child != nil && o.shadowsGlobals(child) {
+			return true
+		}
+	}
+	return false
+}
+
+// cloneExpression returns a deep copy of expr. identifiers local to the function being cloned
+// (depth 0) are replaced by a copy of the expression in params for their slot, if params is not nil.
+func cloneExpression(expr ast.Expression, params map[int]ast.Expression) ast.Expression {
+	switch expr := expr.(type) {
+	case nil:
+		return nil
+	case *ast.Identifier:
+		if params != nil && expr.Resolved && expr.Depth == 0 {
+			return cloneExpression(params[expr.Slot], nil)
+		}
+		ident := *expr
+		return &ident
+	case *ast.IntegerLiteral:
+		lit := *expr
+		return &lit
+	case *ast.StringLiteral:
+		lit := *expr
+		return &lit
+	case *ast.TemplateText:
+		text := *expr
+		return &text
+	case *ast.Boolean:
+		lit := *expr
+		return &lit
+	case *ast.InterpolatedString:
+		return &ast.InterpolatedString{Token: expr.Token, Parts: cloneExpressions(expr.Parts, params)}
+	case *ast.PrefixExpression:
+		return &ast.PrefixExpression{
+			Token:    expr.Token,
+			Operator: expr.Operator,
+			Right:    cloneExpression(expr.Right, params),
+		}
+	case *ast.InfixExpression:
+		return &ast.InfixExpression{
+			Token:    expr.Token,
+			Left:     cloneExpression(expr.Left, params),
+			Operator: expr.Operator,
+			Right:    cloneExpression(expr.Right, params),
+		}
+	case *ast.IfExpression:
+		return &ast.IfExpression{
+			Token:       expr.Token,
+			Condition:   cloneExpression(expr.Condition, params),
+			Consequence: cloneBlock(expr.Consequence, params),
+			Alternative: cloneBlock(expr.Alternative, params),
+		}
+	case *ast.CallExpression:
+		return &ast.CallExpression{
+			Token:     expr.Token,
+			Function:  cloneExpression(expr.Function, params),
+			Arguments: cloneExpressions(expr.Arguments, params),
+		}
+	case *ast.ArrayLiteral:
+		return &ast.ArrayLiteral{Token: expr.Token, Items: cloneExpressions(expr.Items, params)}
+	case *ast.HashLiteral:
+		items := make([]ast.HashItem, len(expr.Items))
+		for ix, item := range expr.Items {
+			items[ix] = ast.HashItem{
+				Key:   cloneExpression(item.Key, params),
+				Value: cloneExpression(item.Value, params),
+			}
+		}
+		return &ast.HashLiteral{Token: expr.Token, Items: items}
+	case *ast.IndexExpression:
+		return &ast.IndexExpression{
+			Token: expr.Token,
+			Left:  cloneExpression(expr.Left, params),
+			Index: cloneExpression(expr.Index, params),
+		}
+	case *ast.SliceExpression:
+		return &ast.SliceExpression{
+			Token: expr.Token,
+			Left:  cloneExpression(expr.Left, params),
+			Start: cloneExpression(expr.Start, params),
+			End:   cloneExpression(expr.End, params),
+		}
+	}
+	// function literals are never cloned, see inlineable
+	return expr
+}
+
+func cloneExpressions(exprs []ast.Expression, params map[int]ast.Expression) []ast.Expression {
+	out := make([]ast.Expression, len(exprs))
+	for ix := range exprs {
+		out[ix] = cloneExpression(exprs[ix], params)
+	}
+	return out
+}
+
+func cloneBlock(b *ast.BlockStatement, params map[int]ast.Expression) *ast.BlockStatement {
+	if b == nil {
+		return nil
+	}
+	stmts := make([]ast.Statement, len(b.Statements))
+	for ix, stmt := range b.Statements {
+		// bodies of inlined functions can only contain expression statements
+		es := stmt.(*ast.ExpressionStatement)
+		stmts[ix] = &ast.ExpressionStatement{Token: es.Token, Expression: cloneExpression(es.Expression, params)}
+	}
+	return &ast.BlockStatement{Token: b.Token, Statements: stmts}
+}
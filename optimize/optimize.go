@@ -0,0 +1,254 @@
+// Package optimize rewrites programs between parsing and evaluation to do less work at runtime.
+//
+// It folds constant expressions (`2 * 3 + 1`), removes the branches of conditionals that can never
+// run, propagates `let` bindings of constant values and inlines calls to small non-recursive
+// functions. A binding is only considered immutable if it is bound by a single unconditional let,
+// and it is only replaced in code that runs after that let.
+//
+// The optimizer assumes it sees the whole program: bindings redefined later (eg. in another REPL
+// line) won't be reflected in code that was already optimized.
+package optimize
+
+import (
+	"github.com/manuelpepe/interpreter/ast"
+	"github.com/manuelpepe/interpreter/resolver"
+	"github.com/manuelpepe/interpreter/token"
+)
+
+type optimizer struct {
+	scopes []*ast.FunctionLiteral // enclosing functions, innermost last
+
+	defs   map[resolver.Binding]int // number of lets for each binding, parameters count as mutable
+	consts map[resolver.Binding]ast.Expression
+	funcs  map[resolver.Binding]*ast.FunctionLiteral // functions that can be inlined
+
+	inlining map[*ast.FunctionLiteral]bool // functions currently being inlined
+}
+
+// Optimize rewrites prog in place and returns it. the program is marked as not resolved, as
+// nodes may have been moved or duplicated.
+func Optimize(prog *ast.Program) *ast.Program {
+	prog.Resolved = false
+	resolver.Resolve(prog)
+
+	o := &optimizer{
+		defs:     make(map[resolver.Binding]int),
+		consts:   make(map[resolver.Binding]ast.Expression),
+		funcs:    make(map[resolver.Binding]*ast.FunctionLiteral),
+		inlining: make(map[*ast.FunctionLiteral]bool),
+	}
+	for _, s := range prog.Statements {
+		o.countDefs(s)
+	}
+	prog.Statements = o.statements(prog.Statements)
+
+	prog.Resolved = false
+	return prog
+}
+
+func (o *optimizer) countDefs(node ast.Node) {
+	switch node := node.(type) {
+	case nil:
+		return
+	case *ast.LetStatement:
+		if b, ok := resolver.BindingOf(o.scopes, node.Name); ok {
+			o.defs[b] += 1
+		}
+	case *ast.FunctionLiteral:
+		o.scopes = append(o.scopes, node)
+		for _, param := range node.Parameters {
+			if b, ok := resolver.BindingOf(o.scopes, param); ok {
+				o.defs[b] += 2
+			}
+		}
+		o.countDefs(node.Body)
+		o.scopes = o.scopes[:len(o.scopes)-1]
+		return
+	}
+	for _, child := range node.ChildNodes() {
+		o.countDefs(child)
+	}
+}
+
+// statements optimizes a list of statements that always run in order (a program or function body),
+// so lets found here can be propagated to the code that follows them.
+func (o *optimizer) statements(stmts []ast.Statement) []ast.Statement {
+	out := make([]ast.Statement, 0, len(stmts))
+	for ix, stmt := range stmts {
+		stmt = o.statement(stmt)
+
+		if es, ok := stmt.(*ast.ExpressionStatement); ok {
+			if branch, ok := deadBranch(es.Expression); ok {
+				last := ix == len(stmts)-1
+				if branch != nil && (len(branch.Statements) > 0 || !last) {
+					// blocks don't create scopes, so the statements of the branch can take the place of the if
+					out = append(out, branch.Statements...)
+					continue
+				}
+				if branch == nil && !last {
+					continue
+				}
+			}
+		}
+
+		if let, ok := stmt.(*ast.LetStatement); ok {
+			o.record(let)
+		}
+		out = append(out, stmt)
+	}
+	return out
+}
+
+// record remembers the value of let if its binding is immutable and it can be propagated.
+func (o *optimizer) record(let *ast.LetStatement) {
+	b, ok := resolver.BindingOf(o.scopes, let.Name)
+	if !ok || o.defs[b] != 1 {
+		return
+	}
+	switch value := let.Value.(type) {
+	case *ast.IntegerLiteral, *ast.StringLiteral, *ast.Boolean:
+		o.consts[b] = value
+	case *ast.FunctionLiteral:
+		if o.inlineable(value, b) {
+			o.funcs[b] = value
+		}
+	}
+}
+
+func (o *optimizer) statement(stmt ast.Statement) ast.Statement {
+	switch stmt := stmt.(type) {
+	case *ast.LetStatement:
+		stmt.Value = o.expression(stmt.Value)
+	case *ast.ReturnStatement:
+		stmt.ReturnValue = o.expression(stmt.ReturnValue)
+	case *ast.ExpressionStatement:
+		stmt.Expression = o.expression(stmt.Expression)
+	case *ast.BlockStatement:
+		o.block(stmt)
+	}
+	return stmt
+}
+
+// block optimizes the statements of a block that may not run, so lets found here are not propagated.
+func (o *optimizer) block(b *ast.BlockStatement) {
+	if b == nil {
+		return
+	}
+	for ix := range b.Statements {
+		b.Statements[ix] = o.statement(b.Statements[ix])
+	}
+}
+
+func (o *optimizer) expression(expr ast.Expression) ast.Expression {
+	switch expr := expr.(type) {
+	case nil:
+		return nil
+	case *ast.Identifier:
+		if b, ok := resolver.BindingOf(o.scopes, expr); ok {
+			if value, ok := o.consts[b]; ok {
+				return cloneExpression(value, nil)
+			}
+		}
+	case *ast.PrefixExpression:
+		expr.Right = o.expression(expr.Right)
+		return foldPrefix(expr)
+	case *ast.InfixExpression:
+		expr.Left = o.expression(expr.Left)
+		expr.Right = o.expression(expr.Right)
+		return foldInfix(expr)
+	case *ast.IfExpression:
+		expr.Condition = o.expression(expr.Condition)
+		o.block(expr.Consequence)
+		o.block(expr.Alternative)
+		return pruneIf(expr)
+	case *ast.FunctionLiteral:
+		o.scopes = append(o.scopes, expr)
+		expr.Body.Statements = o.statements(expr.Body.Statements)
+		o.scopes = o.scopes[:len(o.scopes)-1]
+	case *ast.CallExpression:
+		expr.Function = o.expression(expr.Function)
+		for ix := range expr.Arguments {
+			expr.Arguments[ix] = o.expression(expr.Arguments[ix])
+		}
+		if inlined, ok := o.inline(expr); ok {
+			return inlined
+		}
+	case *ast.ArrayLiteral:
+		for ix := range expr.Items {
+			expr.Items[ix] = o.expression(expr.Items[ix])
+		}
+	case *ast.HashLiteral:
+		for ix := range expr.Items {
+			expr.Items[ix].Key = o.expression(expr.Items[ix].Key)
+			expr.Items[ix].Value = o.expression(expr.Items[ix].Value)
+		}
+	case *ast.IndexExpression:
+		expr.Left = o.expression(expr.Left)
+		expr.Index = o.expression(expr.Index)
+	case *ast.SliceExpression:
+		expr.Left = o.expression(expr.Left)
+		expr.Start = o.expression(expr.Start)
+		expr.End = o.expression(expr.End)
+	case *ast.InterpolatedString:
+		for ix := range expr.Parts {
+			expr.Parts[ix] = o.expression(expr.Parts[ix])
+		}
+		return foldInterpolation(expr)
+	}
+	return expr
+}
+
+// deadBranch reports if expr is a conditional with a constant condition, returning the only branch
+// that can run (which may be nil).
+func deadBranch(expr ast.Expression) (*ast.BlockStatement, bool) {
+	ie, ok := expr.(*ast.IfExpression)
+	if !ok {
+		return nil, false
+	}
+	truthy, ok := constantTruthiness(ie.Condition)
+	if !ok {
+		return nil, false
+	}
+	if truthy {
+		return ie.Consequence, true
+	}
+	return ie.Alternative, true
+}
+
+// pruneIf drops the branch of a conditional that can never run. if the remaining branch is a single
+// expression the conditional is replaced by it.
+func pruneIf(ie *ast.IfExpression) ast.Expression {
+	truthy, ok := constantTruthiness(ie.Condition)
+	if !ok {
+		return ie
+	}
+	branch := ie.Alternative
+	if truthy {
+		branch = ie.Consequence
+	}
+	if branch != nil && len(branch.Statements) == 1 {
+		if es, ok := branch.Statements[0].(*ast.ExpressionStatement); ok && es.Expression != nil {
+			return es.Expression
+		}
+	}
+	if branch == nil {
+		// nothing runs and the conditional evaluates to null
+		return &ast.IfExpression{
+			Token:       ie.Token,
+			Condition:   newBoolean(false),
+			Consequence: &ast.BlockStatement{Token: token.Token{Type: token.LBRACE, Literal: "{"}},
+		}
+	}
+	return &ast.IfExpression{Token: ie.Token, Condition: newBoolean(true), Consequence: branch}
+}
+
+// constantTruthiness reports whether expr is a literal, and if it is truthy.
+func constantTruthiness(expr ast.Expression) (bool, bool) {
+	switch expr := expr.(type) {
+	case *ast.Boolean:
+		return expr.Value, true
+	case *ast.IntegerLiteral, *ast.StringLiteral:
+		return true, true
+	}
+	return false, false
+}
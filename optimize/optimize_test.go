@@ -0,0 +1,100 @@
+package optimize
+
+import (
+	"testing"
+
+	"github.com/manuelpepe/interpreter/ast"
+	"github.com/manuelpepe/interpreter/eval"
+	"github.com/manuelpepe/interpreter/lexer"
+	"github.com/manuelpepe/interpreter/object"
+	"github.com/manuelpepe/interpreter/parser"
+)
+
+func TestOptimize(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		// constant folding
+		{`2 * 3 + 1`, `7`},
+		{`a + 2 * 3`, `(a + 6)`},
+		{`-(1 + 2) * 2`, `-6`},
+		{`!true == false`, `true`},
+		{`!5`, `false`},
+		{`1 < 2`, `true`},
+		{`"a" + "b" == "ab"`, `true`},
+		{`"${1 + 1} apples"`, `"2 apples"`},
+		{`10 / 0`, `(10 / 0)`},
+		{`"a" - "b"`, `("a" - "b")`},
+		{`1 + true`, `(1 + true)`},
+
+		// dead branches
+		{`if (false) { a }; b`, `b`},
+		{`if (true) { a } else { b }; c`, `ac`},
+		{`if (1 > 2) { a } else { b }`, `b`},
+		{`let x = if (true) { 1 } else { 2 }`, `let x = 1;`},
+		{`if (false) { a }`, `iffalse {  }`},
+		{`let f = fn() { if (true) { return 1; }; 2 }`, `let f = fn() { return 1;;2; };`},
+
+		// constant propagation
+		{`let x = 2 * 3; x + 1`, `let x = 6;7`},
+		{`let x = "a"; let f = fn() { x }; f`, `let x = "a";let f = fn() { "a"; };f`},
+		{`a + x; let x = 1; x`, `(a + x)let x = 1;1`},
+		{`let x = 1; let x = 2; x`, `let x = 1;let x = 2;x`},
+		{`if (a) { let x = 1; }; x`, `ifa { let x = 1;; }x`},
+		{`let x = 1; let f = fn(x) { x }; f`, `let x = 1;let f = fn(x) { x; };f`},
+		{`let f = fn() { let y = 1; y + 1 }; f`, `let f = fn() { let y = 1;;2; };f`},
+
+		// inlining
+		{`let double = fn(x) { x * 2 }; double(3)`, `let double = fn(x) { (x * 2); };6`},
+		{`let double = fn(x) { return x * 2; }; double(a)`, `let double = fn(x) { return (x * 2);; };(a * 2)`},
+		{`let add = fn(a, b) { a + b }; add(add(1, 2), 3)`, `let add = fn(a,b) { (a + b); };6`},
+		{`let first = fn(a, b) { a }; first(1, inspect(2))`, `let first = fn(a,b) { a; };first(1, inspect(2))`},
+		{`let f = fn(x) { f(x) }; f(1)`, `let f = fn(x) { f(x); };f(1)`},
+		{`let f = fn(x) { let y = x; y }; f(1)`, `let f = fn(x) { let y = x;;y; };f(1)`},
+		{`f(1); let f = fn(x) { x };`, `f(1)let f = fn(x) { x; };`},
+		{`let n = len; let l = fn(a) { n(a) }; let g = fn(n) { l(n) }`,
+			`let n = len;let l = fn(a) { n(a); };let g = fn(n) { l(n); };`},
+	}
+
+	for _, tt := range tests {
+		prog := Optimize(parse(t, tt.input))
+		if prog.Resolved {
+			t.Errorf("program for %q marked as resolved after optimizing", tt.input)
+		}
+		if prog.String() != tt.expected {
+			t.Errorf("wrong optimization for %q. expected=%q, got=%q", tt.input, tt.expected, prog.String())
+		}
+	}
+}
+
+func TestOptimizedProgramsBehaveTheSame(t *testing.T) {
+	tests := []string{
+		`let x = 5; let double = fn(n) { n * 2 }; double(x) + double(1)`,
+		`let fib = fn(n) { if (n < 2) { n } else { fib(n - 1) + fib(n - 2) } }; fib(10)`,
+		`let f = fn() { if (true) { let y = 3; }; y }; f()`,
+		`let f = fn() { if (false) { 1 } }; f()`,
+		`let k = 10; let add = fn(a) { a + k }; let g = fn(k) { add(k) }; g(1)`,
+		`let max = fn(a, b) { if (a > b) { a } else { b } }; max(3, 7) + max(9, 2)`,
+		`let name = "monkey"; let greet = fn(who) { "hi ${who}" }; greet(name)`,
+		`let x = 1; let f = fn() { x }; f() + x`,
+	}
+
+	for _, input := range tests {
+		expected := eval.Eval(parse(t, input), object.NewEnvironment())
+		got := eval.Eval(Optimize(parse(t, input)), object.NewEnvironment())
+		if expected.Inspect() != got.Inspect() {
+			t.Errorf("optimized program %q returned %s, expected %s", input, got.Inspect(), expected.Inspect())
+		}
+	}
+}
+
+func parse(t *testing.T, input string) *ast.Program {
+	t.Helper()
+	p := parser.New(lexer.NewLexer(input))
+	prog := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("parser errors: %v", p.Errors())
+	}
+	return prog
+}
@@ -34,6 +34,14 @@ var precedences = map[token.TokenType]int{
 	token.LBRACKET: INDEX,
 }
 
+// Precedence returns the binding power of an infix operator, or LOWEST if t is not one.
+func Precedence(t token.TokenType) int {
+	if p, ok := precedences[t]; ok {
+		return p
+	}
+	return LOWEST
+}
+
 type (
 	prefixParseFn func() ast.Expression
 	infixParseFn  func(ast.Expression) ast.Expression
@@ -45,14 +53,14 @@ type Parser struct {
 	curToken  token.Token
 	peekToken token.Token
 
-	errors []string
+	errors []Error
 
 	prefixParseFns map[token.TokenType]prefixParseFn
 	infixParseFns  map[token.TokenType]infixParseFn
 }
 
 func New(l *lexer.Lexer) *Parser {
-	p := &Parser{l: l, errors: make([]string, 0)}
+	p := &Parser{l: l, errors: make([]Error, 0)}
 	p.nextToken()
 	p.nextToken()
 
@@ -60,6 +68,7 @@ func New(l *lexer.Lexer) *Parser {
 	p.registerPrefix(token.IDENT, p.parseIdentifier)
 	p.registerPrefix(token.INT, p.parseIntegerLiteral)
 	p.registerPrefix(token.STRING, p.parseStringLiteral)
+	p.registerPrefix(token.TEMPLATE_START, p.parseInterpolatedString)
 	p.registerPrefix(token.BANG, p.parsePrefixExpression)
 	p.registerPrefix(token.MINUS, p.parsePrefixExpression)
 	p.registerPrefix(token.TRUE, p.parseBoolean)
@@ -85,10 +94,34 @@ func New(l *lexer.Lexer) *Parser {
 	return p
 }
 
+// Error is a syntax error found at the position of Token.
+type Error struct {
+	Token   token.Token
+	Message string
+}
+
+func (e Error) Error() string {
+	return fmt.Sprintf("%d:%d: %s", e.Token.Line, e.Token.Column, e.Message)
+}
+
+// Errors returns the messages of the syntax errors found, see SyntaxErrors for their positions.
 func (p *Parser) Errors() []string {
+	msgs := make([]string, len(p.errors))
+	for ix, err := range p.errors {
+		msgs[ix] = err.Message
+	}
+	return msgs
+}
+
+// SyntaxErrors returns the syntax errors found along with their positions.
+func (p *Parser) SyntaxErrors() []Error {
 	return p.errors
 }
 
+func (p *Parser) errorAt(tok token.Token, msg string) {
+	p.errors = append(p.errors, Error{Token: tok, Message: msg})
+}
+
 func (p *Parser) registerPrefix(tokenType token.TokenType, fn prefixParseFn) {
 	p.prefixParseFns[tokenType] = fn
 }
@@ -142,10 +175,7 @@ func (p *Parser) parseLetStatement() *ast.LetStatement {
 		return nil
 	}
 
-	ident := &ast.Identifier{
-		Token: p.curToken,
-		Value: p.curToken.Literal,
-	}
+	ident := p.parseDeclaration()
 
 	if !p.expectPeek(token.ASSIGN) {
 		return nil
@@ -189,6 +219,7 @@ func (p *Parser) parseBlockStatement() *ast.BlockStatement {
 		}
 		p.nextToken()
 	}
+	block.End = p.curToken
 
 	return block
 }
@@ -200,8 +231,7 @@ func (p *Parser) parseIdentifier() ast.Expression {
 func (p *Parser) parseIntegerLiteral() ast.Expression {
 	n, err := strconv.ParseInt(p.curToken.Literal, 10, 64)
 	if err != nil {
-		err := fmt.Sprintf("expected token to be int, got %s", p.curToken.Literal)
-		p.errors = append(p.errors, err)
+		p.errorAt(p.curToken, fmt.Sprintf("expected token to be int, got %s", p.curToken.Literal))
 		return nil
 	}
 	return &ast.IntegerLiteral{Token: p.curToken, Value: n}
@@ -211,6 +241,36 @@ func (p *Parser) parseStringLiteral() ast.Expression {
 	return &ast.StringLiteral{Token: p.curToken, Value: p.curToken.Literal}
 }
 
+func (p *Parser) parseInterpolatedString() ast.Expression {
+	str := &ast.InterpolatedString{Token: p.curToken, Parts: make([]ast.Expression, 0)}
+	str.Parts = p.appendTemplateText(str.Parts)
+
+	for {
+		p.nextToken()
+		str.Parts = append(str.Parts, p.parseExpression(LOWEST))
+
+		if p.peekTokenIs(token.TEMPLATE_MIDDLE) {
+			p.nextToken()
+			str.Parts = p.appendTemplateText(str.Parts)
+			continue
+		}
+
+		if !p.expectPeek(token.TEMPLATE_END) {
+			return nil
+		}
+		str.Parts = p.appendTemplateText(str.Parts)
+		return str
+	}
+}
+
+// appendTemplateText adds the text of the current template token to parts, skipping it if empty.
+func (p *Parser) appendTemplateText(parts []ast.Expression) []ast.Expression {
+	if p.curToken.Literal == "" {
+		return parts
+	}
+	return append(parts, &ast.TemplateText{Token: p.curToken, Value: p.curToken.Literal})
+}
+
 func (p *Parser) parseBoolean() ast.Expression {
 	return &ast.Boolean{Token: p.curToken, Value: p.curTokenIs(token.TRUE)}
 }
@@ -262,6 +322,12 @@ func (p *Parser) parseFunctionLiteral() ast.Expression {
 
 	lit.Parameters = p.parseFunctionParameters()
 
+	if p.peekTokenIs(token.COLON) {
+		p.nextToken()
+		p.nextToken()
+		lit.ReturnType = p.parseTypeAnnotation()
+	}
+
 	if !p.expectPeek(token.LBRACE) {
 		return nil
 	}
@@ -319,18 +385,12 @@ func (p *Parser) parseFunctionParameters() []*ast.Identifier {
 	}
 
 	p.nextToken()
-	out = append(out, &ast.Identifier{
-		Token: p.curToken,
-		Value: p.curToken.Literal,
-	})
+	out = append(out, p.parseDeclaration())
 
 	for p.peekTokenIs(token.COMMA) {
 		p.nextToken()
 		p.nextToken()
-		out = append(out, &ast.Identifier{
-			Token: p.curToken,
-			Value: p.curToken.Literal,
-		})
+		out = append(out, p.parseDeclaration())
 	}
 
 	if !p.expectPeek(token.RPAREN) {
@@ -340,6 +400,82 @@ func (p *Parser) parseFunctionParameters() []*ast.Identifier {
 	return out
 }
 
+// parseDeclaration parses the identifier at the current position, along with its optional type annotation.
+func (p *Parser) parseDeclaration() *ast.Identifier {
+	ident := &ast.Identifier{
+		Token: p.curToken,
+		Value: p.curToken.Literal,
+	}
+	if p.peekTokenIs(token.COLON) {
+		p.nextToken()
+		p.nextToken()
+		ident.Type = p.parseTypeAnnotation()
+	}
+	return ident
+}
+
+// parseTypeAnnotation parses a type starting at the current position:
+// a name (`int`), an array (`[int]`), a hash (`{string: int}`) or a function (`fn(int, int): bool`).
+func (p *Parser) parseTypeAnnotation() *ast.TypeAnnotation {
+	ta := &ast.TypeAnnotation{Token: p.curToken}
+
+	switch p.curToken.Type {
+	case token.IDENT:
+		ta.Name = p.curToken.Literal
+	case token.LBRACKET:
+		ta.Name = "[]"
+		p.nextToken()
+		ta.Params = []*ast.TypeAnnotation{p.parseTypeAnnotation()}
+		if !p.expectPeek(token.RBRACKET) {
+			return nil
+		}
+	case token.LBRACE:
+		ta.Name = "{}"
+		p.nextToken()
+		key := p.parseTypeAnnotation()
+		if !p.expectPeek(token.COLON) {
+			return nil
+		}
+		p.nextToken()
+		ta.Params = []*ast.TypeAnnotation{key, p.parseTypeAnnotation()}
+		if !p.expectPeek(token.RBRACE) {
+			return nil
+		}
+	case token.FUNCTION:
+		ta.Name = "fn"
+		if !p.expectPeek(token.LPAREN) {
+			return nil
+		}
+		ta.Params = make([]*ast.TypeAnnotation, 0)
+		for !p.peekTokenIs(token.RPAREN) {
+			p.nextToken()
+			ta.Params = append(ta.Params, p.parseTypeAnnotation())
+			if !p.peekTokenIs(token.RPAREN) && !p.expectPeek(token.COMMA) {
+				return nil
+			}
+		}
+		p.nextToken()
+		if !p.expectPeek(token.COLON) {
+			return nil
+		}
+		p.nextToken()
+		ta.Result = p.parseTypeAnnotation()
+	default:
+		p.errorAt(p.curToken, fmt.Sprintf("expected a type, got %s", p.curToken.Type))
+		return nil
+	}
+
+	for _, param := range ta.Params {
+		if param == nil {
+			return nil
+		}
+	}
+	if ta.Name == "fn" && ta.Result == nil {
+		return nil
+	}
+	return ta
+}
+
 func (p *Parser) parseArrayLiteral() ast.Expression {
 	call := &ast.ArrayLiteral{
 		Token: p.curToken,
@@ -356,7 +492,7 @@ func (p *Parser) parseArrayLiteral() ast.Expression {
 }
 
 func (p *Parser) parseHashLiteral() ast.Expression {
-	hash := &ast.HashLiteral{Token: p.curToken, Items: make(map[ast.Expression]ast.Expression)}
+	hash := &ast.HashLiteral{Token: p.curToken, Items: make([]ast.HashItem, 0)}
 	if p.peekTokenIs(token.RBRACE) {
 		p.nextToken()
 		return hash
@@ -373,7 +509,7 @@ func (p *Parser) parseHashLiteral() ast.Expression {
 		p.nextToken()
 
 		valExpr := p.parseExpression(LOWEST)
-		hash.Items[keyExpr] = valExpr
+		hash.Items = append(hash.Items, ast.HashItem{Key: keyExpr, Value: valExpr})
 
 		if !p.peekTokenIs(token.COMMA) {
 			break
@@ -393,15 +529,33 @@ func (p *Parser) parseHashLiteral() ast.Expression {
 	return hash
 }
 
+// parseIndexExpression parses both index expressions `left[ix]` and slice expressions `left[start:end]`.
 func (p *Parser) parseIndexExpression(left ast.Expression) ast.Expression {
-	expr := &ast.IndexExpression{Token: p.curToken, Left: left}
-	p.nextToken()
-	expr.Index = p.parseExpression(LOWEST)
+	tkn := p.curToken
+
+	var start ast.Expression
+	if !p.peekTokenIs(token.COLON) {
+		p.nextToken()
+		start = p.parseExpression(LOWEST)
+	}
+
+	if !p.peekTokenIs(token.COLON) {
+		if !p.expectPeek(token.RBRACKET) {
+			return nil // TODO: should error
+		}
+		return &ast.IndexExpression{Token: tkn, Left: left, Index: start}
+	}
+
+	p.nextToken() // skip to ':'
+	slice := &ast.SliceExpression{Token: tkn, Left: left, Start: start}
+	if !p.peekTokenIs(token.RBRACKET) {
+		p.nextToken()
+		slice.End = p.parseExpression(LOWEST)
+	}
 	if !p.expectPeek(token.RBRACKET) {
 		return nil // TODO: should error
 	}
-	return expr
-
+	return slice
 }
 
 func (p *Parser) parsePrefixExpression() ast.Expression {
@@ -483,10 +637,10 @@ func (p *Parser) expectPeek(t token.TokenType) bool {
 
 func (p *Parser) peekError(t token.TokenType) {
 	err := fmt.Sprintf("expected next token to be %s, got %s", t, p.peekToken.Type)
-	p.errors = append(p.errors, err)
+	p.errorAt(p.peekToken, err)
 }
 
 func (p *Parser) noPrefixParseFnError(t token.TokenType) {
 	msg := fmt.Sprintf("no prefix parse function for %s found", t)
-	p.errors = append(p.errors, msg)
+	p.errorAt(p.curToken, msg)
 }
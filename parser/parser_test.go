@@ -175,6 +175,59 @@ func TestStringLiteralExpression(t *testing.T) {
 	}
 }
 
+func TestInterpolatedStringExpression(t *testing.T) {
+	input := `"Hello ${name}, you are ${age + 1}!"`
+
+	l := lexer.NewLexer(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	str, ok := stmt.Expression.(*ast.InterpolatedString)
+	if !ok {
+		t.Fatalf("exp not *ast.InterpolatedString. got=%T", stmt.Expression)
+	}
+
+	if len(str.Parts) != 5 {
+		t.Fatalf("wrong number of parts. got=%d", len(str.Parts))
+	}
+	testTemplateText(t, str.Parts[0], "Hello ")
+	testIdentifier(t, str.Parts[1], "name")
+	testTemplateText(t, str.Parts[2], ", you are ")
+	testInfixExpression(t, str.Parts[3], "age", "+", 1)
+	testTemplateText(t, str.Parts[4], "!")
+
+	if str.String() != `"Hello ${name}, you are ${(age + 1)}!"` {
+		t.Errorf("str.String() wrong. got=%q", str.String())
+	}
+}
+
+func TestInterpolatedStringLiteralPart(t *testing.T) {
+	input := `"${"$"}{x}"`
+
+	l := lexer.NewLexer(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	str, ok := stmt.Expression.(*ast.InterpolatedString)
+	if !ok {
+		t.Fatalf("exp not *ast.InterpolatedString. got=%T", stmt.Expression)
+	}
+
+	if len(str.Parts) != 2 {
+		t.Fatalf("wrong number of parts. got=%d", len(str.Parts))
+	}
+	testStringLiteral(t, str.Parts[0], "$")
+	testTemplateText(t, str.Parts[1], "{x}")
+
+	if str.String() != input {
+		t.Errorf("str.String() wrong. got=%q", str.String())
+	}
+}
+
 func TestParsingPrefixExpressions(t *testing.T) {
 	prefixTests := []struct {
 		input    string
@@ -723,6 +776,36 @@ func TestParsingIndexExpressions(t *testing.T) {
 	}
 }
 
+func TestParsingSliceExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"myArray[1:2]", "(myArray[1:2])"},
+		{"myArray[:2]", "(myArray[:2])"},
+		{"myArray[1:]", "(myArray[1:])"},
+		{"myArray[:]", "(myArray[:])"},
+		{"myArray[a + 1:-1]", "(myArray[(a + 1):(-1)])"},
+		{"a[1:][0]", "((a[1:])[0])"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.NewLexer(tt.input)
+		p := New(l)
+
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
+		if !ok {
+			t.Fatalf("program.Statements[0] not *ast.ExpressionStatement. got=%T", program.Statements[0])
+		}
+		if stmt.Expression.String() != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, stmt.Expression.String())
+		}
+	}
+}
+
 func TestParsingHashLiteralsStringKeys(t *testing.T) {
 	input := `{"one": 1, "two": 2, "three": 3}`
 
@@ -745,7 +828,8 @@ func TestParsingHashLiteralsStringKeys(t *testing.T) {
 		"two":   2,
 		"three": 3,
 	}
-	for key, value := range hash.Items {
+	for _, item := range hash.Items {
+		key, value := item.Key, item.Value
 		literal, ok := key.(*ast.StringLiteral)
 		if !ok {
 			t.Errorf("key is not ast.StringLiteral. got=%T", key)
@@ -776,7 +860,8 @@ func TestParsingHashLiteralsBoolKeys(t *testing.T) {
 		true:  1,
 		false: 2,
 	}
-	for key, value := range hash.Items {
+	for _, item := range hash.Items {
+		key, value := item.Key, item.Value
 		literal, ok := key.(*ast.Boolean)
 		if !ok {
 			t.Errorf("key is not ast.BooleanLiteral. got=%T", key)
@@ -807,7 +892,8 @@ func TestParsingHashLiteralsIntegerKeys(t *testing.T) {
 		1: 1,
 		2: 2,
 	}
-	for key, value := range hash.Items {
+	for _, item := range hash.Items {
+		key, value := item.Key, item.Value
 		literal, ok := key.(*ast.IntegerLiteral)
 		if !ok {
 			t.Errorf("key is not ast.BooleanLiteral. got=%T", key)
@@ -817,6 +903,30 @@ func TestParsingHashLiteralsIntegerKeys(t *testing.T) {
 	}
 }
 
+func TestParsingHashLiteralsKeepOrder(t *testing.T) {
+	input := `{"c": 1, "a": 2, "b": 3 + 4}`
+
+	l := lexer.NewLexer(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	hash, ok := stmt.Expression.(*ast.HashLiteral)
+	if !ok {
+		t.Fatalf("exp is not ast.HashLiteral. got=%T", stmt.Expression)
+	}
+
+	for ix, key := range []string{"c", "a", "b"} {
+		testStringLiteral(t, hash.Items[ix].Key, key)
+	}
+
+	expected := `{"c": 1, "a": 2, "b": (3 + 4)}`
+	if hash.String() != expected {
+		t.Errorf("hash.String() wrong. expected=%q, got=%q", expected, hash.String())
+	}
+}
+
 func TestParsingEmptyHashLiteral(t *testing.T) {
 	input := "{}"
 
@@ -864,7 +974,8 @@ func TestParsingHashLiteralsWithExpressions(t *testing.T) {
 		},
 	}
 
-	for key, value := range hash.Items {
+	for _, item := range hash.Items {
+		key, value := item.Key, item.Value
 		literal, ok := key.(*ast.StringLiteral)
 		if !ok {
 			t.Errorf("key is not ast.StringLiteral. got=%T", key)
@@ -879,6 +990,75 @@ func TestParsingHashLiteralsWithExpressions(t *testing.T) {
 	}
 }
 
+func TestParsingTypeAnnotations(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let x: int = 5;", "let x: int = 5;"},
+		{"let x = 5;", "let x = 5;"},
+		{"let xs: [string] = [];", "let xs: [string] = [];"},
+		{"let h: {string: [int]} = {};", "let h: {string: [int]} = {};"},
+		{"let f: fn(int, bool): string = g;", "let f: fn(int, bool): string = g;"},
+		{"let f: fn(): null = g;", "let f: fn(): null = g;"},
+		{"fn(a: int, b) { a }", "fn(a: int,b) { a; }"},
+		{"fn(a: int, b: string): bool { true }", "fn(a: int,b: string): bool { true; }"},
+		{"fn(f: fn(int): int): [int] { [] }", "fn(f: fn(int): int): [int] { []; }"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.NewLexer(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if program.String() != tt.expected {
+			t.Errorf("program.String() wrong. expected=%q, got=%q", tt.expected, program.String())
+		}
+	}
+}
+
+func TestParsingInvalidTypeAnnotations(t *testing.T) {
+	tests := []string{
+		"let x: = 5;",
+		"let x: [int = 5;",
+		"let x: {string} = 5;",
+		"fn(a: fn(int)) { a }",
+	}
+
+	for _, input := range tests {
+		p := New(lexer.NewLexer(input))
+		p.ParseProgram()
+		if len(p.Errors()) == 0 {
+			t.Errorf("expected parser errors for %q", input)
+		}
+	}
+}
+
+func TestSyntaxErrorPositions(t *testing.T) {
+	input := `let x = 1;
+let = 2;
+let y 3;`
+
+	p := New(lexer.NewLexer(input))
+	p.ParseProgram()
+
+	expected := []string{
+		"2:5: expected next token to be IDENT, got =",
+		"2:5: no prefix parse function for = found",
+		"3:7: expected next token to be =, got INT",
+	}
+	errs := p.SyntaxErrors()
+	if len(errs) != len(expected) {
+		t.Fatalf("wrong number of errors. expected=%d, got=%d (%v)", len(expected), len(errs), errs)
+	}
+	for ix, err := range errs {
+		if err.Error() != expected[ix] {
+			t.Errorf("wrong error %d. expected=%q, got=%q", ix, expected[ix], err.Error())
+		}
+	}
+}
+
 func testStringLiteral(t *testing.T, e ast.Expression, exp string) bool {
 	literal, ok := e.(*ast.StringLiteral)
 	if !ok {
@@ -894,6 +1074,21 @@ func testStringLiteral(t *testing.T, e ast.Expression, exp string) bool {
 	return true
 }
 
+func testTemplateText(t *testing.T, e ast.Expression, exp string) bool {
+	text, ok := e.(*ast.TemplateText)
+	if !ok {
+		t.Fatalf("expression not *ast.TemplateText. got=%T", e)
+		return false
+	}
+
+	if text.Value != exp {
+		t.Errorf("text.Value not %q. got=%q", exp, text.Value)
+		return false
+	}
+
+	return true
+}
+
 func testLetStatement(t *testing.T, s ast.Statement, name string) bool {
 	if s.TokenLiteral() != "let" {
 		t.Errorf("s.TokenLiteral not 'let'. got=%q", s.TokenLiteral())
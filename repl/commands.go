@@ -0,0 +1,153 @@
+package repl
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/manuelpepe/interpreter/ast"
+	"github.com/manuelpepe/interpreter/eval"
+	"github.com/manuelpepe/interpreter/lexer"
+	"github.com/manuelpepe/interpreter/object"
+	"github.com/manuelpepe/interpreter/parser"
+	"github.com/manuelpepe/interpreter/token"
+)
+
+type command struct {
+	usage string
+	help  string
+	run   func(s *session, arg string)
+}
+
+// commands are run by typing `:name [arg]` in the REPL.
+var commands map[string]command
+
+func init() {
+	// initialized here as :help refers to the map
+	commands = map[string]command{
+		"env":    {":env", "list the bindings of the session", (*session).showEnv},
+		"ast":    {":ast <expr>", "print the syntax tree of an expression", (*session).showAST},
+		"tokens": {":tokens <expr>", "print the tokens of an expression", (*session).showTokens},
+		"load":   {":load <file>", "run a file in the session", (*session).loadFile},
+		"save":   {":save <file>", "save the inputs of the session as a script", (*session).saveHistory},
+		"reset":  {":reset", "start over with an empty session", (*session).reset},
+		"time":   {":time <expr>", "run an expression and print how long it took", (*session).timeInput},
+		"help":   {":help [builtin]", "print this help, or the documentation of a builtin", (*session).help},
+	}
+}
+
+func (s *session) command(input string) {
+	name, arg, _ := strings.Cut(strings.TrimPrefix(input, ":"), " ")
+	cmd, ok := commands[name]
+	if !ok {
+		fmt.Fprintf(s.out, "unknown command :%s, try :help\n", name)
+		return
+	}
+	cmd.run(s, strings.TrimSpace(arg))
+}
+
+func (s *session) help(arg string) {
+	if arg != "" {
+		text, ok := eval.BuiltinHelp(arg)
+		if !ok {
+			fmt.Fprintf(s.out, "unknown builtin %s\n", arg)
+			return
+		}
+		fmt.Fprintln(s.out, text)
+		return
+	}
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(s.out, "  %-16s %s\n", commands[name].usage, commands[name].help)
+	}
+}
+
+func (s *session) showEnv(arg string) {
+	for _, b := range s.env.Bindings() {
+		value := object.Repr(b.Value)
+		if fn, ok := b.Value.(*object.Function); ok {
+			value = fn.Signature()
+		}
+		fmt.Fprintf(s.out, "%s = %s\n", b.Name, value)
+	}
+}
+
+// parse parses the argument of a command, printing the errors if it's not valid.
+func (s *session) parse(src string) (*ast.Program, bool) {
+	p := parser.New(lexer.NewLexer(src))
+	prog := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		PrintParserErrors(s.out, p.Errors())
+		return nil, false
+	}
+	return prog, true
+}
+
+func (s *session) showAST(arg string) {
+	prog, ok := s.parse(arg)
+	if !ok {
+		return
+	}
+	var print func(node ast.Node, depth int)
+	print = func(node ast.Node, depth int) {
+		name := strings.TrimPrefix(reflect.TypeOf(node).String(), "*ast.")
+		fmt.Fprintf(s.out, "%s%s %s\n", strings.Repeat("  ", depth), name, node.String())
+		for _, child := range node.ChildNodes() {
+			print(child, depth+1)
+		}
+	}
+	print(prog, 0)
+}
+
+func (s *session) showTokens(arg string) {
+	l := lexer.NewLexer(arg)
+	for tok := l.NextToken(); tok.Type != token.EOF; tok = l.NextToken() {
+		fmt.Fprintf(s.out, "%d:%d\t%s\t%q\n", tok.Line, tok.Column, tok.Type, tok.Literal)
+	}
+}
+
+func (s *session) loadFile(arg string) {
+	data, err := os.ReadFile(arg)
+	if err != nil {
+		fmt.Fprintf(s.out, "Error opening file: %v\n", err)
+		return
+	}
+	prog, ok := s.parse(string(data))
+	if !ok {
+		return
+	}
+	s.history = append(s.history, strings.TrimRight(string(data), "\n"))
+	s.print(eval.Eval(prog, s.env))
+}
+
+func (s *session) saveHistory(arg string) {
+	if arg == "" {
+		fmt.Fprintln(s.out, "usage: :save <file>")
+		return
+	}
+	script := strings.Join(s.history, "\n")
+	if script != "" {
+		script += "\n"
+	}
+	if err := os.WriteFile(arg, []byte(script), 0o644); err != nil {
+		fmt.Fprintf(s.out, "Error writing file: %v\n", err)
+	}
+}
+
+func (s *session) reset(arg string) {
+	s.env = object.NewEnvironment()
+	s.history = nil
+}
+
+func (s *session) timeInput(arg string) {
+	start := time.Now()
+	s.run(arg)
+	fmt.Fprintf(s.out, "took %s\n", time.Since(start))
+}
@@ -0,0 +1,441 @@
+package repl
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// HISTORY_FILE is the file in the home directory where the REPL keeps its history.
+const HISTORY_FILE = ".monkey_history"
+
+const maxHistory = 1000
+
+// errInterrupted is returned when the user cancels the line with ctrl-c.
+var errInterrupted = errors.New("interrupted")
+
+// lineReader reads the input line by line, showing a prompt if the input is interactive.
+type lineReader interface {
+	// readLine returns the next line, including its line break.
+	readLine(prompt string) (string, error)
+	// pending returns the input that is already available, eg. because it was pasted.
+	pending() string
+}
+
+// plainReader reads lines from input that isn't a terminal.
+type plainReader struct {
+	in  *bufio.Reader
+	out io.Writer
+}
+
+func (r *plainReader) readLine(prompt string) (string, error) {
+	io.WriteString(r.out, prompt)
+	return r.in.ReadString('\n')
+}
+
+func (r *plainReader) pending() string {
+	return pending(r.in)
+}
+
+func pending(in *bufio.Reader) string {
+	data, _ := in.Peek(in.Buffered())
+	return string(data)
+}
+
+// editor reads lines from a terminal, supporting cursor movement, history and completion.
+type editor struct {
+	in  *bufio.Reader
+	out io.Writer
+	fd  int // of the terminal, or -1 if it isn't one (for tests)
+
+	history      []string
+	historyFile  string // where new entries are appended, if any
+	historyLines int    // number of lines in the history file
+	complete     func(prefix string) []string
+	highlight    bool // whether to color the line
+
+	// line being edited
+	prompt string
+	line   []rune
+	pos    int
+}
+
+// newEditor returns an editor if in and out are both terminals.
+func newEditor(in io.Reader, out io.Writer, complete func(prefix string) []string) (*editor, bool) {
+	inFile, ok := in.(*os.File)
+	if !ok || !isTerminal(int(inFile.Fd())) {
+		return nil, false
+	}
+	if outFile, ok := out.(*os.File); !ok || !isTerminal(int(outFile.Fd())) {
+		return nil, false
+	}
+
+	e := &editor{in: bufio.NewReader(in), out: out, fd: int(inFile.Fd()), complete: complete}
+	if home, err := os.UserHomeDir(); err == nil {
+		e.historyFile = filepath.Join(home, HISTORY_FILE)
+		if data, err := os.ReadFile(e.historyFile); err == nil {
+			for _, line := range strings.Split(strings.TrimRight(string(data), "\n"), "\n") {
+				if line != "" {
+					e.history = append(e.history, line)
+				}
+			}
+			e.historyLines = len(e.history)
+			e.history = e.history[max(0, len(e.history)-maxHistory):]
+		}
+	}
+	return e, true
+}
+
+func (e *editor) pending() string {
+	return pending(e.in)
+}
+
+// addHistory adds a line to the history, saving it to the history file.
+func (e *editor) addHistory(line string) {
+	if strings.TrimSpace(line) == "" || len(e.history) > 0 && e.history[len(e.history)-1] == line {
+		return
+	}
+	e.history = append(e.history, line)
+	if len(e.history) > maxHistory {
+		e.history = e.history[1:]
+	}
+	if e.historyFile == "" {
+		return
+	}
+	// history is best effort, errors are ignored
+	if e.historyLines >= maxHistory {
+		e.rewriteHistory()
+		return
+	}
+	f, err := os.OpenFile(e.historyFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	if _, err := fmt.Fprintln(f, line); err == nil {
+		e.historyLines++
+	}
+}
+
+// rewriteHistory replaces the history file with the entries kept in memory, so it doesn't grow
+// forever. the new file is written aside and then renamed, to not lose the history on errors.
+func (e *editor) rewriteHistory() {
+	f, err := os.CreateTemp(filepath.Dir(e.historyFile), HISTORY_FILE+"-*")
+	if err != nil {
+		return
+	}
+	defer os.Remove(f.Name()) // fails once renamed
+	_, err = io.WriteString(f, strings.Join(e.history, "\n")+"\n")
+	if closeErr := f.Close(); err != nil || closeErr != nil {
+		return
+	}
+	if os.Rename(f.Name(), e.historyFile) == nil {
+		e.historyLines = len(e.history)
+	}
+}
+
+// keys that aren't plain characters, read from escape sequences
+const (
+	keyUp rune = -1 - iota
+	keyDown
+	keyLeft
+	keyRight
+	keyHome
+	keyEnd
+	keyDelete
+	keyUnknown
+)
+
+func ctrl(r rune) rune {
+	return r & 0x1f
+}
+
+func (e *editor) readKey() (rune, error) {
+	r, _, err := e.in.ReadRune()
+	if err != nil || r != 0x1b {
+		return r, err
+	}
+	// escape sequences are ESC [ params final or ESC O final
+	next, _, err := e.in.ReadRune()
+	if err != nil {
+		return 0, err
+	}
+	if next != '[' && next != 'O' {
+		return keyUnknown, nil
+	}
+	var params strings.Builder
+	for {
+		r, _, err := e.in.ReadRune()
+		if err != nil {
+			return 0, err
+		}
+		if r >= 0x40 && r <= 0x7e {
+			switch {
+			case r == 'A':
+				return keyUp, nil
+			case r == 'B':
+				return keyDown, nil
+			case r == 'C':
+				return keyRight, nil
+			case r == 'D':
+				return keyLeft, nil
+			case r == 'H', r == '~' && (params.String() == "1" || params.String() == "7"):
+				return keyHome, nil
+			case r == 'F', r == '~' && (params.String() == "4" || params.String() == "8"):
+				return keyEnd, nil
+			case r == '~' && params.String() == "3":
+				return keyDelete, nil
+			}
+			return keyUnknown, nil
+		}
+		params.WriteRune(r)
+	}
+}
+
+// readLine reads a line letting the user edit it.
+func (e *editor) readLine(prompt string) (string, error) {
+	if e.fd >= 0 {
+		restore, err := makeRaw(e.fd)
+		if err != nil {
+			return "", err
+		}
+		defer restore()
+	}
+
+	e.prompt, e.line, e.pos = prompt, nil, 0
+	index := len(e.history) // of the entry being shown, len(e.history) is the new line
+	var draft []rune        // the new line, while going through the history
+	e.refresh()
+
+	for {
+		r, err := e.readKey()
+		if err != nil {
+			return "", err
+		}
+
+		switch r {
+		case '\r', '\n':
+			line := string(e.line)
+			e.addHistory(line)
+			io.WriteString(e.out, "\r\n")
+			return line + "\n", nil
+		case ctrl('c'):
+			io.WriteString(e.out, "^C\r\n")
+			return "", errInterrupted
+		case ctrl('d'):
+			if len(e.line) == 0 {
+				io.WriteString(e.out, "\r\n")
+				return "", io.EOF
+			}
+			e.delete(e.pos, e.pos+1)
+		case keyDelete:
+			e.delete(e.pos, e.pos+1)
+		case 0x7f, ctrl('h'):
+			e.delete(e.pos-1, e.pos)
+		case keyLeft, ctrl('b'):
+			e.pos = max(e.pos-1, 0)
+		case keyRight, ctrl('f'):
+			e.pos = min(e.pos+1, len(e.line))
+		case keyHome, ctrl('a'):
+			e.pos = 0
+		case keyEnd, ctrl('e'):
+			e.pos = len(e.line)
+		case ctrl('k'):
+			e.delete(e.pos, len(e.line))
+		case ctrl('u'):
+			e.delete(0, e.pos)
+		case ctrl('w'):
+			start := e.pos
+			for start > 0 && unicode.IsSpace(e.line[start-1]) {
+				start--
+			}
+			for start > 0 && !unicode.IsSpace(e.line[start-1]) {
+				start--
+			}
+			e.delete(start, e.pos)
+		case ctrl('l'):
+			io.WriteString(e.out, "\x1b[H\x1b[2J")
+		case keyUp, ctrl('p'), keyDown, ctrl('n'):
+			next := index - 1
+			if r == keyDown || r == ctrl('n') {
+				next = index + 1
+			}
+			if next < 0 || next > len(e.history) {
+				break
+			}
+			if index == len(e.history) {
+				draft = e.line
+			}
+			index = next
+			if index == len(e.history) {
+				e.line = draft
+			} else {
+				e.line = []rune(e.history[index])
+			}
+			e.pos = len(e.line)
+		case ctrl('r'):
+			line, done, err := e.search()
+			if err != nil {
+				return "", err
+			}
+			e.line, e.pos = []rune(line), len([]rune(line))
+			if done {
+				e.addHistory(line)
+				io.WriteString(e.out, "\r\n")
+				return line + "\n", nil
+			}
+		case '\t':
+			e.completeWord()
+		default:
+			if r >= ' ' {
+				e.insert(r)
+			}
+		}
+		e.refresh()
+	}
+}
+
+func (e *editor) insert(r rune) {
+	e.line = append(e.line[:e.pos], append([]rune{r}, e.line[e.pos:]...)...)
+	e.pos++
+}
+
+// delete removes the characters in [from, to) of the line.
+func (e *editor) delete(from int, to int) {
+	from, to = max(from, 0), min(to, len(e.line))
+	if from >= to {
+		return
+	}
+	e.line = append(e.line[:from:from], e.line[to:]...)
+	if e.pos > to {
+		e.pos -= to - from
+	} else if e.pos > from {
+		e.pos = from
+	}
+}
+
+// refresh redraws the line, leaving the cursor on its position.
+func (e *editor) refresh() {
+	line := string(e.line)
+	if e.highlight {
+		line = highlight(line)
+	}
+	fmt.Fprintf(e.out, "\r%s%s\x1b[K\r", e.prompt, line)
+	if n := len([]rune(e.prompt)) + e.pos; n > 0 {
+		fmt.Fprintf(e.out, "\x1b[%dC", n)
+	}
+}
+
+// search lets the user find a line of the history containing the typed text, returning it when
+// the search ends. enter accepts the line, reporting true, ctrl-c and ctrl-g cancel the search
+// and any other key ends it leaving the found line to be edited.
+func (e *editor) search() (string, bool, error) {
+	var query []rune
+	match := len(e.history)
+	original := string(e.line)
+	found := original
+
+	find := func(from int) {
+		for ix := min(from, len(e.history)-1); ix >= 0; ix-- {
+			if strings.Contains(e.history[ix], string(query)) {
+				match, found = ix, e.history[ix]
+				return
+			}
+		}
+	}
+
+	for {
+		fmt.Fprintf(e.out, "\r(reverse-i-search)`%s': %s\x1b[K", string(query), found)
+		r, err := e.readKey()
+		if err != nil {
+			return "", false, err
+		}
+		switch r {
+		case ctrl('r'):
+			find(match - 1)
+		case 0x7f, ctrl('h'):
+			if len(query) > 0 {
+				query = query[:len(query)-1]
+				find(len(e.history) - 1)
+			}
+		case '\r', '\n':
+			return found, true, nil
+		case ctrl('c'), ctrl('g'):
+			return original, false, nil
+		default:
+			if r < ' ' {
+				return found, false, nil
+			}
+			query = append(query, r)
+			find(match)
+		}
+	}
+}
+
+// completeWord completes the identifier before the cursor. if there are many candidates it
+// completes their common prefix, or lists them if there is none.
+func (e *editor) completeWord() {
+	if e.complete == nil {
+		return
+	}
+	start := e.pos
+	for start > 0 && (isIdentRune(e.line[start-1])) {
+		start--
+	}
+	if start == 1 && e.line[0] == ':' {
+		start = 0 // commands
+	}
+	prefix := string(e.line[start:e.pos])
+
+	candidates := e.complete(prefix)
+	if len(candidates) == 0 {
+		return
+	}
+	common := []rune(candidates[0])
+	for _, c := range candidates[1:] {
+		n := 0
+		for _, r := range c {
+			if n == len(common) || common[n] != r {
+				break
+			}
+			n++
+		}
+		common = common[:n]
+	}
+	if len(candidates) == 1 {
+		common = append(common, ' ')
+	}
+	if string(common) != prefix {
+		for _, r := range common[len([]rune(prefix)):] {
+			e.insert(r)
+		}
+		return
+	}
+	io.WriteString(e.out, "\r\n"+strings.Join(candidates, "  ")+"\r\n")
+}
+
+func isIdentRune(r rune) bool {
+	return unicode.IsLetter(r) || r == '_' || unicode.IsDigit(r)
+}
+
+// completions returns the names of all the groups starting with prefix, sorted and without duplicates.
+func completions(prefix string, names ...[]string) []string {
+	seen := make(map[string]bool)
+	var out []string
+	for _, group := range names {
+		for _, name := range group {
+			if strings.HasPrefix(name, prefix) && !seen[name] {
+				seen[name] = true
+				out = append(out, name)
+			}
+		}
+	}
+	sort.Strings(out)
+	return out
+}
@@ -0,0 +1,77 @@
+package repl
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/manuelpepe/interpreter/eval"
+	"github.com/manuelpepe/interpreter/lexer"
+	"github.com/manuelpepe/interpreter/token"
+)
+
+// highlight colors the tokens and comments of a line of input.
+func highlight(src string) string {
+	type span struct {
+		start, end int
+		color      string
+	}
+	var spans []span
+
+	l := lexer.NewLexer(src)
+	for tok := l.NextToken(); tok.Type != token.EOF; tok = l.NextToken() {
+		if color := tokenColor(tok); color != "" {
+			start := offset(src, tok)
+			spans = append(spans, span{start, min(start+tokenLen(tok), len(src)), color})
+		}
+	}
+	for _, comment := range l.Comments() {
+		start := offset(src, comment)
+		spans = append(spans, span{start, start + len(comment.Literal), colorGray})
+	}
+	sort.Slice(spans, func(i, j int) bool { return spans[i].start < spans[j].start })
+
+	var out strings.Builder
+	pos := 0
+	for _, s := range spans {
+		if s.start < pos {
+			continue
+		}
+		out.WriteString(src[pos:s.start])
+		out.WriteString(paint(true, s.color, src[s.start:s.end]))
+		pos = s.end
+	}
+	out.WriteString(src[pos:])
+	return out.String()
+}
+
+func tokenColor(tok token.Token) string {
+	switch tok.Type {
+	case token.INT:
+		return colorYellow
+	case token.STRING, token.TEMPLATE_START, token.TEMPLATE_MIDDLE, token.TEMPLATE_END:
+		return colorGreen
+	case token.TRUE, token.FALSE:
+		return colorMagenta
+	case token.FUNCTION, token.LET, token.IF, token.ELSE, token.RETURN:
+		return colorBlue
+	case token.IDENT:
+		if eval.IsBuiltin(tok.Literal) {
+			return colorCyan
+		}
+	case token.ILLEGAL:
+		return colorRed
+	}
+	return ""
+}
+
+// tokenLen returns the length in bytes of a token in the source, which for strings includes
+// the quotes and the delimiters of interpolations around the literal.
+func tokenLen(tok token.Token) int {
+	switch tok.Type {
+	case token.STRING, token.TEMPLATE_END:
+		return len(tok.Literal) + 2 // opening quote or brace and closing quote
+	case token.TEMPLATE_START, token.TEMPLATE_MIDDLE:
+		return len(tok.Literal) + 3 // opening quote or brace and ${
+	}
+	return len(tok.Literal)
+}
@@ -0,0 +1,81 @@
+package repl
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"github.com/manuelpepe/interpreter/lexer"
+	"github.com/manuelpepe/interpreter/token"
+)
+
+// continuations are tokens that can't end a statement, so input ending with them goes on
+// in the next line.
+var continuations = map[token.TokenType]bool{
+	token.ASSIGN:   true,
+	token.PLUS:     true,
+	token.MINUS:    true,
+	token.BANG:     true,
+	token.ASTERISK: true,
+	token.SLASH:    true,
+	token.EQ:       true,
+	token.NOT_EQ:   true,
+	token.LT:       true,
+	token.GT:       true,
+	token.COMMA:    true,
+	token.COLON:    true,
+	token.FUNCTION: true,
+	token.LET:      true,
+	token.IF:       true,
+	token.ELSE:     true,
+}
+
+// incomplete reports whether src needs more lines before it can be parsed: it has unbalanced
+// brackets, an unterminated string or ends with an operator.
+func incomplete(src string) bool {
+	l := lexer.NewLexer(src)
+	depth, templates := 0, 0
+	var last token.Token
+	for tok := l.NextToken(); tok.Type != token.EOF; tok = l.NextToken() {
+		switch tok.Type {
+		case token.LPAREN, token.LBRACE, token.LBRACKET:
+			depth++
+		case token.RPAREN, token.RBRACE, token.RBRACKET:
+			depth--
+		case token.TEMPLATE_START:
+			templates++
+		case token.TEMPLATE_END:
+			templates--
+		}
+		last = tok
+	}
+
+	if templates > 0 {
+		return true // inside an interpolation
+	}
+	switch last.Type {
+	case token.STRING, token.TEMPLATE_END:
+		// strings only end without a closing quote at the end of the input
+		end := offset(src, last) + 1 + len(last.Literal)
+		if end >= len(src) || src[end] != '"' {
+			return true
+		}
+	}
+	return depth > 0 || continuations[last.Type]
+}
+
+// offset returns the position in bytes of a token in src.
+func offset(src string, tok token.Token) int {
+	pos := 0
+	for line := 1; line < tok.Line; line++ {
+		next := strings.IndexByte(src[pos:], '\n')
+		if next < 0 {
+			return len(src)
+		}
+		pos += next + 1
+	}
+	for col := 1; col < tok.Column && pos < len(src); col++ {
+		_, size := utf8.DecodeRuneInString(src[pos:])
+		pos += size
+	}
+	return pos
+}
@@ -0,0 +1,138 @@
+package repl
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/manuelpepe/interpreter/object"
+)
+
+const (
+	// maxWidth is the width after which arrays and hashes are split in many lines.
+	maxWidth = 80
+	// maxItems is the number of items shown of big arrays and hashes.
+	maxItems = 100
+	// indentation of the items of multi-line arrays and hashes.
+	indentation = "  "
+)
+
+// ANSI colours
+const (
+	colorReset   = "\x1b[0m"
+	colorRed     = "\x1b[31m"
+	colorGreen   = "\x1b[32m"
+	colorYellow  = "\x1b[33m"
+	colorBlue    = "\x1b[34m"
+	colorMagenta = "\x1b[35m"
+	colorCyan    = "\x1b[36m"
+	colorGray    = "\x1b[90m"
+)
+
+// pretty formats a result of the REPL, showing arrays and hashes that don't fit in a line with
+// one item per line, and coloring values if color is set.
+func pretty(obj object.Object, color bool) string {
+	p := &printer{color: color}
+	p.print(obj, 0)
+	return p.out.String()
+}
+
+type printer struct {
+	out   strings.Builder
+	color bool
+}
+
+func (p *printer) print(obj object.Object, depth int) {
+	line := flat(obj, false)
+	if len(indentation)*depth+utf8.RuneCountInString(line) <= maxWidth || !isCollection(obj) {
+		p.out.WriteString(flat(obj, p.color))
+		return
+	}
+
+	inner := strings.Repeat(indentation, depth+1)
+	switch obj := obj.(type) {
+	case *object.Array:
+		p.out.WriteString("[\n")
+		for _, item := range obj.Items()[:min(obj.Len(), maxItems)] {
+			p.out.WriteString(inner)
+			p.print(item, depth+1)
+			p.out.WriteString(",\n")
+		}
+		p.more(obj.Len(), inner)
+		p.out.WriteString(strings.Repeat(indentation, depth) + "]")
+	case *object.Hash:
+		p.out.WriteString("{\n")
+		for _, pair := range obj.Items()[:min(obj.Len(), maxItems)] {
+			p.out.WriteString(inner + flat(pair.Key, p.color) + ": ")
+			p.print(pair.Value, depth+1)
+			p.out.WriteString(",\n")
+		}
+		p.more(obj.Len(), inner)
+		p.out.WriteString(strings.Repeat(indentation, depth) + "}")
+	}
+}
+
+// more prints how many items of a multi-line collection of length n were left out, if any.
+func (p *printer) more(n int, indent string) {
+	if n > maxItems {
+		p.out.WriteString(indent + paint(p.color, colorGray, fmt.Sprintf("... (%d more)", n-maxItems)) + "\n")
+	}
+}
+
+// flat formats an object in a single line, leaving out the items of big arrays and hashes.
+func flat(obj object.Object, color bool) string {
+	more := func(n int) string {
+		if n <= maxItems {
+			return ""
+		}
+		return paint(color, colorGray, fmt.Sprintf(", ... (%d more)", n-maxItems))
+	}
+
+	switch obj := obj.(type) {
+	case *object.Array:
+		items := []string{}
+		for _, item := range obj.Items()[:min(obj.Len(), maxItems)] {
+			items = append(items, flat(item, color))
+		}
+		return "[" + strings.Join(items, ", ") + more(obj.Len()) + "]"
+	case *object.Hash:
+		pairs := []string{}
+		for _, pair := range obj.Items()[:min(obj.Len(), maxItems)] {
+			pairs = append(pairs, flat(pair.Key, color)+": "+flat(pair.Value, color))
+		}
+		return "{" + strings.Join(pairs, ", ") + more(obj.Len()) + "}"
+	}
+	return paint(color, colorOf(obj), object.Repr(obj))
+}
+
+func isCollection(obj object.Object) bool {
+	switch obj.(type) {
+	case *object.Array, *object.Hash:
+		return true
+	}
+	return false
+}
+
+// paint wraps text in the given color, if enabled.
+func paint(enabled bool, color string, text string) string {
+	if !enabled || color == "" {
+		return text
+	}
+	return color + text + colorReset
+}
+
+func colorOf(obj object.Object) string {
+	switch obj.(type) {
+	case *object.Integer:
+		return colorYellow
+	case *object.String:
+		return colorGreen
+	case *object.Boolean, *object.Null:
+		return colorMagenta
+	case *object.Function, *object.Builtin:
+		return colorCyan
+	case *object.Error:
+		return colorRed
+	}
+	return ""
+}
@@ -2,45 +2,145 @@ package repl
 
 import (
 	"bufio"
-	"fmt"
+	"errors"
 	"io"
+	"os"
+	"strings"
 
+	"github.com/manuelpepe/interpreter/ast"
 	"github.com/manuelpepe/interpreter/eval"
 	"github.com/manuelpepe/interpreter/lexer"
 	"github.com/manuelpepe/interpreter/object"
 	"github.com/manuelpepe/interpreter/parser"
+	"github.com/manuelpepe/interpreter/token"
 )
 
 const PROMPT = ">> "
 
+// CONTINUATION_PROMPT is shown while the input is incomplete, eg. inside a function body.
+const CONTINUATION_PROMPT = ".. "
+
+// session is the state of a running REPL.
+type session struct {
+	out     io.Writer
+	color   bool // whether to color the output
+	env     *object.Environment
+	history []string // inputs that were run, to be saved as a script
+}
+
+// Start runs a REPL. When in and out are a terminal lines can be edited, with history and
+// tab completion, otherwise they are read as they come. Line editing is supported on linux, macOS,
+// FreeBSD, NetBSD and DragonFly, other platforms always read lines as they come.
 func Start(in io.Reader, out io.Writer) {
-	scanner := bufio.NewScanner(in)
-	env := object.NewEnvironment()
+	s := &session{out: out, env: object.NewEnvironment()}
 
-	for {
-		fmt.Print(PROMPT)
+	var lines lineReader
+	if editor, ok := newEditor(in, out, s.completions); ok {
+		// colors are only used in terminals, unless disabled with NO_COLOR, see https://no-color.org
+		s.color = os.Getenv("NO_COLOR") == ""
+		editor.highlight = s.color
+		lines = editor
+	} else {
+		lines = &plainReader{in: bufio.NewReader(in), out: out}
+	}
 
-		scanned := scanner.Scan()
-		if !scanned {
+	for {
+		src, ok := readInput(lines)
+		if !ok {
 			return
 		}
+		if strings.TrimSpace(src) == "" {
+			continue
+		}
+		if strings.HasPrefix(strings.TrimSpace(src), ":") {
+			s.command(strings.TrimSpace(src))
+			continue
+		}
+		s.run(src)
+	}
+}
 
-		line := scanner.Text()
-		l := lexer.NewLexer(line)
-		p := parser.New(l)
+// run evaluates the input in the session, printing the result of each statement.
+func (s *session) run(src string) {
+	l := lexer.NewLexer(src)
+	p := parser.New(l)
 
-		prog := p.ParseProgram()
-		if len(p.Errors()) != 0 {
-			PrintParserErrors(out, p.Errors())
-			continue
+	prog := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		PrintParserErrors(s.out, p.Errors())
+		return
+	}
+	s.history = append(s.history, strings.TrimRight(src, "\n"))
+
+	// pasted input may hold many statements, which are run one by one as if they were
+	// typed in different lines.
+	for _, stmt := range prog.Statements {
+		res := eval.Eval(&ast.Program{Statements: []ast.Statement{stmt}}, s.env)
+		s.print(res)
+		if _, failed := res.(*object.Error); failed {
+			break
 		}
+	}
+}
+
+// print shows a result, if any.
+func (s *session) print(res object.Object) {
+	if res != nil {
+		io.WriteString(s.out, pretty(res, s.color))
+		io.WriteString(s.out, "\n")
+	}
+}
 
-		res := eval.Eval(prog, env)
-		if res != nil {
-			io.WriteString(out, res.Inspect())
-			io.WriteString(out, "\n")
+// readInput reads lines until they form complete statements. Lines that are already buffered
+// when the input is complete are read too, as they were probably pasted along with it.
+// two empty lines in a row end the input even if it's incomplete, and commands always take
+// a single line.
+func readInput(lines lineReader) (string, bool) {
+	var input strings.Builder
+	blank := 0
+	prompt := PROMPT
+	for {
+		line, err := lines.readLine(prompt)
+		if errors.Is(err, errInterrupted) {
+			return "", true
+		}
+		if err != nil && line == "" {
+			// an incomplete input is still run to report its errors
+			return input.String(), input.Len() != 0
 		}
+		if input.Len() == 0 && strings.HasPrefix(strings.TrimSpace(line), ":") {
+			return line, true
+		}
+		input.WriteString(line)
+
+		if strings.TrimSpace(line) == "" {
+			blank++
+		} else {
+			blank = 0
+		}
+		if blank == 2 || !incomplete(input.String()) {
+			next := lines.pending()
+			if next == "" || strings.HasPrefix(strings.TrimLeft(next, " \t\r\n"), ":") {
+				return input.String(), true
+			}
+		}
+		prompt = CONTINUATION_PROMPT
+		if lines.pending() != "" {
+			prompt = ""
+		}
+	}
+}
+
+// completions returns the names to complete in the session for the given prefix.
+func (s *session) completions(prefix string) []string {
+	var cmds, bindings []string
+	for name := range commands {
+		cmds = append(cmds, ":"+name)
+	}
+	for _, b := range s.env.Bindings() {
+		bindings = append(bindings, b.Name)
 	}
+	return completions(prefix, cmds, bindings, eval.BuiltinNames(), token.Keywords())
 }
 
 func PrintParserErrors(out io.Writer, errors []string) {
@@ -0,0 +1,346 @@
+package repl
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/manuelpepe/interpreter/object"
+)
+
+func TestIncomplete(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected bool
+	}{
+		{`let x = 5;`, false},
+		{`let f = fn(x) {`, true},
+		{"let f = fn(x) {\n x\n}", false},
+		{`add(1,`, true},
+		{`[1, 2`, true},
+		{`{"a": 1}`, false},
+		{`let x = 5 +`, true},
+		{`let x =`, true},
+		{`if (x) { 1 } else`, true},
+		{`"abc`, true},
+		{`"`, true},
+		{`"abc"`, false},
+		{`"abc" // "comment`, false},
+		{`"a ${b`, true},
+		{`"a ${b} c`, true},
+		{`"a ${b} c"`, false},
+		{`"a ${ {"k": 1}["k"] } c"`, false},
+		{`let x = 1; // comment {`, false},
+		{`)`, false},
+		{``, false},
+	}
+
+	for _, tt := range tests {
+		if got := incomplete(tt.input); got != tt.expected {
+			t.Errorf("incomplete(%q) = %v, want %v", tt.input, got, tt.expected)
+		}
+	}
+}
+
+// typing returns one line on each read, as a terminal does while the user types.
+type typing struct {
+	lines []string
+}
+
+func (t *typing) Read(p []byte) (int, error) {
+	if len(t.lines) == 0 {
+		return 0, io.EOF
+	}
+	n := copy(p, t.lines[0]+"\n")
+	t.lines = t.lines[1:]
+	return n, nil
+}
+
+func TestStartMultiLine(t *testing.T) {
+	input := &typing{[]string{
+		"let add = fn(a, b) {",
+		"    a + b",
+		"};",
+		"add(1, 2)",
+		"if (false) { 1 } else {",
+		"    2",
+		"}",
+		`"multi`,
+		`line"`,
+		"missing; 5",
+		"let x = [1,",
+		"",
+		"",
+		"",
+	}}
+
+	var out bytes.Buffer
+	Start(input, &out)
+
+	expected := "3\n2\n\"multi\\nline\"\nERROR: identifier not found: missing\n" +
+		"\tno prefix parse function for EOF found\n" +
+		"\texpected next token to be ], got EOF\n"
+	if withoutPrompts(out.String()) != expected {
+		t.Errorf("wrong output.\nexpected:\n%s\ngot:\n%s", expected, withoutPrompts(out.String()))
+	}
+}
+
+func TestStartPaste(t *testing.T) {
+	// all the lines are read at once, so they are run together
+	input := strings.Join([]string{
+		"let x = 2;",
+		"if (x > 1) { x }",
+		"else { 0 }",
+		"x * 10",
+	}, "\n")
+
+	var out bytes.Buffer
+	Start(strings.NewReader(input), &out)
+
+	if expected := "2\n20\n"; withoutPrompts(out.String()) != expected {
+		t.Errorf("wrong output.\nexpected:\n%s\ngot:\n%s", expected, withoutPrompts(out.String()))
+	}
+}
+
+func TestCommands(t *testing.T) {
+	dir := t.TempDir()
+	lib := filepath.Join(dir, "lib.monkey")
+	if err := os.WriteFile(lib, []byte("let double = fn(x) { x * 2 };\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	script := filepath.Join(dir, "session.monkey")
+
+	input := &typing{[]string{
+		"let a = 1;",
+		":load " + lib,
+		"double(a)",
+		":env",
+		":ast -a",
+		":tokens a + 1",
+		":time double(3)",
+		":save " + script,
+		":reset",
+		":env",
+		"a",
+		":nope",
+		":ast fn(",
+		":help trim",
+	}}
+	var out bytes.Buffer
+	Start(input, &out)
+
+	expected := []string{
+		"2",
+		"a = 1",
+		"double = fn(x)",
+		"Program (-a)",
+		"  ExpressionStatement (-a)",
+		"    PrefixExpression (-a)",
+		"      Identifier a",
+		"1:1\tIDENT\t\"a\"",
+		"1:3\t+\t\"+\"",
+		"1:5\tINT\t\"1\"",
+		"6",
+		"took ",
+		"ERROR: identifier not found: a",
+		"unknown command :nope, try :help",
+		"\texpected next token to be ), got EOF",
+		"\texpected next token to be {, got EOF",
+		"trim(s: STRING, cutset?: STRING): STRING",
+		"    removes leading and trailing whitespace, or the characters in cutset",
+	}
+	lines := strings.Split(strings.TrimRight(withoutPrompts(out.String()), "\n"), "\n")
+	if len(lines) < len(expected) {
+		t.Fatalf("wrong output:\n%s", withoutPrompts(out.String()))
+	}
+	for ix := range expected {
+		if !strings.HasPrefix(lines[ix], expected[ix]) {
+			t.Errorf("wrong output line %d. expected=%q, got=%q", ix, expected[ix], lines[ix])
+		}
+	}
+
+	saved, err := os.ReadFile(script)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "let a = 1;\nlet double = fn(x) { x * 2 };\ndouble(a)\ndouble(3)\n"; string(saved) != expected {
+		t.Errorf("wrong saved session.\nexpected:\n%s\ngot:\n%s", expected, saved)
+	}
+}
+
+func withoutPrompts(out string) string {
+	return strings.NewReplacer(PROMPT, "", CONTINUATION_PROMPT, "").Replace(out)
+}
+
+func TestEditor(t *testing.T) {
+	tests := []struct {
+		keys     string
+		history  []string
+		expected string
+	}{
+		{"abc\r", nil, "abc"},
+		{"abc\x1b[D\x1b[DX\r", nil, "aXbc"},
+		{"ñ\x1b[Dá\r", nil, "áñ"},
+		{"abcd\x7f\x7f\r", nil, "ab"},
+		{"bc\x01a\x05d\r", nil, "abcd"},
+		{"abc\x1b[H\x1b[3~\x1b[Fd\r", nil, "bcd"},
+		{"let x = foo\x17bar\r", nil, "let x = bar"},
+		{"abcdef\x02\x02\x0b\r", nil, "abcd"},
+		{"abcdef\x02\x02\x15\r", nil, "ef"},
+		{"\x1b[A\x1b[A\r", []string{"first", "second"}, "first"},
+		{"\x1b[A\x1b[A\x1b[B\r", []string{"first", "second"}, "second"},
+		{"dr\x1b[A\x1b[B\r", []string{"first"}, "dr"},
+		{"\x1b[A\x1b[A\x1b[A\r", []string{"first"}, "first"},
+		{"\x12let\r", []string{"let a = 1", "f(b)", "let c = 3"}, "let c = 3"},
+		{"\x12let\x12\r", []string{"let a = 1", "f(b)", "let c = 3"}, "let a = 1"},
+		{"\x12f(\x05!\r", []string{"let a = 1", "f(b)", "let c = 3"}, "f(b)!"},
+		{"x\x12f(\x07\r", []string{"f(b)"}, "x"},
+		{"le\tn\r", nil, "len"},
+		{"leng\t\r", nil, "length "},
+		{"x = pu\t\r", nil, "x = push "},
+		{":lo\t\r", nil, ":load "},
+		{"a\t\r", nil, "a"},
+		{"añ\t\r", nil, "añb "},
+	}
+
+	for _, tt := range tests {
+		var out bytes.Buffer
+		e := &editor{
+			in:      bufio.NewReader(strings.NewReader(tt.keys)),
+			out:     &out,
+			fd:      -1,
+			history: tt.history,
+			complete: func(prefix string) []string {
+				return completions(prefix, []string{"len", "length", "let", "push", ":load", ":help", "añb", "aób"})
+			},
+		}
+		line, err := e.readLine(PROMPT)
+		if err != nil {
+			t.Fatalf("readLine(%q) failed: %v", tt.keys, err)
+		}
+		if line != tt.expected+"\n" {
+			t.Errorf("readLine(%q) = %q, want %q", tt.keys, line, tt.expected+"\n")
+		}
+	}
+}
+
+func TestEditorInterrupts(t *testing.T) {
+	for keys, expected := range map[string]error{"\x04": io.EOF, "abc\x03": errInterrupted} {
+		e := &editor{in: bufio.NewReader(strings.NewReader(keys)), out: io.Discard, fd: -1}
+		if _, err := e.readLine(PROMPT); err != expected {
+			t.Errorf("readLine(%q) returned %v, want %v", keys, err, expected)
+		}
+	}
+}
+
+func TestEditorHistoryFile(t *testing.T) {
+	file := filepath.Join(t.TempDir(), HISTORY_FILE)
+	e := &editor{in: bufio.NewReader(strings.NewReader("let a = 1;\r\rlet a = 1;\rlet b = 2;\r")), out: io.Discard, fd: -1, historyFile: file}
+	for range 4 {
+		if _, err := e.readLine(PROMPT); err != nil {
+			t.Fatal(err)
+		}
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "let a = 1;\nlet b = 2;\n"; string(data) != expected {
+		t.Errorf("wrong history file.\nexpected:\n%s\ngot:\n%s", expected, data)
+	}
+}
+
+func TestEditorHistoryFileTrimmed(t *testing.T) {
+	file := filepath.Join(t.TempDir(), HISTORY_FILE)
+	e := &editor{out: io.Discard, fd: -1, historyFile: file}
+	for ix := range maxHistory + 5 {
+		e.addHistory(fmt.Sprintf("let a = %d;", ix))
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	if len(lines) != maxHistory || lines[0] != "let a = 5;" || lines[len(lines)-1] != fmt.Sprintf("let a = %d;", maxHistory+4) {
+		t.Errorf("wrong history file: %d lines from %q to %q", len(lines), lines[0], lines[len(lines)-1])
+	}
+}
+
+func TestPretty(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`"hi"`, `"hi"`},
+		{`["a", 1, true, first([])]`, `["a", 1, true, null]`},
+		{`{"a": [1, 2]}`, `{"a": [1, 2]}`},
+		{
+			`{"name": "a very long name that needs some space", "tags": ["one", "two", "three", "four"]}`,
+			`{
+  "name": "a very long name that needs some space",
+  "tags": ["one", "two", "three", "four"],
+}`,
+		},
+		{
+			`{"matrix": [range(20), range(20)]}`,
+			`{
+  "matrix": [
+    [0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16, 17, 18, 19],
+    [0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16, 17, 18, 19],
+  ],
+}`,
+		},
+	}
+
+	for _, tt := range tests {
+		var out bytes.Buffer
+		Start(strings.NewReader(tt.input), &out)
+		if got := strings.TrimSuffix(withoutPrompts(out.String()), "\n"); got != tt.expected {
+			t.Errorf("wrong output for %s.\nexpected:\n%s\ngot:\n%s", tt.input, tt.expected, got)
+		}
+	}
+}
+
+func TestPrettyTruncates(t *testing.T) {
+	items := make([]object.Object, 150)
+	for ix := range items {
+		items[ix] = &object.Integer{Value: int64(ix)}
+	}
+	lines := strings.Split(pretty(object.NewArray(items), false), "\n")
+	if len(lines) != 103 {
+		t.Fatalf("expected 100 items and 3 more lines, got %d lines", len(lines))
+	}
+	if lines[0] != "[" || lines[1] != "  0," || lines[100] != "  99," || lines[101] != "  ... (50 more)" || lines[102] != "]" {
+		t.Errorf("wrong truncated output: %q", lines)
+	}
+}
+
+func TestColors(t *testing.T) {
+	hash := object.NewHash()
+	hash.Set(&object.String{Value: "a"}, object.NewArray([]object.Object{&object.Integer{Value: 1}, &object.Boolean{Value: true}}))
+
+	expected := "{\x1b[32m\"a\"\x1b[0m: [\x1b[33m1\x1b[0m, \x1b[35mtrue\x1b[0m]}"
+	if got := pretty(hash, true); got != expected {
+		t.Errorf("wrong colored output.\nexpected=%q\ngot=%q", expected, got)
+	}
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`let x = 5;`, "\x1b[34mlet\x1b[0m x = \x1b[33m5\x1b[0m;"},
+		{`len("a${b}c") // n`, "\x1b[36mlen\x1b[0m(\x1b[32m\"a${\x1b[0mb\x1b[32m}c\"\x1b[0m) \x1b[90m// n\x1b[0m"},
+		{`"open`, "\x1b[32m\"open\x1b[0m"},
+		{`if (true) { ñ }`, "\x1b[34mif\x1b[0m (\x1b[35mtrue\x1b[0m) { ñ }"},
+	}
+	for _, tt := range tests {
+		if got := highlight(tt.input); got != tt.expected {
+			t.Errorf("wrong highlight for %s.\nexpected=%q\ngot=%q", tt.input, tt.expected, got)
+		}
+	}
+}
@@ -0,0 +1,10 @@
+//go:build darwin || dragonfly || freebsd || netbsd
+
+package repl
+
+import "syscall"
+
+const (
+	ioctlGetTermios = syscall.TIOCGETA
+	ioctlSetTermios = syscall.TIOCSETA
+)
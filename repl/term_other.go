@@ -0,0 +1,16 @@
+//go:build !linux && !darwin && !dragonfly && !freebsd && !netbsd
+
+package repl
+
+import "errors"
+
+// line editing needs raw mode, which is only supported on linux, macOS and some BSDs. elsewhere
+// input is read line by line.
+
+func isTerminal(fd int) bool {
+	return false
+}
+
+func makeRaw(fd int) (func(), error) {
+	return nil, errors.New("raw mode not supported")
+}
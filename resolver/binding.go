@@ -0,0 +1,26 @@
+package resolver
+
+import (
+	"github.com/manuelpepe/interpreter/ast"
+)
+
+// Binding identifies a single variable: a slot of a function scope, or a global by name. tools
+// walking a resolved program use it as a key to match declarations and references.
+type Binding struct {
+	Scope *ast.FunctionLiteral // nil for globals
+	Slot  int
+	Name  string
+}
+
+// BindingOf returns the binding an identifier of a resolved program refers to, given the function
+// literals enclosing it (innermost last). identifiers that were not resolved are looked up by name
+// at runtime, so they are reported as globals along with false.
+func BindingOf(scopes []*ast.FunctionLiteral, ident *ast.Identifier) (Binding, bool) {
+	if !ident.Resolved {
+		return Binding{Name: ident.Value}, false
+	}
+	if ident.Depth == ast.GlobalDepth {
+		return Binding{Name: ident.Value}, true
+	}
+	return Binding{Scope: scopes[len(scopes)-1-ident.Depth], Slot: ident.Slot, Name: ident.Value}, true
+}
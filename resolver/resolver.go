@@ -0,0 +1,125 @@
+// Package resolver computes the lexical address of every identifier in a program, so the evaluator
+// can find bindings by position instead of searching environments by name.
+//
+// Each function literal is a scope with one slot per parameter and per `let` in its body (blocks
+// don't create scopes). Lets are hoisted: all of them get a slot before the body is resolved, so
+// an inner function can refer to a binding declared after it. Identifiers not bound in any
+// enclosing function are globals, looked up by name at runtime.
+package resolver
+
+import (
+	"github.com/manuelpepe/interpreter/ast"
+)
+
+type scope struct {
+	slots map[string]int
+	names []string
+}
+
+func (s *scope) declare(name string) int {
+	if slot, ok := s.slots[name]; ok {
+		return slot
+	}
+	slot := len(s.names)
+	s.slots[name] = slot
+	s.names = append(s.names, name)
+	return slot
+}
+
+type resolver struct {
+	scopes []*scope // innermost last
+
+	// unknownOuter is set when resolving code out of its context, where bindings not found
+	// in scopes may still belong to an enclosing function instead of being globals.
+	unknownOuter bool
+}
+
+// Resolve annotates all identifiers and function literals in prog. it does nothing if the program
+// was already resolved.
+func Resolve(prog *ast.Program) {
+	if prog.Resolved {
+		return
+	}
+	r := &resolver{}
+	for _, s := range prog.Statements {
+		r.resolve(s)
+	}
+	prog.Resolved = true
+}
+
+// ResolveFunction annotates a function literal on its own. bindings from outside of it are left
+// unresolved, to be looked up by name at runtime.
+func ResolveFunction(fl *ast.FunctionLiteral) {
+	r := &resolver{unknownOuter: true}
+	r.resolveFunction(fl)
+}
+
+func (r *resolver) resolve(node ast.Node) {
+	switch node := node.(type) {
+	case *ast.Identifier:
+		r.resolveIdentifier(node)
+	case *ast.LetStatement:
+		r.resolve(node.Value)
+		r.resolveIdentifier(node.Name)
+	case *ast.FunctionLiteral:
+		r.resolveFunction(node)
+	default:
+		for _, child := range node.ChildNodes() {
+			if child != nil {
+				r.resolve(child)
+			}
+		}
+	}
+}
+
+func (r *resolver) resolveFunction(fl *ast.FunctionLiteral) {
+	sc := &scope{slots: make(map[string]int), names: make([]string, 0)}
+	for _, param := range fl.Parameters {
+		sc.declare(param.Value)
+	}
+	hoistLets(sc, fl.Body)
+
+	r.scopes = append(r.scopes, sc)
+	for _, param := range fl.Parameters {
+		r.resolveIdentifier(param)
+	}
+	r.resolve(fl.Body)
+	r.scopes = r.scopes[:len(r.scopes)-1]
+
+	fl.Locals = sc.names
+}
+
+func (r *resolver) resolveIdentifier(ident *ast.Identifier) {
+	ident.Resolved = true
+	for depth := 0; depth < len(r.scopes); depth++ {
+		sc := r.scopes[len(r.scopes)-1-depth]
+		if slot, ok := sc.slots[ident.Value]; ok {
+			ident.Depth = depth
+			ident.Slot = slot
+			return
+		}
+	}
+	if r.unknownOuter {
+		ident.Resolved = false
+		return
+	}
+	ident.Depth = ast.GlobalDepth
+	ident.Slot = 0
+}
+
+// hoistLets declares a slot for every let statement in node, without entering nested functions.
+func hoistLets(sc *scope, node ast.Node) {
+	switch node := node.(type) {
+	case *ast.LetStatement:
+		sc.declare(node.Name.Value)
+		hoistLets(sc, node.Value)
+	case *ast.FunctionLiteral:
+		return
+	default:
+		for _, child := range node.ChildNodes() {
+			if child != nil {
+				hoistLets(sc, child)
+			}
+		}
+	}
+}
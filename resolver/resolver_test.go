@@ -0,0 +1,148 @@
+package resolver
+
+import (
+	"testing"
+
+	"github.com/manuelpepe/interpreter/ast"
+	"github.com/manuelpepe/interpreter/lexer"
+	"github.com/manuelpepe/interpreter/parser"
+)
+
+func TestResolve(t *testing.T) {
+	input := `
+let g = 1;
+let f = fn(a, b) {
+	let c = a + g;
+	let inner = fn(d) {
+		d + c + a + later + g
+	};
+	let later = 2;
+	if (b) { let c = 3; }
+	inner(c)
+};
+`
+	prog := parse(t, input)
+	Resolve(prog)
+
+	if !prog.Resolved {
+		t.Fatalf("program not marked as resolved")
+	}
+
+	tests := []struct {
+		name  string
+		depth int
+		slot  int
+	}{
+		{"g", ast.GlobalDepth, 0}, // let g
+		{"f", ast.GlobalDepth, 0}, // let f
+		{"a", 0, 0},               // param a
+		{"b", 0, 1},               // param b
+		{"c", 0, 2},               // let c
+		{"a", 0, 0},
+		{"g", ast.GlobalDepth, 0},
+		{"inner", 0, 3}, // let inner
+		{"d", 0, 0},     // param d
+		{"d", 0, 0},
+		{"c", 1, 2},
+		{"a", 1, 0},
+		{"later", 1, 4}, // hoisted
+		{"g", ast.GlobalDepth, 0},
+		{"later", 0, 4}, // let later
+		{"b", 0, 1},
+		{"c", 0, 2}, // blocks don't create scopes
+		{"inner", 0, 3},
+		{"c", 0, 2},
+	}
+
+	idents := collectIdentifiers(prog)
+	if len(idents) != len(tests) {
+		t.Fatalf("wrong number of identifiers. got=%d", len(idents))
+	}
+	for ix, tt := range tests {
+		ident := idents[ix]
+		if ident.Value != tt.name {
+			t.Fatalf("identifier %d: wrong name. expected=%q, got=%q", ix, tt.name, ident.Value)
+		}
+		if !ident.Resolved || ident.Depth != tt.depth || ident.Slot != tt.slot {
+			t.Errorf("identifier %d (%s): wrong address. expected=(%d, %d), got=(%d, %d)",
+				ix, tt.name, tt.depth, tt.slot, ident.Depth, ident.Slot)
+		}
+	}
+
+	f := prog.Statements[1].(*ast.LetStatement).Value.(*ast.FunctionLiteral)
+	expectedLocals := []string{"a", "b", "c", "inner", "later"}
+	if len(f.Locals) != len(expectedLocals) {
+		t.Fatalf("wrong locals. got=%v", f.Locals)
+	}
+	for ix := range expectedLocals {
+		if f.Locals[ix] != expectedLocals[ix] {
+			t.Errorf("wrong local %d. expected=%q, got=%q", ix, expectedLocals[ix], f.Locals[ix])
+		}
+	}
+}
+
+func TestResolveFunctionLeavesOuterBindingsUnresolved(t *testing.T) {
+	prog := parse(t, "fn(a) { a + outer }")
+	fl := prog.Statements[0].(*ast.ExpressionStatement).Expression.(*ast.FunctionLiteral)
+
+	ResolveFunction(fl)
+
+	idents := collectIdentifiers(fl)
+	if !idents[1].Resolved || idents[1].Value != "a" {
+		t.Errorf("a should be resolved. got=%+v", idents[1])
+	}
+	if idents[2].Resolved || idents[2].Value != "outer" {
+		t.Errorf("outer should not be resolved. got=%+v", idents[2])
+	}
+}
+
+func TestBindingOf(t *testing.T) {
+	prog := parse(t, "let f = fn(a) { fn(b) { a + b + g } }")
+	Resolve(prog)
+	outer := prog.Statements[0].(*ast.LetStatement).Value.(*ast.FunctionLiteral)
+	inner := outer.Body.Statements[0].(*ast.ExpressionStatement).Expression.(*ast.FunctionLiteral)
+	scopes := []*ast.FunctionLiteral{outer, inner}
+
+	tests := []struct {
+		name     string
+		expected Binding
+	}{
+		{"a", Binding{Scope: outer, Slot: 0, Name: "a"}},
+		{"b", Binding{Scope: inner, Slot: 0, Name: "b"}},
+		{"g", Binding{Name: "g"}},
+	}
+	idents := collectIdentifiers(inner.Body)
+	for ix, tt := range tests {
+		b, ok := BindingOf(scopes, idents[ix])
+		if !ok || b != tt.expected {
+			t.Errorf("wrong binding for %s. expected=%+v, got=%+v (%t)", tt.name, tt.expected, b, ok)
+		}
+	}
+
+	unresolved := parse(t, "fn(c) { c + outer }").Statements[0].(*ast.ExpressionStatement).Expression.(*ast.FunctionLiteral)
+	ResolveFunction(unresolved)
+	idents = collectIdentifiers(unresolved.Body)
+	if b, ok := BindingOf([]*ast.FunctionLiteral{unresolved}, idents[1]); ok || b != (Binding{Name: "outer"}) {
+		t.Errorf("wrong binding for unresolved identifier: %+v (%t)", b, ok)
+	}
+}
+
+func parse(t *testing.T, input string) *ast.Program {
+	p := parser.New(lexer.NewLexer(input))
+	prog := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("parser errors: %v", p.Errors())
+	}
+	return prog
+}
+
+func collectIdentifiers(node ast.Node) []*ast.Identifier {
+	out := make([]*ast.Identifier, 0)
+	if ident, ok := node.(*ast.Identifier); ok {
+		out = append(out, ident)
+	}
+	for _, child := range node.ChildNodes() {
+		out = append(out, collectIdentifiers(child)...)
+	}
+	return out
+}
@@ -1,10 +1,17 @@
 package token
 
+import "sort"
+
 type TokenType string
 
 type Token struct {
 	Type    TokenType
 	Literal string
+
+	// position of the first character of the token in the source, starting from 1.
+	// columns are counted in runes.
+	Line   int
+	Column int
 }
 
 const (
@@ -16,6 +23,15 @@ const (
 	INT    = "INT"
 	STRING = "STRING"
 
+	// interpolated strings, eg. "a ${x} b ${y} c" is lexed as
+	// TEMPLATE_START("a ") IDENT(x) TEMPLATE_MIDDLE(" b ") IDENT(y) TEMPLATE_END(" c")
+	TEMPLATE_START  = "TEMPLATE_START"
+	TEMPLATE_MIDDLE = "TEMPLATE_MIDDLE"
+	TEMPLATE_END    = "TEMPLATE_END"
+
+	// line comments, eg. `// text`. they are not returned by the lexer's NextToken, see Lexer.Comments
+	COMMENT = "COMMENT"
+
 	// Operators
 	ASSIGN   = "="
 	PLUS     = "+"
@@ -61,10 +77,20 @@ var keywords = map[string]TokenType{
 	"return": RETURN,
 }
 
-// LookupIdent checks if a given string is a reserved keyword, returning it's type or IDENT otherwise.
+// LookupIdent checks if a given string is a reserved keyword, returning its type or IDENT otherwise.
 func LookupIdent(ident string) TokenType {
 	if val, ok := keywords[ident]; ok {
 		return val
 	}
 	return IDENT
 }
+
+// Keywords returns the reserved keywords in alphabetical order.
+func Keywords() []string {
+	out := make([]string, 0, len(keywords))
+	for kw := range keywords {
+		out = append(out, kw)
+	}
+	sort.Strings(out)
+	return out
+}
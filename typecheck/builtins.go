@@ -0,0 +1,92 @@
+package typecheck
+
+import (
+	"strings"
+
+	"github.com/manuelpepe/interpreter/eval"
+	"github.com/manuelpepe/interpreter/object"
+)
+
+// polymorphic holds the types of the builtins whose result depends on the types of their arguments,
+// which can't be derived from their spec.
+var polymorphic = map[string]func(a, b *Var) Type{
+	"first":  func(a, b *Var) Type { return fn(a, &Array{Item: a}) },
+	"last":   func(a, b *Var) Type { return fn(a, &Array{Item: a}) },
+	"rest":   func(a, b *Var) Type { return fn(&Array{Item: a}, &Array{Item: a}) },
+	"push":   func(a, b *Var) Type { return fn(&Array{Item: a}, &Array{Item: a}, a) },
+	"map":    func(a, b *Var) Type { return fn(&Array{Item: b}, &Array{Item: a}, fn(b, a)) },
+	"filter": func(a, b *Var) Type { return fn(&Array{Item: a}, &Array{Item: a}, fn(Any, a)) },
+	"reduce": func(a, b *Var) Type { return fn(b, &Array{Item: a}, b, fn(b, b, a)) },
+	"unique": func(a, b *Var) Type { return fn(&Array{Item: a}, &Array{Item: a}) },
+	"keys":   func(a, b *Var) Type { return fn(&Array{Item: a}, &Hash{Key: a, Value: b}) },
+	"values": func(a, b *Var) Type { return fn(&Array{Item: b}, &Hash{Key: a, Value: b}) },
+	"has":    func(a, b *Var) Type { return fn(Bool, &Hash{Key: a, Value: b}, a) },
+	"delete": func(a, b *Var) Type { return fn(&Hash{Key: a, Value: b}, &Hash{Key: a, Value: b}, a) },
+}
+
+// universe returns a scope with the types of the builtins in registry. namespaces are hashes of
+// strings to values of any type, as their members have different types.
+func universe(registry *object.Registry) *scope {
+	s := &scope{names: make(map[string]*entry)}
+	defaults := eval.Builtins()
+	for _, name := range registry.Names() {
+		if ns, _, qualified := strings.Cut(name, "."); qualified {
+			s.names[ns] = &entry{scheme: &Scheme{Type: &Hash{Key: String, Value: Any}}}
+			continue
+		}
+		builtin, _ := registry.Get(name)
+		scheme := builtinScheme(builtin)
+		if sig, ok := polymorphic[name]; ok && isDefault(defaults, builtin) {
+			a, b := &Var{}, &Var{} // always instantiated before being used
+			scheme = &Scheme{Vars: []*Var{a, b}, Type: sig(a, b)}
+		}
+		s.names[name] = &entry{scheme: scheme}
+	}
+	return s
+}
+
+// isDefault reports whether b is the default builtin with its name, and not one replaced by the host.
+func isDefault(defaults *object.Registry, b *object.Builtin) bool {
+	def, ok := defaults.Get(b.Fn.Name())
+	return ok && def.Fn == b.Fn
+}
+
+// builtinScheme derives the type of a builtin from its spec. builtins without a spec or accepting
+// an optional number of arguments are dynamic and can be called with anything.
+func builtinScheme(b *object.Builtin) *Scheme {
+	if b.Spec == nil || b.Spec.Optional != 0 || b.Spec.Variadic {
+		return &Scheme{Type: Any}
+	}
+	params := make([]Type, len(b.Spec.Params))
+	for ix, param := range b.Spec.Params {
+		params[ix] = Any
+		if len(param.Types) == 1 {
+			params[ix] = objectType(param.Types[0])
+		}
+	}
+	return &Scheme{Type: fn(objectType(b.Spec.Result), params...)}
+}
+
+// objectType returns the type of the values of an object type, or any if they have many.
+func objectType(typ object.ObjectType) Type {
+	switch typ {
+	case object.INTEGER_OBJ:
+		return Int
+	case object.STRING_OBJ:
+		return String
+	case object.BOOLEAN_OBJ:
+		return Bool
+	case object.NULL_OBJ:
+		return Null
+	case object.ARRAY_OBJ:
+		return &Array{Item: Any}
+	case object.HASH_OBJ:
+		return &Hash{Key: Any, Value: Any}
+	}
+	return Any
+}
+
+// fn returns the type of a function with the given result and parameters.
+func fn(result Type, params ...Type) *Function {
+	return &Function{Params: params, Result: result}
+}
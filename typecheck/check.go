@@ -0,0 +1,251 @@
+// Package typecheck infers the types of Monkey programs before running them.
+//
+// Bindings and function results can be annotated (`let x: int = 5`, `fn(a: int, b: string): bool { }`),
+// anything without an annotation is inferred using Hindley-Milner style unification, with let bound
+// functions generalized so they can be used with different types.
+//
+// Values whose type can only be known at runtime (like the result of some builtins or conditionals
+// without alternative) have the type `any`, which is compatible with every other type.
+package typecheck
+
+import (
+	"fmt"
+
+	"github.com/manuelpepe/interpreter/ast"
+	"github.com/manuelpepe/interpreter/eval"
+	"github.com/manuelpepe/interpreter/object"
+	"github.com/manuelpepe/interpreter/token"
+)
+
+// Error is a type error found in a program.
+type Error struct {
+	Line    int
+	Column  int
+	Message string
+}
+
+func (e Error) Error() string {
+	return fmt.Sprintf("%d:%d: %s", e.Line, e.Column, e.Message)
+}
+
+type entry struct {
+	scheme *Scheme
+
+	// forward entries are declared before the let that defines them is reached,
+	// so functions can refer to bindings defined after them.
+	forward bool
+	used    bool
+}
+
+// scope holds the bindings of a function (or the whole program), blocks don't create scopes.
+type scope struct {
+	names map[string]*entry
+	outer *scope
+}
+
+func (s *scope) lookup(name string) (*entry, bool) {
+	for ; s != nil; s = s.outer {
+		if e, ok := s.names[name]; ok {
+			return e, true
+		}
+	}
+	return nil, false
+}
+
+type checker struct {
+	nextVar int
+	errors  []Error
+
+	returns []Type // expected result of the enclosing functions, innermost last
+
+	trail    []*Var // variables bound while trying an unification
+	trailing bool
+}
+
+// Check infers the types of prog and reports any type errors found, in source order.
+func Check(prog *ast.Program) []Error {
+	return CheckWith(prog, eval.Builtins())
+}
+
+// CheckWith is like Check for programs running with the builtins in registry, whose types are
+// derived from their specs.
+func CheckWith(prog *ast.Program, registry *object.Registry) []Error {
+	c := &checker{}
+	global := c.enter(universe(registry), prog.Statements)
+	for _, stmt := range prog.Statements {
+		c.statement(stmt, global)
+	}
+	return c.errors
+}
+
+func (c *checker) errorf(tok token.Token, format string, args ...any) {
+	c.errors = append(c.errors, Error{Line: tok.Line, Column: tok.Column, Message: fmt.Sprintf(format, args...)})
+}
+
+func (c *checker) fresh() *Var {
+	c.nextVar += 1
+	return &Var{id: c.nextVar}
+}
+
+// enter creates a new scope declaring all the lets found in stmts, without entering nested functions.
+func (c *checker) enter(outer *scope, stmts []ast.Statement) *scope {
+	s := &scope{names: make(map[string]*entry), outer: outer}
+	var declare func(ast.Node)
+	declare = func(node ast.Node) {
+		switch node := node.(type) {
+		case nil, *ast.FunctionLiteral:
+			return
+		case *ast.LetStatement:
+			if _, ok := s.names[node.Name.Value]; !ok {
+				s.names[node.Name.Value] = &entry{scheme: &Scheme{Type: c.fresh()}, forward: true}
+			}
+		}
+		for _, child := range node.ChildNodes() {
+			declare(child)
+		}
+	}
+	for _, stmt := range stmts {
+		declare(stmt)
+	}
+	return s
+}
+
+func (c *checker) statement(stmt ast.Statement, s *scope) Type {
+	switch stmt := stmt.(type) {
+	case *ast.LetStatement:
+		c.let(stmt, s)
+		return Null
+	case *ast.ReturnStatement:
+		t := c.infer(stmt.ReturnValue, s)
+		if n := len(c.returns); n > 0 {
+			c.expect(stmt.Token, t, c.returns[n-1], "cannot return %[1]s from function returning %[2]s")
+		}
+		// the statement after a return never runs, so its result can have any type
+		return c.fresh()
+	case *ast.ExpressionStatement:
+		return c.infer(stmt.Expression, s)
+	case *ast.BlockStatement:
+		return c.block(stmt, s)
+	}
+	return Any
+}
+
+func (c *checker) block(b *ast.BlockStatement, s *scope) Type {
+	var result Type = Null
+	for _, stmt := range b.Statements {
+		result = c.statement(stmt, s)
+	}
+	return result
+}
+
+func (c *checker) let(let *ast.LetStatement, s *scope) {
+	name := let.Name.Value
+	prev := s.names[name] // lets are always declared when entering the scope
+	if _, isFn := let.Value.(*ast.FunctionLiteral); isFn && !prev.forward {
+		// functions redefining a binding see the new definition when referring to themselves.
+		prev = &entry{scheme: &Scheme{Type: c.fresh()}, forward: true}
+		s.names[name] = prev
+	}
+
+	t := c.infer(let.Value, s)
+	if let.Name.Type != nil {
+		annotated := c.annotation(let.Name.Type)
+		c.expect(let.Name.Token, t, annotated, "cannot use %[1]s as %[2]s in let "+name)
+		t = annotated
+	}
+	if prev.forward && prev.used {
+		c.expect(let.Name.Token, t, prev.scheme.Type, "cannot use %[1]s as "+name+", used as %[2]s before")
+	}
+
+	s.names[name] = &entry{scheme: c.generalize(t, s, name)}
+}
+
+// generalize quantifies t over the variables that are not bound in any scope, ignoring the binding
+// being defined.
+func (c *checker) generalize(t Type, s *scope, name string) *Scheme {
+	vars := make(map[*Var]bool)
+	order := freeVars(t, vars)
+	for sc := s; sc != nil && len(vars) > 0; sc = sc.outer {
+		for n, e := range sc.names {
+			if sc == s && n == name {
+				continue
+			}
+			bound := make(map[*Var]bool)
+			freeVars(e.scheme.Type, bound)
+			for _, v := range e.scheme.Vars {
+				delete(bound, v)
+			}
+			for v := range bound {
+				delete(vars, v)
+			}
+		}
+	}
+	scheme := &Scheme{Type: t}
+	for _, v := range order {
+		if vars[v] {
+			scheme.Vars = append(scheme.Vars, v)
+		}
+	}
+	return scheme
+}
+
+func (c *checker) instantiate(scheme *Scheme) Type {
+	if len(scheme.Vars) == 0 {
+		return scheme.Type
+	}
+	subst := make(map[*Var]Type, len(scheme.Vars))
+	for _, v := range scheme.Vars {
+		subst[v] = c.fresh()
+	}
+	return substitute(scheme.Type, subst)
+}
+
+func substitute(t Type, subst map[*Var]Type) Type {
+	switch t := prune(t).(type) {
+	case *Var:
+		if r, ok := subst[t]; ok {
+			return r
+		}
+		return t
+	case *Array:
+		return &Array{Item: substitute(t.Item, subst)}
+	case *Hash:
+		return &Hash{Key: substitute(t.Key, subst), Value: substitute(t.Value, subst)}
+	case *Function:
+		params := make([]Type, len(t.Params))
+		for ix, p := range t.Params {
+			params[ix] = substitute(p, subst)
+		}
+		return &Function{Params: params, Result: substitute(t.Result, subst)}
+	default:
+		return t
+	}
+}
+
+// annotation converts a type annotation into a Type.
+func (c *checker) annotation(ta *ast.TypeAnnotation) Type {
+	params := make([]Type, len(ta.Params))
+	for ix, p := range ta.Params {
+		params[ix] = c.annotation(p)
+	}
+	switch ta.Name {
+	case "int":
+		return Int
+	case "string":
+		return String
+	case "bool":
+		return Bool
+	case "null":
+		return Null
+	case "any":
+		return Any
+	case "[]":
+		return &Array{Item: params[0]}
+	case "{}":
+		return &Hash{Key: params[0], Value: params[1]}
+	case "fn":
+		return &Function{Params: params, Result: c.annotation(ta.Result)}
+	}
+	c.errorf(ta.Token, "unknown type: %s", ta.Name)
+	return Any
+}
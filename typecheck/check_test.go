@@ -0,0 +1,135 @@
+package typecheck
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/manuelpepe/interpreter/ast"
+	"github.com/manuelpepe/interpreter/eval"
+	"github.com/manuelpepe/interpreter/lexer"
+	"github.com/manuelpepe/interpreter/parser"
+)
+
+func TestCheckValidPrograms(t *testing.T) {
+	tests := []string{
+		`let x = 5; let y = x * 2 + 1; y > 3`,
+		`let x: int = 5; let s: string = "a" + "b"; let b: bool = !x;`,
+		`let add = fn(a: int, b: int): int { a + b }; add(1, 2)`,
+		`let concat = fn(a, b) { a + b }; concat(1, 2); concat("a", "b")`,
+		`let id = fn(x) { x }; id(1) + 1; id("a") + "b"`,
+		`let fib = fn(n) { if (n < 2) { return n; }; fib(n - 1) + fib(n - 2) }; fib(10)`,
+		`let f = fn() { g(1) }; let g = fn(x) { x * 2 }; f() + 1`,
+		`let x = 1; let x = "a"; x + "b"`,
+		`let xs = [1, 2, 3]; xs[0] + len(xs); map(xs, fn(x) { x * 2 })[0] + 1`,
+		`let mixed = [1, "a", true]; mixed[0]`,
+		`let h = {"a": 1, "b": 2}; h["a"] * 2; keys(h)[0] + "c"`,
+		`let s = "hello"; s[0] + s[1:3]`,
+		`let apply = fn(f: fn(int): int, x: int): int { f(x) }; apply(fn(x) { x + 1 }, 2)`,
+		`let d: any = 1; d + "a"`,
+		`"${1} and ${true}"`,
+		`let maybe = if (true) { 1 }; maybe`,
+		`inspect(1, "a"); format("%d", 1)`,
+		`1 == "a"`,
+		`let f = fn(x) { if (x) { return 1; } else { return 2; } }; f(true) + 1`,
+	}
+
+	for _, input := range tests {
+		errs := Check(parse(t, input))
+		if len(errs) != 0 {
+			t.Errorf("unexpected type errors in %q: %v", input, errs)
+		}
+	}
+}
+
+func TestCheckErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`1 + "a"`, `1:3: type mismatch: int + string`},
+		{`let x = 1;
+let y = "a";
+x + y`, `3:3: type mismatch: int + string`},
+		{`"a" - "b"`, `1:5: unknown operator: string - string`},
+		{`[1] + [2]`, `1:5: unknown operator: [int] + [int]`},
+		{`-"a"`, `1:1: unknown operator: -string`},
+		{`let x: int = "a";`, `1:5: cannot use string as int in let x`},
+		{`let f = fn(a: int) { a }; f("a")`, `1:29: cannot use string as int in argument to f`},
+		{`let f = fn(a, b) { a }; f(1)`, `1:25: wrong number of arguments to f. got=1, want=2`},
+		{`let f = fn(): string { 1 }`, `1:24: cannot return int from function returning string`},
+		{`let f = fn(): string { return 1; }`, `1:24: cannot return int from function returning string`},
+		{`let g = fn(x) { x + 1 }; g("a")`, `1:28: cannot use string as int in argument to g`},
+		{`let id = fn(x) { x }; id(1) + id("a")`, `1:29: type mismatch: int + string`},
+		{`if (true) { 1 } else { "a" }`, `1:1: branches of if have different types: int and string`},
+		{`let x = 5; x(1)`, `1:12: not a function: int`},
+		{`let x = 5; x[0]`, `1:13: index operator not supported: int`},
+		{`let xs = [1]; xs["a"]`, `1:18: cannot use string as array index`},
+		{`let h = {"a": 1}; h[1]`, `1:21: cannot use int as hash key of type string`},
+		{`{fn(x) { x }: 1}`, `1:2: unusable as hash key: fn(t2): t2`},
+		{`let f = fn(a, b) { a }; let z = f + 1;`, `1:35: type mismatch: fn(t6, t7): t6 + int`},
+		{`y + 1`, `1:1: identifier not found: y`},
+		{`let x: number = 1;`, `1:8: unknown type: number`},
+		{`let f = fn(x) { g(x) }; let g = fn(y) { y * 2 }; f("a")`, `1:52: cannot use string as int in argument to f`},
+		{`upper(1)`, `1:7: cannot use int as string in argument to upper`},
+		{`filter(["a"], fn(x: int): bool { true })`, `1:15: cannot use fn(int): bool as fn(string): any in argument to filter`},
+	}
+
+	for _, tt := range tests {
+		errs := Check(parse(t, tt.input))
+		if len(errs) == 0 {
+			t.Errorf("expected type errors in %q", tt.input)
+			continue
+		}
+		if errs[0].Error() != tt.expected {
+			t.Errorf("wrong error for %q. expected=%q, got=%q", tt.input, tt.expected, errs[0].Error())
+		}
+	}
+}
+
+func TestCheckBuiltins(t *testing.T) {
+	u := universe(eval.Builtins())
+	for _, name := range eval.BuiltinNames() {
+		if _, ok := u.lookup(name); !ok {
+			t.Errorf("builtin %s has no type", name)
+		}
+	}
+	for name := range polymorphic {
+		if !eval.IsBuiltin(name) {
+			t.Errorf("type given for unknown builtin %s", name)
+		}
+	}
+
+	registry := eval.Builtins()
+	for name, fn := range map[string]any{
+		"shout":        strings.ToUpper,
+		"strings.trim": strings.TrimSpace,
+	} {
+		b, err := eval.WrapFunc(name, fn)
+		if err != nil {
+			t.Fatal(err)
+		}
+		registry.MustRegister(b.Fn, b.Spec)
+	}
+
+	if errs := CheckWith(parse(t, `shout("a") + "b"; strings["trim"](" a ")`), registry); len(errs) != 0 {
+		t.Errorf("unexpected type errors: %v", errs)
+	}
+	errs := CheckWith(parse(t, `shout("a") + 1`), registry)
+	if len(errs) == 0 || errs[0].Error() != "1:12: type mismatch: string + int" {
+		t.Errorf("wrong errors for host builtin: %v", errs)
+	}
+	errs = CheckWith(parse(t, `shout(1)`), registry)
+	if len(errs) == 0 || errs[0].Error() != "1:7: cannot use int as string in argument to shout" {
+		t.Errorf("wrong errors for host builtin: %v", errs)
+	}
+}
+
+func parse(t *testing.T, input string) *ast.Program {
+	t.Helper()
+	p := parser.New(lexer.NewLexer(input))
+	prog := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("parser errors: %v", p.Errors())
+	}
+	return prog
+}
@@ -0,0 +1,346 @@
+package typecheck
+
+import (
+	"github.com/manuelpepe/interpreter/ast"
+	"github.com/manuelpepe/interpreter/token"
+)
+
+func (c *checker) infer(expr ast.Expression, s *scope) Type {
+	switch expr := expr.(type) {
+	case nil:
+		return Any
+	case *ast.IntegerLiteral:
+		return Int
+	case *ast.StringLiteral, *ast.TemplateText:
+		return String
+	case *ast.Boolean:
+		return Bool
+	case *ast.InterpolatedString:
+		for _, part := range expr.Parts {
+			c.infer(part, s)
+		}
+		return String
+	case *ast.Identifier:
+		e, ok := s.lookup(expr.Value)
+		if !ok {
+			c.errorf(expr.Token, "identifier not found: %s", expr.Value)
+			return Any
+		}
+		e.used = true
+		return c.instantiate(e.scheme)
+	case *ast.PrefixExpression:
+		return c.prefix(expr, s)
+	case *ast.InfixExpression:
+		return c.infix(expr, s)
+	case *ast.IfExpression:
+		c.infer(expr.Condition, s)
+		cons := c.block(expr.Consequence, s)
+		if expr.Alternative == nil {
+			// evaluates to null when the condition doesn't hold
+			return Any
+		}
+		alt := c.block(expr.Alternative, s)
+		c.expect(expr.Token, alt, cons, "branches of if have different types: %[2]s and %[1]s")
+		return cons
+	case *ast.FunctionLiteral:
+		return c.function(expr, s)
+	case *ast.CallExpression:
+		return c.call(expr, s)
+	case *ast.ArrayLiteral:
+		items := make([]Type, len(expr.Items))
+		for ix, item := range expr.Items {
+			items[ix] = c.infer(item, s)
+		}
+		return &Array{Item: c.common(items)}
+	case *ast.HashLiteral:
+		keys := make([]Type, len(expr.Items))
+		values := make([]Type, len(expr.Items))
+		for ix, item := range expr.Items {
+			keys[ix] = c.infer(item.Key, s)
+			values[ix] = c.infer(item.Value, s)
+			switch k := prune(keys[ix]).(type) {
+			case *Hash, *Function:
+				c.errorf(tokenOf(item.Key), "unusable as hash key: %s", k)
+			}
+		}
+		return &Hash{Key: c.common(keys), Value: c.common(values)}
+	case *ast.IndexExpression:
+		return c.index(expr, s)
+	case *ast.SliceExpression:
+		left := c.infer(expr.Left, s)
+		for _, bound := range []ast.Expression{expr.Start, expr.End} {
+			if bound != nil {
+				c.expect(tokenOf(bound), c.infer(bound, s), Int, "cannot use %[1]s as slice index")
+			}
+		}
+		switch l := prune(left).(type) {
+		case *Array, *Dynamic, *Var:
+			return l
+		case *Basic:
+			if l == String {
+				return l
+			}
+		}
+		c.errorf(expr.Token, "slice operator not supported: %s", left)
+		return Any
+	}
+	return Any
+}
+
+func (c *checker) prefix(pe *ast.PrefixExpression, s *scope) Type {
+	right := c.infer(pe.Right, s)
+	switch pe.Operator {
+	case "!":
+		return Bool
+	case "-":
+		c.expect(pe.Token, right, Int, "unknown operator: -%[1]s")
+		return Int
+	}
+	return Any
+}
+
+func (c *checker) infix(ie *ast.InfixExpression, s *scope) Type {
+	left := c.infer(ie.Left, s)
+	right := c.infer(ie.Right, s)
+
+	switch ie.Operator {
+	case "==", "!=":
+		// values of different types are never equal, but can be compared
+		return Bool
+	case "+":
+		if !c.unify(left, right) {
+			c.errorf(ie.Token, "type mismatch: %s + %s", left, right)
+			return Any
+		}
+		switch l := prune(left).(type) {
+		case *Var, *Dynamic:
+			return l
+		case *Basic:
+			if l == Int || l == String {
+				return l
+			}
+		}
+		c.errorf(ie.Token, "unknown operator: %s + %s", left, right)
+		return Any
+	case "-", "*", "/", "<", ">":
+		if !c.unify(left, right) {
+			c.errorf(ie.Token, "type mismatch: %s %s %s", left, ie.Operator, right)
+		} else if !c.unify(left, Int) {
+			c.errorf(ie.Token, "unknown operator: %s %s %s", left, ie.Operator, right)
+		}
+		if ie.Operator == "<" || ie.Operator == ">" {
+			return Bool
+		}
+		return Int
+	}
+	return Any
+}
+
+func (c *checker) index(ie *ast.IndexExpression, s *scope) Type {
+	left := c.infer(ie.Left, s)
+	index := c.infer(ie.Index, s)
+
+	switch l := prune(left).(type) {
+	case *Array:
+		c.expect(tokenOf(ie.Index), index, Int, "cannot use %[1]s as array index")
+		return l.Item
+	case *Hash:
+		c.expect(tokenOf(ie.Index), index, l.Key, "cannot use %[1]s as hash key of type %[2]s")
+		return l.Value
+	case *Basic:
+		if l == String {
+			c.expect(tokenOf(ie.Index), index, Int, "cannot use %[1]s as string index")
+			return String
+		}
+	case *Dynamic, *Var:
+		// could be an array, a hash or a string
+		return Any
+	}
+	c.errorf(ie.Token, "index operator not supported: %s", left)
+	return Any
+}
+
+func (c *checker) function(fl *ast.FunctionLiteral, outer *scope) Type {
+	s := c.enter(outer, fl.Body.Statements)
+
+	params := make([]Type, len(fl.Parameters))
+	for ix, param := range fl.Parameters {
+		if param.Type != nil {
+			params[ix] = c.annotation(param.Type)
+		} else {
+			params[ix] = c.fresh()
+		}
+		s.names[param.Value] = &entry{scheme: &Scheme{Type: params[ix]}}
+	}
+
+	var result Type = c.fresh()
+	if fl.ReturnType != nil {
+		result = c.annotation(fl.ReturnType)
+	}
+
+	c.returns = append(c.returns, result)
+	body := c.block(fl.Body, s)
+	c.returns = c.returns[:len(c.returns)-1]
+
+	tok := fl.Token
+	if n := len(fl.Body.Statements); n > 0 {
+		tok = tokenOf(fl.Body.Statements[n-1])
+	}
+	c.expect(tok, body, result, "cannot return %[1]s from function returning %[2]s")
+
+	return &Function{Params: params, Result: result}
+}
+
+func (c *checker) call(call *ast.CallExpression, s *scope) Type {
+	fn := c.infer(call.Function, s)
+	args := make([]Type, len(call.Arguments))
+	for ix, arg := range call.Arguments {
+		args[ix] = c.infer(arg, s)
+	}
+
+	switch f := prune(fn).(type) {
+	case *Dynamic:
+		return Any
+	case *Function:
+		if len(f.Params) != len(args) {
+			c.errorf(tokenOf(call.Function), "wrong number of arguments to %s. got=%d, want=%d",
+				call.Function, len(args), len(f.Params))
+			return f.Result
+		}
+		for ix := range args {
+			c.expect(tokenOf(call.Arguments[ix]), args[ix], f.Params[ix],
+				"cannot use %[1]s as %[2]s in argument to "+call.Function.String())
+		}
+		return f.Result
+	case *Var:
+		result := c.fresh()
+		c.unify(f, &Function{Params: args, Result: result})
+		return result
+	}
+	c.errorf(tokenOf(call.Function), "not a function: %s", fn)
+	return Any
+}
+
+// common returns the type shared by all types in ts, or any if they are not compatible.
+func (c *checker) common(ts []Type) Type {
+	if len(ts) == 0 {
+		return c.fresh()
+	}
+	for _, t := range ts[1:] {
+		if !c.tryUnify(ts[0], t) {
+			return Any
+		}
+	}
+	return ts[0]
+}
+
+// expect unifies got with want, reporting an error formatted with both types if they don't match.
+func (c *checker) expect(tok token.Token, got Type, want Type, format string) {
+	if !c.unify(got, want) {
+		c.errorf(tok, format, got, want)
+	}
+}
+
+// tryUnify is like unify, but leaves the types untouched if they don't match.
+func (c *checker) tryUnify(a Type, b Type) bool {
+	trailing, trail := c.trailing, len(c.trail)
+	c.trailing = true
+	ok := c.unify(a, b)
+	c.trailing = trailing
+	if !ok {
+		for _, v := range c.trail[trail:] {
+			v.instance = nil
+		}
+	}
+	if !trailing || !ok {
+		c.trail = c.trail[:trail]
+	}
+	return ok
+}
+
+func (c *checker) unify(a Type, b Type) bool {
+	a, b = prune(a), prune(b)
+	if a == b {
+		return true
+	}
+	if v, ok := a.(*Var); ok {
+		if occurs(v, b) {
+			return false
+		}
+		v.instance = b
+		if c.trailing {
+			c.trail = append(c.trail, v)
+		}
+		return true
+	}
+	if _, ok := b.(*Var); ok {
+		return c.unify(b, a)
+	}
+	if a == Any || b == Any {
+		return true
+	}
+
+	switch a := a.(type) {
+	case *Array:
+		b, ok := b.(*Array)
+		return ok && c.unify(a.Item, b.Item)
+	case *Hash:
+		b, ok := b.(*Hash)
+		return ok && c.unify(a.Key, b.Key) && c.unify(a.Value, b.Value)
+	case *Function:
+		b, ok := b.(*Function)
+		if !ok || len(a.Params) != len(b.Params) {
+			return false
+		}
+		for ix := range a.Params {
+			if !c.unify(a.Params[ix], b.Params[ix]) {
+				return false
+			}
+		}
+		return c.unify(a.Result, b.Result)
+	}
+	return false
+}
+
+// tokenOf returns the token that starts node, to report errors at its position.
+func tokenOf(node ast.Node) token.Token {
+	switch node := node.(type) {
+	case *ast.LetStatement:
+		return node.Token
+	case *ast.ReturnStatement:
+		return node.Token
+	case *ast.ExpressionStatement:
+		return tokenOf(node.Expression)
+	case *ast.BlockStatement:
+		return node.Token
+	case *ast.Identifier:
+		return node.Token
+	case *ast.IntegerLiteral:
+		return node.Token
+	case *ast.StringLiteral:
+		return node.Token
+	case *ast.InterpolatedString:
+		return node.Token
+	case *ast.Boolean:
+		return node.Token
+	case *ast.PrefixExpression:
+		return node.Token
+	case *ast.InfixExpression:
+		return tokenOf(node.Left)
+	case *ast.IfExpression:
+		return node.Token
+	case *ast.FunctionLiteral:
+		return node.Token
+	case *ast.CallExpression:
+		return tokenOf(node.Function)
+	case *ast.ArrayLiteral:
+		return node.Token
+	case *ast.HashLiteral:
+		return node.Token
+	case *ast.IndexExpression:
+		return tokenOf(node.Left)
+	case *ast.SliceExpression:
+		return tokenOf(node.Left)
+	}
+	return token.Token{}
+}
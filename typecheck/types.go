@@ -0,0 +1,139 @@
+package typecheck
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Type is the static type of an expression.
+type Type interface {
+	String() string
+}
+
+// Basic types are the types of literals and null.
+type Basic struct {
+	Name string
+}
+
+func (b *Basic) String() string { return b.Name }
+
+var (
+	Int    = &Basic{Name: "int"}
+	String = &Basic{Name: "string"}
+	Bool   = &Basic{Name: "bool"}
+	Null   = &Basic{Name: "null"}
+)
+
+// Dynamic is the type of values that are only known at runtime, eg. the result of builtins that
+// accept arguments of different types. it is compatible with every other type.
+type Dynamic struct{}
+
+func (d *Dynamic) String() string { return "any" }
+
+var Any = &Dynamic{}
+
+type Array struct {
+	Item Type
+}
+
+func (a *Array) String() string { return "[" + a.Item.String() + "]" }
+
+type Hash struct {
+	Key   Type
+	Value Type
+}
+
+func (h *Hash) String() string { return "{" + h.Key.String() + ": " + h.Value.String() + "}" }
+
+type Function struct {
+	Params []Type
+	Result Type
+}
+
+func (f *Function) String() string {
+	params := make([]string, len(f.Params))
+	for ix, p := range f.Params {
+		params[ix] = p.String()
+	}
+	return "fn(" + strings.Join(params, ", ") + "): " + f.Result.String()
+}
+
+// Var is a type that hasn't been inferred yet. once unified with another type, instance points to it.
+type Var struct {
+	id       int
+	instance Type
+}
+
+func (v *Var) String() string {
+	if v.instance != nil {
+		return v.instance.String()
+	}
+	return fmt.Sprintf("t%d", v.id)
+}
+
+// Scheme is a type generalized over some of its variables, so each use of a binding can instantiate
+// them with different types (eg. `let id = fn(x) { x }` can be used both on ints and strings).
+type Scheme struct {
+	Vars []*Var
+	Type Type
+}
+
+// prune follows instantiated variables, returning the type they stand for.
+// paths are not compressed so bindings made while trying an unification can be undone.
+func prune(t Type) Type {
+	for {
+		v, ok := t.(*Var)
+		if !ok || v.instance == nil {
+			return t
+		}
+		t = v.instance
+	}
+}
+
+// occurs reports if v appears in t.
+func occurs(v *Var, t Type) bool {
+	switch t := prune(t).(type) {
+	case *Var:
+		return t == v
+	case *Array:
+		return occurs(v, t.Item)
+	case *Hash:
+		return occurs(v, t.Key) || occurs(v, t.Value)
+	case *Function:
+		for _, p := range t.Params {
+			if occurs(v, p) {
+				return true
+			}
+		}
+		return occurs(v, t.Result)
+	}
+	return false
+}
+
+// freeVars adds the variables that appear in t to vars, returning the new ones in the order they
+// first appear.
+func freeVars(t Type, vars map[*Var]bool) []*Var {
+	var order []*Var
+	var walk func(t Type)
+	walk = func(t Type) {
+		switch t := prune(t).(type) {
+		case *Var:
+			if !vars[t] {
+				vars[t] = true
+				order = append(order, t)
+			}
+		case *Array:
+			walk(t.Item)
+		case *Hash:
+			walk(t.Key)
+			walk(t.Value)
+		case *Function:
+			for _, p := range t.Params {
+				walk(p)
+			}
+			walk(t.Result)
+		}
+	}
+	walk(t)
+	return order
+}